@@ -0,0 +1,161 @@
+package dids
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ===== types =====
+
+// the kind of surprise a payload field will hit during EIP-712 conversion
+type PayloadWarningKind string
+
+const (
+	// a float goes through the float handler, which may round or truncate it
+	WarnFloat PayloadWarningKind = "float"
+	// a number too big to be held exactly in a float64 (e.g. after a JSON round trip)
+	WarnImpreciseNumber PayloadWarningKind = "imprecise-number"
+	// a 0x string that will be typed as `address` rather than `string`
+	WarnAddressCoercion PayloadWarningKind = "address-coercion"
+	// a 0x string that looks like an address but isn't one, so stays a `string`
+	WarnAddressLookalike PayloadWarningKind = "address-lookalike"
+	// an empty array gets the `undefined[]` type
+	WarnEmptyArray PayloadWarningKind = "empty-array"
+	// an array whose elements don't share a type (the first element decides the array's type)
+	WarnMixedArray PayloadWarningKind = "mixed-array"
+	// a value the converter can't type at all
+	WarnUnsupportedType PayloadWarningKind = "unsupported-type"
+)
+
+// a non-fatal heads up about how a field will convert
+type PayloadWarning struct {
+	Path        string // dotted field path, with [i] for array elements
+	Kind        PayloadWarningKind
+	Description string
+}
+
+// ===== analysis =====
+
+// walks a payload and reports fields that will convert lossily or surprisingly, without converting it
+//
+// meant for dev tooling that wants to warn before something gets signed
+func AnalyzePayload(data interface{}) ([]PayloadWarning, error) {
+	// normalize structs etc. into a map the same way ConvertToEIP712TypedData does
+	dataMap, ok := stringKeyedMap(data)
+	if !ok {
+		dataMap, ok = wrapTopLevelArray(data)
+	}
+	if !ok {
+		jsonBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		decoded, err := decodeJSONExact(jsonBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload into map: %w", err)
+		}
+		if dataMap, ok = decoded.(map[string]interface{}); !ok && decoded != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload into map: %T payload isn't a JSON object", data)
+		}
+	}
+
+	warnings := []PayloadWarning{}
+	analyzeValue("", dataMap, &warnings)
+	return warnings, nil
+}
+
+func analyzeValue(path string, value interface{}, warnings *[]PayloadWarning) {
+	warn := func(kind PayloadWarningKind, format string, args ...interface{}) {
+		*warnings = append(*warnings, PayloadWarning{Path: path, Kind: kind, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		warn(WarnUnsupportedType, "nil values can't be typed")
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// sorted, so warnings come out in a stable order
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			analyzeValue(joinFieldPath(path, key), v[key], warnings)
+		}
+		return
+
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			warn(WarnFloat, "%v can't be represented as an integer", v)
+		} else if v != math.Trunc(v) {
+			warn(WarnFloat, "fractional value %v will be passed through the float handler", v)
+		} else if math.Abs(v) > 1<<53 {
+			warn(WarnImpreciseNumber, "%v exceeds 2^53 and may have lost precision as a float64", v)
+		} else {
+			warn(WarnFloat, "whole number %v is a float64 and will be passed through the float handler", v)
+		}
+		return
+
+	case float32:
+		analyzeValue(path, float64(v), warnings)
+		return
+
+	case string:
+		if isEthAddr(v) {
+			warn(WarnAddressCoercion, "%q will be typed as address, not string", v)
+		} else if strings.HasPrefix(v, "0x") && len(v) >= 40 && len(v) <= 44 {
+			warn(WarnAddressLookalike, "%q looks like an address but isn't one, so will stay a string", v)
+		}
+		return
+
+	case json.RawMessage:
+		// converted as whatever it decodes to
+		decoded, err := decodeRawMessage(v)
+		if err != nil {
+			warn(WarnUnsupportedType, "json.RawMessage isn't valid JSON: %v", err)
+			return
+		}
+		analyzeValue(path, decoded, warnings)
+		return
+
+	case []byte:
+		// bytes are fine as-is
+		return
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			warn(WarnEmptyArray, "empty arrays are typed as undefined[]")
+			return
+		}
+		firstType := reflect.TypeOf(rv.Index(0).Interface())
+		for i := 0; i < rv.Len(); i++ {
+			elem := rv.Index(i).Interface()
+			if reflect.TypeOf(elem) != firstType {
+				warn(WarnMixedArray, "element %d is %T but the array is typed from its first element (%v)", i, elem, firstType)
+			}
+			analyzeValue(fmt.Sprintf("%s[%d]", path, i), elem, warnings)
+		}
+
+	case reflect.Map:
+		// string keyed maps of any value type convert like map[string]interface{}
+		m, ok := stringKeyedMap(value)
+		if !ok {
+			warn(WarnUnsupportedType, "%T maps can't be typed, only maps with string keys", value)
+			return
+		}
+		analyzeValue(path, m, warnings)
+
+	case reflect.Func, reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		warn(WarnUnsupportedType, "%s values can't be typed", rv.Kind())
+	}
+}
@@ -0,0 +1,66 @@
+package dids_test
+
+import (
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzePayload(t *testing.T) {
+	data := map[string]interface{}{
+		"op": "transfer",
+		"tx": map[string]interface{}{
+			"amount": 1.5,
+			"wallet": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		"nonce": uint64(1),
+	}
+
+	warnings, err := dids.AnalyzePayload(data)
+	assert.Nil(t, err)
+
+	// only the float and the 0x string are worth a warning
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, "tx.amount", warnings[0].Path)
+	assert.Equal(t, dids.WarnFloat, warnings[0].Kind)
+	assert.NotEmpty(t, warnings[0].Description)
+	assert.Equal(t, "tx.wallet", warnings[1].Path)
+	assert.Equal(t, dids.WarnAddressCoercion, warnings[1].Kind)
+}
+
+func TestAnalyzePayloadArrays(t *testing.T) {
+	data := map[string]interface{}{
+		"intents": []interface{}{},
+		"mixed":   []interface{}{"a", 2},
+		"big":     []interface{}{float64(1 << 60)},
+	}
+
+	warnings, err := dids.AnalyzePayload(data)
+	assert.Nil(t, err)
+
+	kinds := map[string]dids.PayloadWarningKind{}
+	for _, warning := range warnings {
+		kinds[warning.Path] = warning.Kind
+	}
+	assert.Equal(t, dids.WarnImpreciseNumber, kinds["big[0]"])
+	assert.Equal(t, dids.WarnEmptyArray, kinds["intents"])
+	assert.Equal(t, dids.WarnMixedArray, kinds["mixed"])
+}
+
+func TestAnalyzePayloadTypedMaps(t *testing.T) {
+	data := map[string]interface{}{
+		"labels": map[string]string{"wallet": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC", "memo": "rent"},
+		"ids":    map[int]string{1: "a"},
+	}
+
+	warnings, err := dids.AnalyzePayload(data)
+	assert.Nil(t, err)
+
+	// typed maps are walked like any other, and only non-string keys are unsupported
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, "ids", warnings[0].Path)
+	assert.Equal(t, dids.WarnUnsupportedType, warnings[0].Kind)
+	assert.Equal(t, "labels.wallet", warnings[1].Path)
+	assert.Equal(t, dids.WarnAddressCoercion, warnings[1].Kind)
+}
@@ -0,0 +1,93 @@
+package dids
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ===== errors =====
+
+var ErrSignerNotInAuths = fmt.Errorf("signer is not listed in the payload's required_auths")
+
+// ===== required_auths guard =====
+
+// a copy of the provider that refuses to sign payloads whose required_auths don't list its own address, since
+// the protocol would reject the sig anyway. the provider it's called on is left as it was
+//
+// auths are matched in any form vsc uses: a did:pkh DID (on any chain) or a bare address, in any case
+func (e *EthProvider) WithRequireSelfInAuths() *EthProvider {
+	guarded := *e
+	guarded.convertOpts = slices.Clone(e.convertOpts)
+	guarded.requireSelfInAuths = true
+	return &guarded
+}
+
+// errors with ErrSignerNotInAuths unless the payload's required_auths include the provider's address
+//
+// the provider must have a key
+func (e *EthProvider) checkSelfInAuths(data interface{}) error {
+	if !e.requireSelfInAuths {
+		return nil
+	}
+
+	auths, err := requiredAuths(data)
+	if err != nil {
+		return err
+	}
+
+	self := strings.ToLower(crypto.PubkeyToAddress(e.privKey.PublicKey).Hex())
+	for _, auth := range auths {
+		// auths that aren't EVM addresses (hive accounts, key DIDs) just can't be us
+		if address, err := AddressFromDID(auth); err == nil && address == self {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSignerNotInAuths, self)
+}
+
+// ===== utils =====
+
+// the required_auths of a payload, from headers.required_auths or else a top-level required_auths
+func requiredAuths(data interface{}) ([]string, error) {
+	dataMap, ok := data.(map[string]interface{})
+	if !ok {
+		// structs etc. are read the same way ConvertToEIP712TypedData falls back to
+		jsonBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		if err := json.Unmarshal(jsonBytes, &dataMap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload into map: %w", err)
+		}
+	}
+
+	raw, ok := dataMap["required_auths"]
+	if headers, isMap := dataMap["headers"].(map[string]interface{}); isMap {
+		if headerAuths, found := headers["required_auths"]; found {
+			raw, ok = headerAuths, true
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	switch auths := raw.(type) {
+	case []string:
+		return auths, nil
+	case []interface{}:
+		authStrs := make([]string, len(auths))
+		for i, auth := range auths {
+			authStr, ok := auth.(string)
+			if !ok {
+				return nil, fmt.Errorf("required_auths: expected strings, got %T", auth)
+			}
+			authStrs[i] = authStr
+		}
+		return authStrs, nil
+	}
+	return nil, fmt.Errorf("required_auths: expected a list of strings, got %T", raw)
+}
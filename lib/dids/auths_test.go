@@ -0,0 +1,46 @@
+package dids_test
+
+import (
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthProviderRequireSelfInAuths(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	unguarded := dids.NewEthProviderFromKey(privateKey)
+	provider := unguarded.WithRequireSelfInAuths()
+
+	withAuths := func(auths ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"tx":      map[string]interface{}{"op": "transfer"},
+			"headers": map[string]interface{}{"required_auths": auths},
+		}
+	}
+
+	// listed, in any of the forms vsc uses
+	for _, auth := range []string{
+		"did:pkh:eip155:1:" + address,
+		"did:pkh:eip155:1:" + strings.ToLower(address),
+		address,
+	} {
+		sig, err := provider.SignData(withAuths("hive:alice", auth))
+		assert.Nil(t, err, auth)
+		assert.NotEmpty(t, sig)
+	}
+
+	// not listed (or no auths at all)
+	_, err = provider.SignData(withAuths("hive:alice", "did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22"))
+	assert.ErrorIs(t, err, dids.ErrSignerNotInAuths)
+	_, err = provider.SignData(map[string]interface{}{"tx": map[string]interface{}{"op": "transfer"}})
+	assert.ErrorIs(t, err, dids.ErrSignerNotInAuths)
+
+	// the guard is opt-in, and only on the copy it was turned on for
+	_, err = unguarded.SignData(withAuths("hive:alice"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,261 @@
+package dids
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/multiformats/go-multibase"
+)
+
+// ===== constants =====
+
+// the IETF BLS ciphersuite consensus sigs use: min-pk (G1 pub keys, G2 sigs), with proof of possession
+const blsDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+
+// varint encoded multicodec prefix of a bls12_381-g1-pub (0xea) key
+var blsKeyPrefix = []byte{0xEA, 0x01}
+
+// ===== errors =====
+
+var ErrBlsIdentityPoint = fmt.Errorf("BLS point is the identity")
+
+// ===== interface assertions =====
+
+var _ IdentifiedDID[*bls12381.G1Affine] = BlsDID("")
+var _ Provider = BlsProvider{}
+
+// ===== BlsDID =====
+
+// a did:key for a BLS12-381 G1 pub key
+//
+// sigs are compressed G2 points over the block's CID bytes, as base64url (no padding)
+type BlsDID string
+
+func NewBlsDID(pubKey *bls12381.G1Affine) (BlsDID, error) {
+	if pubKey == nil || pubKey.IsInfinity() {
+		return BlsDID(""), fmt.Errorf("invalid public key")
+	}
+
+	pubKeyBytes := pubKey.Bytes()
+	data := append(append([]byte{}, blsKeyPrefix...), pubKeyBytes[:]...)
+
+	base58Encoded, err := multibase.Encode(multibase.Base58BTC, data)
+	if err != nil {
+		return BlsDID(""), err
+	}
+
+	return BlsDID(KeyDIDPrefix + base58Encoded), nil
+}
+
+// ===== implementing the DID interface =====
+
+func (d BlsDID) String() string {
+	return string(d)
+}
+
+// the DID's pub key, or nil if the DID doesn't hold a valid BLS12-381 G1 key
+func (d BlsDID) Identifier() *bls12381.G1Affine {
+	pubKey, err := d.pubKey()
+	if err != nil {
+		return nil
+	}
+	return pubKey
+}
+
+// BlsDIDs sign the raw CID bytes: BLS hashes the message to G2 itself (see blsDST), so it takes no pre-hashing
+func (d BlsDID) PreHash() PreHash {
+	return PreHashNone
+}
+
+func (d BlsDID) Verify(block blocks.Block, sig string) (bool, error) {
+	pubKey, err := d.pubKey()
+	if err != nil {
+		return false, err
+	}
+	return verifyBLS(pubKey, block.Cid().Bytes(), sig)
+}
+
+// ===== aggregation =====
+
+// verifies one aggregate sig (see AggregateBlsSignatures) made by every one of the DIDs over the block
+//
+// the pub keys are summed, so this is only safe against rogue key attacks when every DID has proven possession
+// of its key beforehand (e.g. when it was registered as a consensus member)
+func AggregateVerify(dids []BlsDID, block blocks.Block, aggSig string) (bool, error) {
+	if len(dids) == 0 {
+		return false, fmt.Errorf("no signers to verify against")
+	}
+
+	// signers are told apart by key rather than DID string, so one key can't be counted twice
+	seen := make(map[[bls12381.SizeOfG1AffineCompressed]byte]bool, len(dids))
+	var aggregated bls12381.G1Jac
+	for _, did := range dids {
+		pubKey, err := did.pubKey()
+		if err != nil {
+			return false, err
+		}
+
+		pubKeyBytes := pubKey.Bytes()
+		if seen[pubKeyBytes] {
+			return false, fmt.Errorf("signer %s is listed more than once", did)
+		}
+		seen[pubKeyBytes] = true
+		aggregated.AddMixed(pubKey)
+	}
+
+	// keys that cancel out (like pk and -pk) sum to the identity, which the identity sig would verify against for
+	// any block
+	var aggregatedPubKey bls12381.G1Affine
+	aggregatedPubKey.FromJacobian(&aggregated)
+	if aggregatedPubKey.IsInfinity() {
+		return false, fmt.Errorf("%w: the signers' keys cancel out", ErrBlsIdentityPoint)
+	}
+	return verifyBLS(&aggregatedPubKey, block.Cid().Bytes(), aggSig)
+}
+
+// sums sigs over the same block into the single sig AggregateVerify checks
+func AggregateBlsSignatures(sigs []string) (string, error) {
+	if len(sigs) == 0 {
+		return "", fmt.Errorf("no signatures to aggregate")
+	}
+
+	var aggregated bls12381.G2Jac
+	for i, sig := range sigs {
+		point, err := decodeBLSSig(sig)
+		if err != nil {
+			return "", fmt.Errorf("signature %d: %w", i, err)
+		}
+		aggregated.AddMixed(point)
+	}
+
+	var aggregatedSig bls12381.G2Affine
+	aggregatedSig.FromJacobian(&aggregated)
+	return encodeBLSSig(&aggregatedSig), nil
+}
+
+// ===== BlsProvider =====
+
+type BlsProvider struct {
+	secretKey *big.Int
+}
+
+// creates a provider from a 32 byte big-endian secret key, which must be non-zero and below the group order
+func NewBlsProvider(secretKey []byte) (BlsProvider, error) {
+	if len(secretKey) != fr.Bytes {
+		return BlsProvider{}, fmt.Errorf("secret key must be %d bytes, got %d", fr.Bytes, len(secretKey))
+	}
+	scalar := new(big.Int).SetBytes(secretKey)
+	if scalar.Sign() == 0 || scalar.Cmp(fr.Modulus()) >= 0 {
+		return BlsProvider{}, fmt.Errorf("secret key is out of range")
+	}
+	return BlsProvider{secretKey: scalar}, nil
+}
+
+// creates a provider with a random secret key
+func GenerateBlsProvider() (BlsProvider, error) {
+	scalar, err := rand.Int(rand.Reader, new(big.Int).Sub(fr.Modulus(), big.NewInt(1)))
+	if err != nil {
+		return BlsProvider{}, err
+	}
+	return BlsProvider{secretKey: scalar.Add(scalar, big.NewInt(1))}, nil
+}
+
+// the DID for this provider's key
+func (p BlsProvider) DID() (BlsDID, error) {
+	_, _, g1, _ := bls12381.Generators()
+	var pubKey bls12381.G1Affine
+	pubKey.ScalarMultiplication(&g1, p.secretKey)
+	return NewBlsDID(&pubKey)
+}
+
+// ===== implementing the Provider interface =====
+
+func (p BlsProvider) Sign(block blocks.Block) (string, error) {
+	if p.secretKey == nil {
+		return "", fmt.Errorf("BLS provider has no secret key")
+	}
+
+	point, err := bls12381.HashToG2(block.Cid().Bytes(), []byte(blsDST))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash block to G2: %w", err)
+	}
+
+	var sig bls12381.G2Affine
+	sig.ScalarMultiplication(&point, p.secretKey)
+	return encodeBLSSig(&sig), nil
+}
+
+// ===== utils =====
+
+// the DID's pub key, checked to be a valid (subgroup, non-identity) G1 point
+func (d BlsDID) pubKey() (*bls12381.G1Affine, error) {
+	algorithm, keyBytes, err := KeyDID(d).KeyMaterial()
+	if err != nil {
+		return nil, err
+	}
+	if algorithm != KeyAlgorithmBLS12381G1 {
+		return nil, fmt.Errorf("DID %q holds a %s key, not a BLS12-381 G1 one", d, algorithm)
+	}
+
+	var pubKey bls12381.G1Affine
+	if _, err := pubKey.SetBytes(keyBytes); err != nil {
+		return nil, fmt.Errorf("invalid BLS public key: %w", err)
+	}
+	if pubKey.IsInfinity() {
+		return nil, fmt.Errorf("invalid BLS public key: identity point")
+	}
+	return &pubKey, nil
+}
+
+// checks e(pubKey, H(msg)) == e(g1, sig)
+//
+// an identity pub key or sig makes both sides 1 whatever the message, so either is rejected outright
+func verifyBLS(pubKey *bls12381.G1Affine, msg []byte, sig string) (bool, error) {
+	if pubKey.IsInfinity() {
+		return false, fmt.Errorf("%w: public key", ErrBlsIdentityPoint)
+	}
+	sigPoint, err := decodeBLSSig(sig)
+	if err != nil {
+		return false, err
+	}
+	if sigPoint.IsInfinity() {
+		return false, fmt.Errorf("%w: signature", ErrBlsIdentityPoint)
+	}
+
+	hashed, err := bls12381.HashToG2(msg, []byte(blsDST))
+	if err != nil {
+		return false, fmt.Errorf("failed to hash message to G2: %w", err)
+	}
+
+	_, _, g1, _ := bls12381.Generators()
+	var negG1 bls12381.G1Affine
+	negG1.Neg(&g1)
+
+	return bls12381.PairingCheck([]bls12381.G1Affine{*pubKey, negG1}, []bls12381.G2Affine{hashed, *sigPoint})
+}
+
+func decodeBLSSig(sig string) (*bls12381.G2Affine, error) {
+	sigBytes, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if len(sigBytes) != bls12381.SizeOfG2AffineCompressed {
+		return nil, fmt.Errorf("BLS signature must be %d bytes, got %d", bls12381.SizeOfG2AffineCompressed, len(sigBytes))
+	}
+
+	var point bls12381.G2Affine
+	if _, err := point.SetBytes(sigBytes); err != nil {
+		return nil, fmt.Errorf("invalid BLS signature: %w", err)
+	}
+	return &point, nil
+}
+
+func encodeBLSSig(sig *bls12381.G2Affine) string {
+	sigBytes := sig.Bytes()
+	return base64.RawURLEncoding.EncodeToString(sigBytes[:])
+}
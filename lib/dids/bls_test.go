@@ -0,0 +1,178 @@
+package dids_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"vsc-node/lib/dids"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/multiformats/go-multibase"
+	"github.com/stretchr/testify/assert"
+)
+
+func blsTestBlock(t *testing.T, data map[string]interface{}) blocks.Block {
+	block, err := dids.EncodeBlock(data)
+	assert.Nil(t, err)
+	return block
+}
+
+func blsTestSigner(t *testing.T) (dids.BlsProvider, dids.BlsDID) {
+	provider, err := dids.GenerateBlsProvider()
+	assert.Nil(t, err)
+	did, err := provider.DID()
+	assert.Nil(t, err)
+	return provider, did
+}
+
+func TestBlsDIDVerify(t *testing.T) {
+	block := blsTestBlock(t, map[string]interface{}{"foo": "bar"})
+	provider, did := blsTestSigner(t)
+
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+
+	valid, err := did.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the same sig doesn't cover another block
+	other := blsTestBlock(t, map[string]interface{}{"foo": "baz"})
+	valid, err = did.Verify(other, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// BLS key DIDs parse as BlsDIDs
+	parsed, err := dids.ParseDID(did.String())
+	assert.Nil(t, err)
+	assert.Equal(t, did, parsed)
+	assert.NotNil(t, did.Identifier())
+}
+
+func TestBlsAggregateVerify(t *testing.T) {
+	block := blsTestBlock(t, map[string]interface{}{"foo": "bar", "baz": 12345})
+	alice, aliceDID := blsTestSigner(t)
+	bob, bobDID := blsTestSigner(t)
+	_, carolDID := blsTestSigner(t)
+
+	aliceSig, err := alice.Sign(block)
+	assert.Nil(t, err)
+	bobSig, err := bob.Sign(block)
+	assert.Nil(t, err)
+
+	aggSig, err := dids.AggregateBlsSignatures([]string{aliceSig, bobSig})
+	assert.Nil(t, err)
+
+	valid, err := dids.AggregateVerify([]dids.BlsDID{aliceDID, bobDID}, block, aggSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// signer order doesn't matter
+	valid, err = dids.AggregateVerify([]dids.BlsDID{bobDID, aliceDID}, block, aggSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// swapping a signer for one who didn't sign fails
+	valid, err = dids.AggregateVerify([]dids.BlsDID{aliceDID, carolDID}, block, aggSig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// as does leaving one out
+	valid, err = dids.AggregateVerify([]dids.BlsDID{aliceDID}, block, aggSig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	_, err = dids.AggregateVerify([]dids.BlsDID{aliceDID, aliceDID}, block, aggSig)
+	assert.NotNil(t, err)
+	_, err = dids.AggregateVerify(nil, block, aggSig)
+	assert.NotNil(t, err)
+}
+
+func TestNewBlsProviderRejectsBadKeys(t *testing.T) {
+	_, err := dids.NewBlsProvider(make([]byte, 31))
+	assert.NotNil(t, err)
+
+	_, err = dids.NewBlsProvider(make([]byte, 32))
+	assert.NotNil(t, err)
+
+	secretKey := make([]byte, 32)
+	secretKey[31] = 1
+	provider, err := dids.NewBlsProvider(secretKey)
+	assert.Nil(t, err)
+
+	// sk = 1 makes the pub key the G1 generator
+	did, err := provider.DID()
+	assert.Nil(t, err)
+	pubKey := did.Identifier().Bytes()
+	assert.Equal(t, "97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb", hex.EncodeToString(pubKey[:]))
+}
+
+func TestBlsAggregateVerifyOneKeyTwoEncodings(t *testing.T) {
+	block := blsTestBlock(t, map[string]interface{}{"foo": "bar"})
+	provider, did := blsTestSigner(t)
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+
+	// the same key as a base16 (f) multibase did:key
+	pubKey := did.Identifier().Bytes()
+	base16, err := multibase.Encode(multibase.Base16, append([]byte{0xEA, 0x01}, pubKey[:]...))
+	assert.Nil(t, err)
+	otherForm := dids.BlsDID(dids.KeyDIDPrefix + base16)
+
+	// a sig added to itself doesn't pass for two signers when they're one key
+	doubled, err := dids.AggregateBlsSignatures([]string{sig, sig})
+	assert.Nil(t, err)
+	valid, err := dids.AggregateVerify([]dids.BlsDID{did, otherForm}, block, doubled)
+	assert.ErrorIs(t, err, dids.ErrNotBase58BTC)
+	assert.False(t, valid)
+
+	// nor does that form parse or verify on its own
+	_, err = dids.ParseDID(otherForm.String())
+	assert.ErrorIs(t, err, dids.ErrNotBase58BTC)
+	_, err = otherForm.Verify(block, sig)
+	assert.ErrorIs(t, err, dids.ErrNotBase58BTC)
+}
+
+func TestBlsAggregateVerifyRejectsIdentity(t *testing.T) {
+	block := blsTestBlock(t, map[string]interface{}{"foo": "bar"})
+
+	// sk and r - sk give pk and -pk, which sum to the identity
+	order, ok := new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+	assert.True(t, ok)
+	secretKey := big.NewInt(1234567)
+	provider, err := dids.NewBlsProvider(secretKey.FillBytes(make([]byte, 32)))
+	assert.Nil(t, err)
+	negProvider, err := dids.NewBlsProvider(new(big.Int).Sub(order, secretKey).FillBytes(make([]byte, 32)))
+	assert.Nil(t, err)
+	did, err := provider.DID()
+	assert.Nil(t, err)
+	negDID, err := negProvider.DID()
+	assert.Nil(t, err)
+
+	// the compressed identity in G2, which is also what their two sigs sum to
+	identitySig := base64.RawURLEncoding.EncodeToString(append([]byte{0xC0}, make([]byte, 95)...))
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+	negSig, err := negProvider.Sign(block)
+	assert.Nil(t, err)
+	summed, err := dids.AggregateBlsSignatures([]string{sig, negSig})
+	assert.Nil(t, err)
+	assert.Equal(t, identitySig, summed)
+
+	// without either of them signing this block
+	otherBlock := blsTestBlock(t, map[string]interface{}{"foo": "baz"})
+	for _, b := range []blocks.Block{block, otherBlock} {
+		valid, err := dids.AggregateVerify([]dids.BlsDID{did, negDID}, b, identitySig)
+		assert.ErrorIs(t, err, dids.ErrBlsIdentityPoint)
+		assert.False(t, valid)
+	}
+
+	// and the identity sig isn't valid for a single signer either
+	valid, err := did.Verify(block, identitySig)
+	assert.ErrorIs(t, err, dids.ErrBlsIdentityPoint)
+	assert.False(t, valid)
+	valid, err = dids.AggregateVerify([]dids.BlsDID{did}, block, identitySig)
+	assert.ErrorIs(t, err, dids.ErrBlsIdentityPoint)
+	assert.False(t, valid)
+}
@@ -0,0 +1,126 @@
+package dids
+
+import (
+	"fmt"
+)
+
+// ===== errors =====
+
+var ErrDuplicateField = fmt.Errorf("field is already set")
+
+// ===== TypedDataBuilder =====
+
+// builds typed data field by field, for application code that assembles a payload incrementally instead of
+// as a whole map
+//
+// Build converts exactly as ConvertToEIP712TypedData does for the equivalent map (fields sorted by name, nested
+// builders as nested structs), so the two always give the same typed data. the setters chain, and the first
+// mistake (like a repeated field) is held and returned by Build
+type TypedDataBuilder struct {
+	domainName  string
+	primaryType string
+	opts        []ConvertOption
+
+	fields map[string]interface{}
+	err    error
+}
+
+// a builder for vsc's domain and primary type, converting with opts (e.g. WithChainID, WithFloatHandler)
+func NewTypedDataBuilder(opts ...ConvertOption) *TypedDataBuilder {
+	return &TypedDataBuilder{
+		domainName:  vscDomainName,
+		primaryType: vscPrimaryType,
+		opts:        opts,
+		fields:      make(map[string]interface{}),
+	}
+}
+
+// sets the EIP-712 domain name (vsc.network by default)
+func (b *TypedDataBuilder) SetDomain(domainName string) *TypedDataBuilder {
+	b.domainName = domainName
+	return b
+}
+
+// sets the primary type name (tx_container_v0 by default)
+func (b *TypedDataBuilder) SetPrimaryType(primaryType string) *TypedDataBuilder {
+	b.primaryType = primaryType
+	return b
+}
+
+// adds a field, converted like the same value in a map payload
+func (b *TypedDataBuilder) AddField(name string, value interface{}) *TypedDataBuilder {
+	b.setField(name, value)
+	return b
+}
+
+// adds a nested struct built from another builder's fields
+//
+// only the sub builder's fields are used (its domain, primary type and options don't apply), and they're read
+// when Build is called, so it can still be filled in until then
+func (b *TypedDataBuilder) AddNested(name string, sub *TypedDataBuilder) *TypedDataBuilder {
+	if sub == nil {
+		b.fail(fmt.Errorf("nested builder for field %q is nil", name))
+		return b
+	}
+	b.setField(name, sub)
+	return b
+}
+
+// converts the fields into typed data
+func (b *TypedDataBuilder) Build() (TypedData, error) {
+	data, err := b.data(make(map[*TypedDataBuilder]bool))
+	if err != nil {
+		return TypedData{}, err
+	}
+	return ConvertToEIP712TypedData(b.domainName, data, b.primaryType, defaultFloatHandler, b.opts...)
+}
+
+// ===== utils =====
+
+func (b *TypedDataBuilder) setField(name string, value interface{}) {
+	if name == "" {
+		b.fail(fmt.Errorf("field name cannot be empty"))
+		return
+	}
+	if _, ok := b.fields[name]; ok {
+		b.fail(fmt.Errorf("%w: %q", ErrDuplicateField, name))
+		return
+	}
+	b.fields[name] = value
+}
+
+// keeps the first error, which is the one worth reporting
+func (b *TypedDataBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// the builder's fields as the map payload they stand for, with nested builders turned into maps
+//
+// building tracks the builders being expanded, since one nested inside itself would never finish
+func (b *TypedDataBuilder) data(building map[*TypedDataBuilder]bool) (map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if building[b] {
+		return nil, fmt.Errorf("builder is nested inside itself")
+	}
+	building[b] = true
+	defer delete(building, b)
+
+	data := make(map[string]interface{}, len(b.fields))
+	for name, value := range b.fields {
+		sub, ok := value.(*TypedDataBuilder)
+		if !ok {
+			data[name] = value
+			continue
+		}
+		nested, err := sub.data(building)
+		if err != nil {
+			return nil, fmt.Errorf("nested field %q: %w", name, err)
+		}
+		data[name] = nested
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package dids_test
+
+import (
+	"math/big"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedDataBuilderMatchesMap(t *testing.T) {
+	payload := dids.NewTypedDataBuilder().
+		AddField("to", "hive:bob").
+		AddField("amount", 1000).
+		AddField("tk", "HIVE")
+	built, err := dids.NewTypedDataBuilder(dids.WithChainID(1)).
+		SetDomain("vsc.network").
+		SetPrimaryType("tx_container_v0").
+		AddField("op", "transfer").
+		AddField("nonce", uint64(7)).
+		AddField("required_auths", []string{"did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22"}).
+		AddNested("payload", payload).
+		Build()
+	assert.Nil(t, err)
+
+	converted, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"op":             "transfer",
+		"nonce":          uint64(7),
+		"required_auths": []string{"did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22"},
+		"payload": map[string]interface{}{
+			"to":     "hive:bob",
+			"amount": 1000,
+			"tk":     "HIVE",
+		},
+	}, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}, dids.WithChainID(1))
+	assert.Nil(t, err)
+
+	builtJSON, err := built.MarshalJSON()
+	assert.Nil(t, err)
+	convertedJSON, err := converted.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, string(convertedJSON), string(builtJSON))
+
+	builtHash, err := dids.ComputeEIP712Hash(built)
+	assert.Nil(t, err)
+	convertedHash, err := dids.ComputeEIP712Hash(converted)
+	assert.Nil(t, err)
+	assert.Equal(t, convertedHash, builtHash)
+}
+
+func TestTypedDataBuilderErrors(t *testing.T) {
+	_, err := dids.NewTypedDataBuilder().AddField("op", "transfer").AddField("op", "stake").Build()
+	assert.ErrorIs(t, err, dids.ErrDuplicateField)
+
+	// a mistake in a nested builder comes out of the outer Build
+	nested := dids.NewTypedDataBuilder().AddField("", 1)
+	_, err = dids.NewTypedDataBuilder().AddNested("payload", nested).Build()
+	assert.ErrorContains(t, err, "payload")
+
+	_, err = dids.NewTypedDataBuilder().AddNested("payload", nil).Build()
+	assert.NotNil(t, err)
+
+	// as does a builder nested inside itself
+	loop := dids.NewTypedDataBuilder()
+	loop.AddNested("self", loop)
+	_, err = loop.Build()
+	assert.NotNil(t, err)
+
+	// and conversion errors
+	_, err = dids.NewTypedDataBuilder().SetDomain("").AddField("op", "transfer").Build()
+	assert.ErrorIs(t, err, dids.ErrEmptyDomain)
+}
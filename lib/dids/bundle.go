@@ -0,0 +1,222 @@
+package dids
+
+import (
+	"fmt"
+	"strings"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== types =====
+
+// a sig along with the DID that made it
+type DIDSig struct {
+	DID DID
+	Sig string
+}
+
+// the sigs collected so far over a single block, possibly from several peers
+type SignatureBundle struct {
+	Sigs []DIDSig
+}
+
+// ===== merging =====
+
+// folds other bundles into this one, keeping one entry per (signer, sig) pair
+//
+// a signer appearing with the same sig (in any hex formatting) is collapsed to its first entry. a signer
+// appearing with different sigs keeps all of them: at most one can be valid for the block, but a bundle doesn't
+// know the block, and dropping either could throw away the real sig in favour of a forged one. verifying (say,
+// with VerifyWeightedThreshold) skips the ones that don't check out. if an entry has no DID the bundle is left
+// unchanged
+func (b *SignatureBundle) Merge(others ...SignatureBundle) error {
+	merged := SignatureBundle{Sigs: append([]DIDSig{}, b.Sigs...)}
+	for _, other := range others {
+		merged.Sigs = append(merged.Sigs, other.Sigs...)
+	}
+
+	for _, entry := range merged.Sigs {
+		if entry.DID == nil {
+			return fmt.Errorf("bundle entry has no DID")
+		}
+	}
+
+	merged.Dedup()
+	b.Sigs = merged.Sigs
+	return nil
+}
+
+// removes repeated (signer, sig) pairs, keeping the first of each in order
+//
+// the result is a new slice, so a slice the bundle was built from (and may still share with the caller) is left
+// as it was
+func (b *SignatureBundle) Dedup() {
+	seen := make(map[[2]string]bool)
+	deduped := make([]DIDSig, 0, len(b.Sigs))
+	for _, entry := range b.Sigs {
+		signer := ""
+		if entry.DID != nil {
+			signer = entry.DID.String()
+		}
+		pair := [2]string{signer, sigKey(entry.Sig)}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		deduped = append(deduped, entry)
+	}
+	b.Sigs = deduped
+}
+
+// ===== thresholds =====
+
+// checks whether the signers with valid sigs over the block carry at least threshold weight between them
+//
+// weights are keyed by DID string, and signers missing from weights count for nothing. a signer is looked up and
+// counted by who they sign as (see signerKey), so differently cased or chained DIDs for one EVM address, or
+// encodings of one did:key, are one signer whichever form weights or sigs use. each signer's weight is counted
+// once however many sigs they appear with, and sigs that don't verify are skipped rather than failing the whole
+// set. the summed weight is returned either way, for reporting how close a block is
+func VerifyWeightedThreshold(block blocks.Block, sigs []DIDSig, weights map[string]int, threshold int) (bool, int, error) {
+	if threshold <= 0 {
+		return false, 0, fmt.Errorf("threshold must be positive, got %d", threshold)
+	}
+
+	// a signer weighted under two forms of their DID would be ambiguous
+	signerWeights := make(map[string]int, len(weights))
+	for did, weight := range weights {
+		signer := did
+		if parsed, err := ParseDID(did); err == nil {
+			signer = signerKey(parsed)
+		}
+		if _, ok := signerWeights[signer]; ok {
+			return false, 0, fmt.Errorf("weights list signer %s more than once", did)
+		}
+		signerWeights[signer] = weight
+	}
+
+	counted := make(map[string]bool)
+	totalWeight := 0
+	for _, entry := range sigs {
+		if entry.DID == nil {
+			return false, 0, fmt.Errorf("signature entry has no DID")
+		}
+		signer := signerKey(entry.DID)
+		if counted[signer] {
+			continue
+		}
+
+		weight, ok := signerWeights[signer]
+		if !ok || weight <= 0 {
+			continue
+		}
+
+		valid, err := entry.DID.Verify(block, entry.Sig)
+		if err != nil || !valid {
+			continue
+		}
+
+		counted[signer] = true
+		totalWeight += weight
+	}
+
+	return totalWeight >= threshold, totalWeight, nil
+}
+
+// checks whether at least m distinct DIDs from required have valid sigs over the block, for M-of-N authorization
+// like a tx's required_auths
+//
+// sigs are keyed by DID string. a signer is counted once however many times they're listed, including as
+// differently cased or chained DIDs for the same EVM address, and sigs that don't verify are skipped rather than
+// failing the whole set
+func VerifyThreshold(block blocks.Block, required []DID, sigs map[string]string, m int) (bool, error) {
+	if m <= 0 {
+		return false, fmt.Errorf("threshold must be positive, got %d", m)
+	}
+
+	counted := make(map[string]bool)
+	for _, did := range required {
+		if did == nil {
+			return false, fmt.Errorf("required signer has no DID")
+		}
+		signer := signerKey(did)
+		if counted[signer] {
+			continue
+		}
+
+		sig, ok := sigs[did.String()]
+		if !ok {
+			continue
+		}
+		valid, err := did.Verify(block, sig)
+		if err != nil || !valid {
+			continue
+		}
+
+		counted[signer] = true
+		if len(counted) >= m {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ===== any-of =====
+
+// verifies a sig made by any one of several allowed DIDs (say, the old and new key during a rotation), returning
+// the first in allowed it's valid for
+//
+// the block's EIP-712 hash is built once and shared by every EthDID, rather than once per DID as calling Verify on
+// each would. a DID the sig doesn't verify for (or is malformed for) is just skipped, so the error is only for a
+// nil DID or a block EthDIDs can't hash at all
+func VerifyAnyOf(block blocks.Block, allowed []DID, sig string) (DID, bool, error) {
+	verifyOpts := newVerifyOptions(nil)
+	var dataHash []byte
+
+	for _, did := range allowed {
+		if did == nil {
+			return nil, false, fmt.Errorf("allowed signer has no DID")
+		}
+
+		ethDID, ok := did.(EthDID)
+		if !ok {
+			if valid, err := did.Verify(block, sig); err == nil && valid {
+				return did, true, nil
+			}
+			continue
+		}
+
+		if dataHash == nil {
+			var err error
+			if _, dataHash, err = blockTypedDataHash(block, verifyOpts); err != nil {
+				return nil, false, err
+			}
+		}
+		if result, err := ethDID.verifyHash(dataHash, sig, verifyOpts); err == nil && result.Valid {
+			return did, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// ===== utils =====
+
+// the form sigs are compared on: canonical hex for hex sigs, otherwise the trimmed sig (e.g. a JWS)
+func sigKey(sig string) string {
+	if canonical, err := CanonicalSigHex(sig); err == nil {
+		return canonical
+	}
+	return strings.TrimSpace(sig)
+}
+
+// who a DID signs as: its address for EVM DIDs, so every form of one account is the same signer, its decoded key
+// for did:keys, so every encoding of one key is too, otherwise the DID
+func signerKey(did DID) string {
+	if address, err := AddressFromDID(did.String()); err == nil {
+		return address
+	}
+	if algorithm, keyBytes, err := KeyDID(did.String()).KeyMaterial(); err == nil {
+		return fmt.Sprintf("%s:%x", algorithm, keyBytes)
+	}
+	return did.String()
+}
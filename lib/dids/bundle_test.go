@@ -0,0 +1,305 @@
+package dids_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/multiformats/go-multibase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureBundleMerge(t *testing.T) {
+	alice := dids.NewEthDID("0x00000000000000000000000000000000000000a1")
+	bob := dids.NewEthDID("0x00000000000000000000000000000000000000b2")
+	carol := dids.NewEthDID("0x00000000000000000000000000000000000000c3")
+
+	fromPeerA := dids.SignatureBundle{Sigs: []dids.DIDSig{
+		{DID: alice, Sig: "0xaaaa"},
+		{DID: bob, Sig: "0xbbbb"},
+	}}
+	// bob again, with the same sig formatted differently
+	fromPeerB := dids.SignatureBundle{Sigs: []dids.DIDSig{
+		{DID: bob, Sig: "BBBB"},
+		{DID: carol, Sig: "0xcccc"},
+	}}
+
+	assert.Nil(t, fromPeerA.Merge(fromPeerB))
+	assert.Equal(t, []dids.DIDSig{
+		{DID: alice, Sig: "0xaaaa"},
+		{DID: bob, Sig: "0xbbbb"},
+		{DID: carol, Sig: "0xcccc"},
+	}, fromPeerA.Sigs)
+
+	// an entry with no DID leaves the bundle alone
+	err := fromPeerA.Merge(dids.SignatureBundle{Sigs: []dids.DIDSig{{Sig: "0xdddd"}}})
+	assert.NotNil(t, err)
+	assert.Len(t, fromPeerA.Sigs, 3)
+}
+
+func TestSignatureBundleMergeConflicting(t *testing.T) {
+	data := map[string]interface{}{"block_height": 100, "op": "finalize"}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	alice := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	realSig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	forgedSig, err := dids.NewEthProviderFromKey(otherKey).SignData(data)
+	assert.Nil(t, err)
+
+	// a peer passing on a forged sig for alice, merged in either order, doesn't stop the real one getting through
+	forged := dids.SignatureBundle{Sigs: []dids.DIDSig{{DID: alice, Sig: forgedSig}}}
+	honest := dids.SignatureBundle{Sigs: []dids.DIDSig{{DID: alice, Sig: realSig}}}
+	for _, bundles := range [][2]dids.SignatureBundle{{forged, honest}, {honest, forged}} {
+		merged := dids.SignatureBundle{}
+		assert.Nil(t, merged.Merge(bundles[0], bundles[1]))
+		assert.Len(t, merged.Sigs, 2)
+
+		ok, total, err := dids.VerifyWeightedThreshold(block, merged.Sigs, map[string]int{alice.String(): 1}, 1)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 1, total)
+	}
+}
+
+func TestSignatureBundleDedup(t *testing.T) {
+	alice := dids.NewEthDID("0x00000000000000000000000000000000000000a1")
+
+	bundle := dids.SignatureBundle{Sigs: []dids.DIDSig{
+		{DID: alice, Sig: "0xaaaa"},
+		{DID: alice, Sig: " 0XAAAA "},
+		{DID: alice, Sig: "0xaaaa"},
+	}}
+	bundle.Dedup()
+	assert.Equal(t, []dids.DIDSig{{DID: alice, Sig: "0xaaaa"}}, bundle.Sigs)
+
+	// the slice the bundle was built from isn't filtered in place
+	bob := dids.NewEthDID("0x00000000000000000000000000000000000000b2")
+	sigs := []dids.DIDSig{
+		{DID: alice, Sig: "0xaaaa"},
+		{DID: alice, Sig: "0xaaaa"},
+		{DID: bob, Sig: "0xbbbb"},
+	}
+	bundle = dids.SignatureBundle{Sigs: sigs}
+	bundle.Dedup()
+	assert.Equal(t, []dids.DIDSig{{DID: alice, Sig: "0xaaaa"}, {DID: bob, Sig: "0xbbbb"}}, bundle.Sigs)
+	assert.Equal(t, dids.DIDSig{DID: alice, Sig: "0xaaaa"}, sigs[1])
+}
+
+func TestVerifyWeightedThreshold(t *testing.T) {
+	data := map[string]interface{}{"block_height": 100, "op": "finalize"}
+	block := createCBORBlock(t, data)
+
+	// three validators with weights 2, 3 and 5
+	var validators []dids.DIDSig
+	weights := map[string]int{}
+	for _, weight := range []int{2, 3, 5} {
+		privateKey, err := crypto.GenerateKey()
+		assert.Nil(t, err)
+		sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+		assert.Nil(t, err)
+		did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+		validators = append(validators, dids.DIDSig{DID: did, Sig: sig})
+		weights[did.String()] = weight
+	}
+
+	// 2 + 5 reaches 7
+	ok, total, err := dids.VerifyWeightedThreshold(block, []dids.DIDSig{validators[0], validators[2]}, weights, 7)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 7, total)
+
+	// 2 + 3 doesn't, and repeating a signer doesn't count them twice
+	ok, total, err = dids.VerifyWeightedThreshold(block, []dids.DIDSig{validators[0], validators[1], validators[1]}, weights, 7)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 5, total)
+
+	// an invalid sig carries no weight
+	forged := dids.DIDSig{DID: validators[2].DID, Sig: validators[1].Sig}
+	ok, total, err = dids.VerifyWeightedThreshold(block, []dids.DIDSig{validators[0], forged}, weights, 7)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, total)
+}
+
+func TestVerifyThreshold(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "required_auths": []interface{}{"a", "b", "c"}}
+	block := createCBORBlock(t, data)
+
+	var required []dids.DID
+	sigs := map[string]string{}
+	for i := 0; i < 3; i++ {
+		privateKey, err := crypto.GenerateKey()
+		assert.Nil(t, err)
+		sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+		assert.Nil(t, err)
+		did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+		required = append(required, did)
+		sigs[did.String()] = sig
+	}
+
+	// exactly 2 of 3
+	twoSigs := map[string]string{required[0].String(): sigs[required[0].String()], required[2].String(): sigs[required[2].String()]}
+	ok, err := dids.VerifyThreshold(block, required, twoSigs, 2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 2 isn't 3
+	ok, err = dids.VerifyThreshold(block, required, twoSigs, 3)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// a sig by the wrong signer doesn't count
+	forged := map[string]string{required[0].String(): sigs[required[0].String()], required[1].String(): sigs[required[2].String()]}
+	ok, err = dids.VerifyThreshold(block, required, forged, 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = dids.VerifyThreshold(block, required, sigs, 0)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyWeightedThresholdAliasedSigner(t *testing.T) {
+	data := map[string]interface{}{"block_height": 100, "op": "finalize"}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	alice := dids.NewEthDID(address)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	alicePolygon := dids.NewPkhDID(137, address)
+	polygonSig, err := dids.NewEthProviderFromKey(privateKey, dids.WithChainID(137)).SignData(data)
+	assert.Nil(t, err)
+
+	// alice with valid sigs under her chain 1 and chain 137 DIDs is still one signer, counted once
+	weights := map[string]int{alice.String(): 3}
+	aliased := []dids.DIDSig{{DID: alice, Sig: sig}, {DID: alicePolygon, Sig: polygonSig}}
+	ok, total, err := dids.VerifyWeightedThreshold(block, aliased, weights, 6)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 3, total)
+
+	// and she carries her weight whichever form weights uses, lowercased or on another chain
+	for _, weighted := range []string{dids.EthDIDPrefix + strings.ToLower(address), alicePolygon.String()} {
+		ok, total, err = dids.VerifyWeightedThreshold(block, []dids.DIDSig{{DID: alice, Sig: sig}}, map[string]int{weighted: 3}, 3)
+		assert.Nil(t, err, weighted)
+		assert.True(t, ok, weighted)
+		assert.Equal(t, 3, total, weighted)
+	}
+
+	// weighting her twice is ambiguous
+	_, _, err = dids.VerifyWeightedThreshold(block, aliased, map[string]int{alice.String(): 3, alicePolygon.String(): 3}, 3)
+	assert.NotNil(t, err)
+	_, _, err = dids.VerifyWeightedThreshold(block, aliased, map[string]int{alice.String(): 3, dids.EthDIDPrefix + strings.ToLower(address): 3}, 3)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyThresholdDuplicateSigner(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer"}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	// the same signer listed twice, and under a lowercased DID for the same address, is still one signer
+	alice := dids.NewEthDID(address)
+	aliceLower := dids.EthDID(dids.EthDIDPrefix + strings.ToLower(address))
+	required := []dids.DID{alice, alice, aliceLower}
+	sigs := map[string]string{alice.String(): sig, aliceLower.String(): sig}
+
+	ok, err := dids.VerifyThreshold(block, required, sigs, 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = dids.VerifyThreshold(block, required, sigs, 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifyAnyOf(t *testing.T) {
+	data := map[string]interface{}{"op": "rotate_key"}
+	block := createCBORBlock(t, data)
+
+	var allowed []dids.DID
+	var sigs []string
+	for i := 0; i < 3; i++ {
+		privateKey, err := crypto.GenerateKey()
+		assert.Nil(t, err)
+		sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+		assert.Nil(t, err)
+		allowed = append(allowed, dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()))
+		sigs = append(sigs, sig)
+	}
+
+	// the second key signed, so it's the one matched
+	matched, ok, err := dids.VerifyAnyOf(block, allowed, sigs[1])
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, allowed[1], matched)
+
+	// a key that isn't allowed matches nothing
+	matched, ok, err = dids.VerifyAnyOf(block, []dids.DID{allowed[0], allowed[2]}, sigs[1])
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, matched)
+
+	// nor does a malformed sig
+	_, ok, err = dids.VerifyAnyOf(block, allowed, "0x1234")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// other DID types are checked with their own Verify
+	blsProvider, blsDID := blsTestSigner(t)
+	blsSig, err := blsProvider.Sign(block)
+	assert.Nil(t, err)
+	matched, ok, err = dids.VerifyAnyOf(block, append(allowed, blsDID), blsSig)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, dids.DID(blsDID), matched)
+
+	_, _, err = dids.VerifyAnyOf(block, []dids.DID{nil}, sigs[0])
+	assert.NotNil(t, err)
+}
+
+func TestVerifyThresholdDuplicateKeyDID(t *testing.T) {
+	block := createDummyBlock([]byte("dummy data"))
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	did, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+	sig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+
+	// the same key re-encoded in base32 (which did:key doesn't allow) never counts as a second signer
+	_, data, err := multibase.Decode(strings.TrimPrefix(did.String(), dids.KeyDIDPrefix))
+	assert.Nil(t, err)
+	base32, err := multibase.Encode(multibase.Base32, data)
+	assert.Nil(t, err)
+	reencoded := dids.KeyDID(dids.KeyDIDPrefix + base32)
+
+	required := []dids.DID{did, reencoded}
+	sigs := map[string]string{did.String(): sig, reencoded.String(): sig}
+
+	ok, err := dids.VerifyThreshold(block, required, sigs, 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = dids.VerifyThreshold(block, required, sigs, 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
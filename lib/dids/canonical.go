@@ -0,0 +1,31 @@
+package dids
+
+import (
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== verification + canonical form =====
+
+// verifies the sig like did.Verify and, only if it's valid, returns the typed data it verified against as
+// deterministic sorted-key JSON, so what gets archived is exactly what was checked
+//
+// only DIDs that sign typed data (EthDID) have a canonical form
+func VerifyAndCanonicalize(block blocks.Block, sig string, did DID) (canonicalJSON []byte, valid bool, err error) {
+	ethDID, ok := did.(EthDID)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrNotTypedDataDID, did)
+	}
+
+	typedData, result, err := ethDID.verify(block, sig, newVerifyOptions(nil))
+	if err != nil || !result.Valid {
+		return nil, false, err
+	}
+
+	canonicalJSON, err = typedData.MarshalJSONSorted()
+	if err != nil {
+		return nil, false, err
+	}
+	return canonicalJSON, true, nil
+}
@@ -0,0 +1,91 @@
+package dids_test
+
+import (
+	"encoding/json"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAndCanonicalize(t *testing.T) {
+	data := map[string]interface{}{
+		"tx":      map[string]interface{}{"op": "transfer", "amount": 10, "to": "hive:bob"},
+		"headers": map[string]interface{}{"nonce": 3},
+	}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	provider := dids.NewEthProviderFromKey(privateKey)
+	sig, err := provider.SignData(data)
+	assert.Nil(t, err)
+
+	canonicalJSON, valid, err := dids.VerifyAndCanonicalize(block, sig, ethDID)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// deterministic: keys sorted at every level
+	assert.Contains(t, string(canonicalJSON), `{"EIP712Domain":`)
+	assert.Contains(t, string(canonicalJSON), `"message":{"headers":{"nonce":3},"tx":{"amount":10,"op":"transfer","to":"hive:bob"}}`)
+
+	// and the stored JSON hashes back to exactly what was signed
+	var archived dids.TypedData
+	assert.Nil(t, json.Unmarshal(canonicalJSON, &archived))
+	signed, err := provider.TypedData(data)
+	assert.Nil(t, err)
+
+	archivedHash, err := dids.HashStructFor(archived, archived.Data.PrimaryType, archived.Data.Message)
+	assert.Nil(t, err)
+	signedHash, err := dids.HashStructFor(signed, signed.Data.PrimaryType, signed.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, signedHash, archivedHash)
+	assert.Equal(t, signed.Data.Domain, archived.Data.Domain)
+
+	// nothing is returned for a bad sig
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	canonicalJSON, valid, err = dids.VerifyAndCanonicalize(block, sig, dids.NewEthDID(crypto.PubkeyToAddress(otherKey.PublicKey).Hex()))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	assert.Nil(t, canonicalJSON)
+}
+
+func TestTypedDataMarshalJSONSorted(t *testing.T) {
+	data := map[string]interface{}{
+		"tx":      map[string]interface{}{"op": "transfer", "amount": 10, "to": "hive:bob"},
+		"headers": map[string]interface{}{"nonce": 3, "required_auths": []interface{}{"hive:alice"}},
+		"memo":    "hello",
+	}
+
+	first, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	second, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+
+	firstJSON, err := first.MarshalJSONSorted()
+	assert.Nil(t, err)
+	secondJSON, err := second.MarshalJSONSorted()
+	assert.Nil(t, err)
+	assert.Equal(t, firstJSON, secondJSON)
+
+	// an ordered payload's message keys are sorted too, but its field order is kept in the types
+	ordered := dids.NewOrderedMap().Set("to", "hive:bob").Set("amount", 10)
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", ordered, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	sortedJSON, err := typedData.MarshalJSONSorted()
+	assert.Nil(t, err)
+	assert.Contains(t, string(sortedJSON), `"message":{"amount":10,"to":"hive:bob"}`)
+	assert.Contains(t, string(sortedJSON), `"tx_container_v0":[{"name":"to","type":"string"},{"name":"amount","type":"int256"}]`)
+
+	// and it still hashes the same once parsed back
+	var parsed dids.TypedData
+	assert.Nil(t, json.Unmarshal(sortedJSON, &parsed))
+	expectedHash, err := dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+	parsedHash, err := dids.ComputeEIP712Hash(parsed)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedHash, parsedHash)
+}
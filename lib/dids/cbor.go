@@ -0,0 +1,227 @@
+package dids
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+)
+
+// ===== errors =====
+
+var (
+	ErrNonCanonicalCBOR = fmt.Errorf("block is not canonically encoded dag-cbor")
+	ErrNotDagCBOR       = fmt.Errorf("block CID codec is not dag-cbor")
+)
+
+// ===== constants =====
+
+// the field a top-level array payload is wrapped under
+//
+// EIP-712 needs a struct at the root, so a payload that is an array (e.g. `[a, b, c]`) is signed and verified
+// as if it were `{"items": [a, b, c]}`, i.e. the primary type is `tx_container_v0(T[] items)`. signers on
+// other stacks must apply the same wrapping to produce matching sigs
+const TopLevelArrayField = "items"
+
+// ===== CBOR blocks =====
+
+// CBOR encodes a payload (map, struct or top-level array) into the canonical dag-cbor block, CIDed with
+// sha2-256, that's signed and verified
+//
+// the one place blocks are built from values, so a signer and a verifier encoding the same value always get the
+// same bytes and CID. the block's CID is always CIDv1 (CIDv0 can't address dag-cbor), and a CID has no multibase
+// of its own, so neither is an option here. pick how the CID is rendered with FormatCID or TransactionID instead
+func EncodeBlock(data interface{}) (blocks.Block, error) {
+	node, err := cbor.WrapObject(data, multihash.SHA2_256, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CBOR data: %w", err)
+	}
+	return blocks.NewBlockWithCid(node.RawData(), node.Cid())
+}
+
+// wraps already encoded dag-cbor bytes in a block, CIDed with sha2-256 as EncodeBlock does
+//
+// the bytes are taken as they are, not re-encoded, so a non-canonical encoding still gets a block
+func blockFromCBOR(data []byte) (blocks.Block, error) {
+	hash, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash CBOR data: %w", err)
+	}
+	return blocks.NewBlockWithCid(data, cid.NewCidV1(cid.DagCBOR, hash))
+}
+
+// decodes a signed block's payload into the map form that gets converted, wrapping a top-level array
+func decodeBlockPayload(data []byte) (map[string]interface{}, error) {
+	var decoded interface{}
+	if err := cbor.DecodeInto(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode CBOR data: %v", err)
+	}
+
+	if dataMap, ok := decoded.(map[string]interface{}); ok {
+		return dataMap, nil
+	}
+	if wrapped, ok := wrapTopLevelArray(decoded); ok {
+		return wrapped, nil
+	}
+	return nil, fmt.Errorf("payload must be a map or an array, got %T", decoded)
+}
+
+// wraps an array payload under TopLevelArrayField, reporting false for anything that isn't an array
+func wrapTopLevelArray(data interface{}) (map[string]interface{}, bool) {
+	if data == nil {
+		return nil, false
+	}
+	if _, isBytes := data.([]byte); isBytes {
+		return nil, false
+	}
+
+	kind := reflect.Indirect(reflect.ValueOf(data)).Kind()
+	if kind != reflect.Slice && kind != reflect.Array {
+		return nil, false
+	}
+	return map[string]interface{}{TopLevelArrayField: data}, true
+}
+
+// ===== canonical encoding =====
+
+// reports whether the block is exactly one canonically encoded dag-cbor item, the only encoding EncodeBlock
+// produces
+//
+// that means every length and integer in its shortest form, no indefinite-length items, map keys in length-first
+// then bytewise order with no duplicates, floats as 64 bits and nothing after the item. a block that breaks any
+// of these decodes to data whose canonical encoding (and so CID) differs, which is what a sig commits to. the
+// error is for bytes that aren't well-formed CBOR at all
+func IsCanonicalCBOR(block blocks.Block) (bool, error) {
+	data := block.RawData()
+	end, canonical, err := checkCanonicalCBORItem(data, 0, 0)
+	if err != nil {
+		return false, err
+	}
+	return canonical && end == len(data), nil
+}
+
+// the most deeply nested items checkCanonicalCBORItem follows before giving up
+const maxCBORNesting = 256
+
+// checks the item starting at offset, returning the offset just past it
+//
+// a non-canonical item stops the walk early, since its end no longer matters
+func checkCanonicalCBORItem(data []byte, offset int, depth int) (int, bool, error) {
+	if depth > maxCBORNesting {
+		return 0, false, fmt.Errorf("CBOR nests deeper than %d items", maxCBORNesting)
+	}
+	if offset >= len(data) {
+		return 0, false, fmt.Errorf("unexpected end of CBOR data")
+	}
+
+	major, info := data[offset]>>5, data[offset]&0x1f
+	offset++
+
+	// floats and simple values carry their own widths, so they aren't ordinary heads
+	if major == 7 {
+		switch {
+		case info < 24:
+			return offset, true, nil
+		case info == 27:
+			if offset+8 > len(data) {
+				return 0, false, fmt.Errorf("unexpected end of CBOR data")
+			}
+			return offset + 8, true, nil
+		case info == 24 || info == 25 || info == 26:
+			// one byte simple values and half/single floats are never what the encoder writes
+			return offset, false, nil
+		default:
+			return 0, false, fmt.Errorf("invalid CBOR simple value %d", info)
+		}
+	}
+
+	if info == 31 {
+		if major < 2 || major == 6 {
+			return 0, false, fmt.Errorf("invalid indefinite length for CBOR major type %d", major)
+		}
+		return offset, false, nil
+	}
+
+	// the head's argument, which must use the fewest bytes that fit it
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if offset+size > len(data) {
+			return 0, false, fmt.Errorf("unexpected end of CBOR data")
+		}
+		buf := make([]byte, 8)
+		copy(buf[8-size:], data[offset:offset+size])
+		arg = binary.BigEndian.Uint64(buf)
+		offset += size
+
+		minimal := (size == 1 && arg >= 24) || (size > 1 && arg >= 1<<(4*size))
+		if !minimal {
+			return offset, false, nil
+		}
+	default:
+		return 0, false, fmt.Errorf("invalid CBOR additional info %d", info)
+	}
+
+	switch major {
+	case 0, 1:
+		return offset, true, nil
+
+	case 2, 3:
+		if arg > uint64(len(data)-offset) {
+			return 0, false, fmt.Errorf("unexpected end of CBOR data")
+		}
+		return offset + int(arg), true, nil
+
+	case 4:
+		for i := uint64(0); i < arg; i++ {
+			next, canonical, err := checkCanonicalCBORItem(data, offset, depth+1)
+			if err != nil || !canonical {
+				return next, canonical, err
+			}
+			offset = next
+		}
+		return offset, true, nil
+
+	case 5:
+		var previousKey []byte
+		for i := uint64(0); i < arg; i++ {
+			keyEnd, canonical, err := checkCanonicalCBORItem(data, offset, depth+1)
+			if err != nil || !canonical {
+				return keyEnd, canonical, err
+			}
+
+			// keys must strictly ascend, which also rules out duplicates
+			key := data[offset:keyEnd]
+			if previousKey != nil && !canonicalKeyLess(previousKey, key) {
+				return keyEnd, false, nil
+			}
+			previousKey = key
+
+			valueEnd, canonical, err := checkCanonicalCBORItem(data, keyEnd, depth+1)
+			if err != nil || !canonical {
+				return valueEnd, canonical, err
+			}
+			offset = valueEnd
+		}
+		return offset, true, nil
+
+	default: // 6, a tag (42 for links) wrapping one item
+		return checkCanonicalCBORItem(data, offset, depth+1)
+	}
+}
+
+// the dag-cbor map key order: shorter encoded keys first, then bytewise
+func canonicalKeyLess(a, b []byte) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return bytes.Compare(a, b) < 0
+}
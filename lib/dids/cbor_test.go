@@ -0,0 +1,161 @@
+package dids_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthDIDVerifyTopLevelArray(t *testing.T) {
+	// a message that is an array rather than a map at the root
+	data := []interface{}{"hive:alice", "hive:bob", "hive:carol"}
+	block, err := dids.EncodeBlock(data)
+	assert.Nil(t, err)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	// the array is signed wrapped in a struct root
+	typedData, err := provider.TypedData(data)
+	assert.Nil(t, err)
+	assert.Contains(t, typedData.Data.Message, dids.TopLevelArrayField)
+	assert.Equal(t, "string[]", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	sig, err := provider.SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// which is the same as signing the wrapped form explicitly
+	wrappedSig, err := provider.SignData(map[string]interface{}{dids.TopLevelArrayField: data})
+	assert.Nil(t, err)
+	assert.Equal(t, sig, wrappedSig)
+}
+
+func TestIsCanonicalCBOR(t *testing.T) {
+	encoded, err := dids.EncodeBlock(map[string]interface{}{
+		"op":      "transfer",
+		"amount":  100000,
+		"ratio":   1.5,
+		"tags":    []interface{}{"a", "b"},
+		"headers": map[string]interface{}{"nonce": 3},
+	})
+	assert.Nil(t, err)
+	canonical, err := dids.IsCanonicalCBOR(encoded)
+	assert.Nil(t, err)
+	assert.True(t, canonical)
+
+	rawBlock := func(hexData string) blocks.Block {
+		data, err := hex.DecodeString(hexData)
+		assert.Nil(t, err)
+		return blocks.NewBlock(data)
+	}
+
+	// all of these decode to {"a": 1, "b": 1} (or {"a": 1}) but aren't how it encodes
+	for name, hexData := range map[string]string{
+		"unsorted keys":      "a2616201616101",
+		"duplicate keys":     "a2616101616101",
+		"indefinite map":     "bf616101ff",
+		"non-minimal int":    "a161611801",
+		"non-minimal length": "b80161616101",
+		"half float":         "a16161f93c00",
+		"trailing bytes":     "a1616101ff",
+	} {
+		canonical, err := dids.IsCanonicalCBOR(rawBlock(hexData))
+		assert.Nil(t, err, name)
+		assert.False(t, canonical, name)
+	}
+
+	// truncated data isn't CBOR at all
+	_, err = dids.IsCanonicalCBOR(rawBlock("a26161"))
+	assert.NotNil(t, err)
+}
+
+func TestEthDIDVerifyStrictCBOR(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(map[string]interface{}{"a": 1, "b": 1})
+	assert.Nil(t, err)
+
+	// {"b": 1, "a": 1}, the same data with its keys out of order
+	data, err := hex.DecodeString("a2616201616101")
+	assert.Nil(t, err)
+	block := blocks.NewBlock(data)
+
+	// decoding hides the difference
+	valid, err := ethDID.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// which strict mode refuses
+	valid, err = ethDID.VerifyWithOptions(block, sig, dids.WithStrictCBOR())
+	assert.ErrorIs(t, err, dids.ErrNonCanonicalCBOR)
+	assert.False(t, valid)
+
+	canonicalBlock, err := dids.EncodeBlock(map[string]interface{}{"a": 1, "b": 1})
+	assert.Nil(t, err)
+	valid, err = ethDID.VerifyWithOptions(canonicalBlock, sig, dids.WithStrictCBOR())
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestEthDIDVerifyRequireDagCBOR(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block, err := dids.EncodeBlock(data)
+	assert.Nil(t, err)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	sig, err := dids.NewEthProviderFromKey(privateKey).Sign(block)
+	assert.Nil(t, err)
+
+	valid, err := ethDID.VerifyWithOptions(block, sig, dids.WithRequireDagCBOR())
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the same bytes addressed with the raw codec
+	rawBlock, err := blocks.NewBlockWithCid(block.RawData(), cid.NewCidV1(cid.Raw, block.Cid().Hash()))
+	assert.Nil(t, err)
+
+	// still decode to the signed data
+	valid, err = ethDID.Verify(rawBlock, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// which the codec check refuses
+	valid, err = ethDID.VerifyWithOptions(rawBlock, sig, dids.WithRequireDagCBOR())
+	assert.ErrorIs(t, err, dids.ErrNotDagCBOR)
+	assert.False(t, valid)
+}
+
+func TestEncodeBlock(t *testing.T) {
+	data := map[string]any{"foo": "bar", "baz": 12345}
+
+	block, err := dids.EncodeBlock(data)
+	assert.Nil(t, err)
+
+	// the same block the two steps in TestEthDIDVerify build
+	cborData, err := cbor.WrapObject(data, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	manual, err := blocks.NewBlockWithCid(cborData.RawData(), cborData.Cid())
+	assert.Nil(t, err)
+	assert.Equal(t, manual.Cid(), block.Cid())
+	assert.Equal(t, manual.RawData(), block.RawData())
+
+	canonical, err := dids.IsCanonicalCBOR(block)
+	assert.Nil(t, err)
+	assert.True(t, canonical)
+}
@@ -0,0 +1,59 @@
+package dids
+
+import (
+	"context"
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+)
+
+// ===== errors =====
+
+var ErrCIDMismatch = fmt.Errorf("fetched bytes do not hash to the requested CID")
+
+// ===== types =====
+
+// fetches the raw block bytes for a CID from whatever content-addressed store the caller uses (IPFS, local blockstore, etc.)
+type BlockFetcher func(ctx context.Context, c cid.Cid) ([]byte, error)
+
+// ===== verification by CID =====
+
+// verifies a sig when all we have is the CID of the signed content
+//
+// the bytes are fetched via the injected fetcher and re-hashed with the CID's own prefix (version, codec and multihash)
+// before verifying, so a misbehaving store can't hand us different content than what the CID commits to
+func VerifyByCID(ctx context.Context, c cid.Cid, sig string, did DID, fetch BlockFetcher) (bool, error) {
+	if did == nil {
+		return false, fmt.Errorf("DID cannot be nil")
+	}
+	if fetch == nil {
+		return false, fmt.Errorf("block fetcher cannot be nil")
+	}
+
+	// bail early if the caller already gave up
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	data, err := fetch(ctx, c)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch block %s: %w", c, err)
+	}
+
+	// confirm the fetched bytes actually hash to the CID we asked for
+	computed, err := c.Prefix().Sum(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash fetched block: %w", err)
+	}
+	if !computed.Equals(c) {
+		return false, ErrCIDMismatch
+	}
+
+	block, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return false, fmt.Errorf("failed to create block: %w", err)
+	}
+
+	return did.Verify(block, sig)
+}
@@ -0,0 +1,61 @@
+package dids_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyByCID(t *testing.T) {
+	// gen a keypair and the matching DID
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	did, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+
+	// encode some data into a CBOR block and sign it
+	block, err := cbor.WrapObject(map[string]interface{}{"foo": "bar"}, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	sig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+
+	// mock "IPFS" store that only knows about our block
+	store := map[string][]byte{
+		block.Cid().String(): block.RawData(),
+	}
+	fetch := func(ctx context.Context, c cid.Cid) ([]byte, error) {
+		data, ok := store[c.String()]
+		if !ok {
+			return nil, fmt.Errorf("not found")
+		}
+		return data, nil
+	}
+
+	valid, err := dids.VerifyByCID(context.Background(), block.Cid(), sig, did, fetch)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a store returning the wrong bytes for the CID must be rejected
+	store[block.Cid().String()] = []byte("tampered")
+	valid, err = dids.VerifyByCID(context.Background(), block.Cid(), sig, did, fetch)
+	assert.ErrorIs(t, err, dids.ErrCIDMismatch)
+	assert.False(t, valid)
+
+	// fetcher errors are surfaced
+	delete(store, block.Cid().String())
+	_, err = dids.VerifyByCID(context.Background(), block.Cid(), sig, did, fetch)
+	assert.NotNil(t, err)
+
+	// as is a missing DID, rather than a panic
+	valid, err = dids.VerifyByCID(context.Background(), block.Cid(), sig, nil, fetch)
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+}
@@ -0,0 +1,99 @@
+package dids
+
+import (
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+)
+
+// ===== CID format options =====
+
+// picks the CID version and multibase CIDs are rendered in (see FormatCID and TransactionID)
+//
+// without options, CIDs keep their own version and render the way go-cid does by default (base32 for CIDv1,
+// base58btc for CIDv0)
+type CIDOption func(*cidOptions)
+
+type cidOptions struct {
+	version    uint64
+	versionSet bool
+
+	base    multibase.Encoding
+	baseSet bool
+}
+
+func newCIDOptions(opts []CIDOption) *cidOptions {
+	o := &cidOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// sets the CID version (0 or 1)
+//
+// CIDv0 can only address dag-pb sha2-256 content, so it can't be used for CBOR blocks
+func WithCIDVersion(version uint64) CIDOption {
+	return func(o *cidOptions) {
+		o.version = version
+		o.versionSet = true
+	}
+}
+
+// sets the multibase CID strings are rendered in (e.g. multibase.Base32 or multibase.Base58BTC)
+func WithMultibase(base multibase.Encoding) CIDOption {
+	return func(o *cidOptions) {
+		o.base = base
+		o.baseSet = true
+	}
+}
+
+// ===== rendering =====
+
+// renders a CID as a string in the configured version and multibase
+func FormatCID(c cid.Cid, opts ...CIDOption) (string, error) {
+	o := newCIDOptions(opts)
+
+	converted, err := o.convert(c)
+	if err != nil {
+		return "", err
+	}
+
+	if !o.baseSet {
+		return converted.String(), nil
+	}
+	rendered, err := converted.StringOfBase(o.base)
+	if err != nil {
+		return "", fmt.Errorf("failed to render CID %s: %w", c, err)
+	}
+	return rendered, nil
+}
+
+// the ID a signed tx block is referred to by: its CID, rendered per the options
+func TransactionID(block blocks.Block, opts ...CIDOption) (string, error) {
+	return FormatCID(block.Cid(), opts...)
+}
+
+// ===== utils =====
+
+// converts a CID to the configured version, if one was set
+func (o *cidOptions) convert(c cid.Cid) (cid.Cid, error) {
+	if !o.versionSet || c.Version() == o.version {
+		return c, nil
+	}
+
+	switch o.version {
+	case 0:
+		prefix := c.Prefix()
+		if prefix.Codec != cid.DagProtobuf || prefix.MhType != multihash.SHA2_256 {
+			return cid.Undef, fmt.Errorf("CID %s can't be expressed as CIDv0, which only addresses dag-pb sha2-256 content", c)
+		}
+		return cid.NewCidV0(c.Hash()), nil
+	case 1:
+		return cid.NewCidV1(c.Type(), c.Hash()), nil
+	}
+	return cid.Undef, fmt.Errorf("unsupported CID version %d", o.version)
+}
@@ -0,0 +1,58 @@
+package dids_test
+
+import (
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionIDMultibase(t *testing.T) {
+	block, err := dids.EncodeBlock(map[string]interface{}{"op": "transfer", "amount": 1})
+	assert.Nil(t, err)
+
+	// defaults to go-cid's own rendering
+	defaultID, err := dids.TransactionID(block)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Cid().String(), defaultID)
+
+	base32ID, err := dids.TransactionID(block, dids.WithMultibase(multibase.Base32))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(base32ID, "b"))
+
+	base58ID, err := dids.TransactionID(block, dids.WithMultibase(multibase.Base58BTC))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(base58ID, "z"))
+
+	// both are the same CID
+	for _, id := range []string{base32ID, base58ID} {
+		parsed, err := cid.Decode(id)
+		assert.Nil(t, err)
+		assert.True(t, parsed.Equals(block.Cid()))
+	}
+}
+
+func TestFormatCIDVersion(t *testing.T) {
+	mh, err := multihash.Sum([]byte("hello"), multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	v0 := cid.NewCidV0(mh)
+
+	// dag-pb sha2-256 CIDs convert between versions
+	v1String, err := dids.FormatCID(v0, dids.WithCIDVersion(1))
+	assert.Nil(t, err)
+	assert.Equal(t, cid.NewCidV1(cid.DagProtobuf, mh).String(), v1String)
+
+	v0String, err := dids.FormatCID(cid.NewCidV1(cid.DagProtobuf, mh), dids.WithCIDVersion(0))
+	assert.Nil(t, err)
+	assert.Equal(t, v0.String(), v0String)
+
+	// but CBOR blocks can't be CIDv0
+	block, err := dids.EncodeBlock(map[string]interface{}{"op": "transfer"})
+	assert.Nil(t, err)
+	_, err = dids.TransactionID(block, dids.WithCIDVersion(0))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,74 @@
+package dids
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ===== commitments =====
+//
+// a commitment stands in for a field the sender doesn't want to reveal yet: the signed message carries only a
+// salted hash of the value (typed `bytes32`), and the value and salt can be disclosed later and checked against it
+//
+// on the wire (CBOR) a commitment is just its 32 hash bytes, which convert as `bytes` by default, so verifiers
+// must pin the field back to `bytes32` with WithTypeOverrides (e.g. {"tx.memo": "bytes32"}) to match the signer
+
+// a salted keccak256 commitment to a hidden value
+type Commitment struct {
+	Hash []byte
+}
+
+// commits to a value: keccak256(len(salt) ‖ salt ‖ JSON(value)), with the length as 8 big-endian bytes and
+// object keys sorted
+//
+// the length keeps where the salt ends fixed, so a value can't be revealed as another one by moving bytes
+// between it and the salt
+//
+// the salt should be random and kept with the value until it's revealed, else low-entropy values (amounts,
+// known usernames, etc.) can simply be guessed from the hash
+func NewCommitment(value interface{}, salt []byte) (Commitment, error) {
+	hash, err := commitmentHash(value, salt)
+	if err != nil {
+		return Commitment{}, err
+	}
+	return Commitment{Hash: hash}, nil
+}
+
+// checks a revealed value and salt against a commitment
+func VerifyCommitment(commitment Commitment, revealed interface{}, salt []byte) (bool, error) {
+	if len(commitment.Hash) != 32 {
+		return false, fmt.Errorf("commitment hash must be 32 bytes, got %d", len(commitment.Hash))
+	}
+
+	hash, err := commitmentHash(revealed, salt)
+	if err != nil {
+		return false, err
+	}
+	return constantTimeEqual(hash, commitment.Hash), nil
+}
+
+// ===== utils =====
+
+func commitmentHash(value interface{}, salt []byte) ([]byte, error) {
+	// encoding/json writes map keys sorted, so equal values always serialize the same
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize committed value: %w", err)
+	}
+	saltLength := binary.BigEndian.AppendUint64(nil, uint64(len(salt)))
+	return crypto.Keccak256(saltLength, salt, encoded), nil
+}
+
+// the commitment a field holds, if it holds one (by value or pointer)
+func asCommitment(value interface{}) (*Commitment, bool) {
+	switch v := value.(type) {
+	case Commitment:
+		return &v, true
+	case *Commitment:
+		return v, v != nil
+	}
+	return nil, false
+}
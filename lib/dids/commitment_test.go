@@ -0,0 +1,71 @@
+package dids_test
+
+import (
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitmentSelectiveDisclosure(t *testing.T) {
+	hidden := map[string]interface{}{"memo": "rent for march", "invoice": 42}
+	salt := []byte("0123456789abcdef0123456789abcdef")
+
+	commitment, err := dids.NewCommitment(hidden, salt)
+	assert.Nil(t, err)
+
+	// the sender signs the tx with only the commitment in place of the hidden field
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	signed := map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "to": "hive:bob", "details": commitment},
+	}
+	typedData, err := provider.TypedData(signed)
+	assert.Nil(t, err)
+	assert.Contains(t, typedData.Data.Types["tx_container_v0.tx"], apitypes.Type{Name: "details", Type: "bytes32"})
+
+	sig, err := provider.SignData(signed)
+	assert.Nil(t, err)
+
+	// on the wire the commitment is just its hash bytes, pinned back to bytes32 when verifying
+	block := createCBORBlock(t, map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "to": "hive:bob", "details": commitment.Hash},
+	})
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).VerifyWithOptions(
+		block, sig,
+		dids.WithConvertOptions(dids.WithTypeOverrides(map[string]string{"tx.details": "bytes32"})),
+	)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// later, the revealed value checks out against the commitment
+	valid, err = dids.VerifyCommitment(commitment, map[string]interface{}{"invoice": 42, "memo": "rent for march"}, salt)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// while a different value or salt doesn't
+	valid, err = dids.VerifyCommitment(commitment, map[string]interface{}{"memo": "rent for april", "invoice": 42}, salt)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	valid, err = dids.VerifyCommitment(commitment, hidden, []byte("another salt"))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestCommitmentSaltBoundary(t *testing.T) {
+	// "a" ‖ "12" and "a1" ‖ "2" are the same bytes, but not the same commitment
+	commitment, err := dids.NewCommitment(12, []byte("a"))
+	assert.Nil(t, err)
+
+	valid, err := dids.VerifyCommitment(commitment, 12, []byte("a"))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	valid, err = dids.VerifyCommitment(commitment, 2, []byte("a1"))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
@@ -0,0 +1,456 @@
+package dids
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ===== EIP-712 conversion options =====
+
+// how (u)int256 values are rendered in the message
+//
+// like AddressCase, the struct hash encodes the number itself either way, but wallet libraries that hash
+// their own JSON rendering of the message need it to match what they produced: decimal or 0x hex
+type NumberFormat int
+
+const (
+	// *big.Int values, which marshal as JSON decimal numbers
+	NumberFormatDecimal NumberFormat = iota
+	// 0x-prefixed hex strings (with a leading - for negatives)
+	NumberFormatHex
+)
+
+// how a string coerced to `address` is written in the message
+//
+// the struct hash encodes the 20 byte value, so this only changes the message JSON, but some client libraries
+// hash the address string itself and need the casing to match theirs
+type AddressCase int
+
+const (
+	// leaves the address as it appeared in the payload
+	AddressCaseAsIs AddressCase = iota
+	// all lowercase hex
+	AddressCaseLower
+	// EIP-55 mixed-case checksum
+	AddressCaseChecksum
+)
+
+// how time.Time values are written in the message
+type TimeFormat int
+
+const (
+	// the unix timestamp in whole seconds, typed uint256 (int256 for times before 1970)
+	TimeFormatUnix TimeFormat = iota
+	// an RFC 3339 string in UTC (with fractional seconds only when there are any), typed string
+	TimeFormatRFC3339
+)
+
+// how a float with a fractional part becomes an integer, for WithFloatRounding
+type FloatRounding int
+
+const (
+	// drops the fractional part, rounding toward zero (what the default float handler does)
+	FloatRoundingTruncate FloatRounding = iota
+	// rounds to the nearest integer, halves away from zero
+	FloatRoundingNearest
+	// fails the conversion with ErrInexactFloat
+	FloatRoundingError
+)
+
+// what happens to a field whose value is nil (a nil pointer, or a null from JSON/CBOR)
+type NilPolicy int
+
+const (
+	// fails the conversion with ErrNilValue
+	NilPolicyError NilPolicy = iota
+	// leaves the field out of the type and message, as if it weren't there
+	NilPolicyOmit
+	// converts a nil pointer as the zero value of what it points at (e.g. "" for *string, 0 for *big.Int). an
+	// untyped nil has nothing to take a zero value of, so it still errors
+	NilPolicyZero
+)
+
+// the conversion limits used when WithMaxDepth, WithMaxFields or WithMaxSliceLength don't say otherwise
+//
+// they're well beyond any real tx, and only there so a hostile payload can't make verification recurse or allocate
+// without bound
+const (
+	DefaultMaxConvertDepth = 64
+	DefaultMaxFields       = 100000
+	DefaultMaxSliceLength  = 100000
+)
+
+// tweaks how ConvertToEIP712TypedData turns a payload into typed data
+type ConvertOption func(*convertOptions)
+
+type convertOptions struct {
+	floatHandler func(float64) (*big.Int, error)
+
+	// when set, 0x-prefixed 40 hex char strings stay `string` instead of becoming `address`
+	disableAddressCoercion bool
+
+	// how coerced address values are cased in the message
+	addressCase AddressCase
+
+	// how integer values are rendered in the message
+	numberFormat NumberFormat
+
+	// how time.Time values are converted
+	timeFormat TimeFormat
+
+	// what nil field values become
+	nilPolicy NilPolicy
+
+	// when set, whole-valued floats are converted as the equivalent integer
+	wholeFloatsAsInts bool
+
+	// when set, floats fail the conversion rather than going to the float handler
+	rejectFloats bool
+
+	// when set, fmt.Stringer values are converted as their String() form
+	stringers bool
+
+	// when set, the domain has no fields at all and the domain name is ignored
+	emptyDomain bool
+
+	// optional EIP-712 domain fields besides the name
+	domainVersion     string
+	chainID           *big.Int
+	verifyingContract string
+	salt              string
+
+	// explicit EIP-712 types keyed by dotted field path (e.g. "headers.nonce")
+	typeOverrides map[string]string
+
+	// float handlers for specific fields, keyed by dotted field path like typeOverrides
+	fieldFloatHandlers map[string]func(float64) (*big.Int, error)
+
+	// limits on the payload's shape, with <= 0 meaning the default
+	maxDepth       int
+	maxFields      int
+	maxSliceLength int
+}
+
+func newConvertOptions(floatHandler func(float64) (*big.Int, error), opts []ConvertOption) *convertOptions {
+	o := &convertOptions{floatHandler: floatHandler}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// builds the typed data domain from the name plus whichever optional fields were set
+func (o *convertOptions) domain(domainName string) apitypes.TypedDataDomain {
+	domain := apitypes.TypedDataDomain{
+		Name:    domainName,
+		Version: o.domainVersion,
+		Salt:    o.salt,
+	}
+	if o.chainID != nil {
+		domain.ChainId = (*math.HexOrDecimal256)(new(big.Int).Set(o.chainID))
+	}
+	if o.verifyingContract != "" {
+		// checksummed, as wallets display it
+		domain.VerifyingContract = common.HexToAddress(o.verifyingContract).Hex()
+	}
+	return domain
+}
+
+// checks the optional domain fields that have a fixed format
+func (o *convertOptions) validateDomainFields() error {
+	if o.verifyingContract != "" && !isEthAddr(o.verifyingContract) {
+		return fmt.Errorf("invalid domain verifyingContract %q: must be a 0x-prefixed 20 byte hex address", o.verifyingContract)
+	}
+	if o.salt != "" {
+		salt, err := hexutil.Decode(o.salt)
+		if err != nil {
+			return fmt.Errorf("invalid domain salt %q: must be 0x-prefixed hex: %v", o.salt, err)
+		}
+		if len(salt) != 32 {
+			return fmt.Errorf("invalid domain salt %q: must be 32 bytes, got %d", o.salt, len(salt))
+		}
+	}
+	return nil
+}
+
+// cases a coerced address per the addressCase option
+func (o *convertOptions) formatAddress(address string) string {
+	switch o.addressCase {
+	case AddressCaseLower:
+		return "0x" + strings.ToLower(address[2:])
+	case AddressCaseChecksum:
+		return common.HexToAddress(address).Hex()
+	}
+	return address
+}
+
+// the integer a float value is signed as
+//
+// a whole float (as JSON decoding gives for every number) is exactly the integer it equals, typed by sign like
+// any other, so the float handler is only asked about floats with a fractional part. NaN and Inf have no integer
+// form whatever the handler, so they're an ErrNonFiniteFloat
+//
+// a field with its own handler (WithFieldFloatHandlers) has every float go through it, whole or not, since it's
+// there to scale that field's values. fieldPath is the field's dotted path, any array indexes included
+//
+// with WithRejectFloats, or no handler at all, every other float fails with ErrFloatsRejected, whole ones too
+func (o *convertOptions) floatToBigInt(f float64, fieldPath string) (*big.Int, error) {
+	if isNonFinite(f) {
+		return nil, fmt.Errorf("%w: %v", ErrNonFiniteFloat, f)
+	}
+	arrayPath, _, _ := strings.Cut(fieldPath, "[")
+	if handler, ok := o.fieldFloatHandlers[arrayPath]; ok {
+		return handler(f)
+	}
+	if o.rejectFloats || o.floatHandler == nil {
+		return nil, fmt.Errorf("%w: got %v", ErrFloatsRejected, f)
+	}
+	if n, ok := wholeFloatToBigInt(f); ok {
+		return n, nil
+	}
+	return o.floatHandler(f)
+}
+
+// renders a message integer per the numberFormat option
+func (o *convertOptions) formatNumber(n *big.Int) interface{} {
+	if o.numberFormat == NumberFormatHex {
+		return hexutil.EncodeBig(n)
+	}
+	return n
+}
+
+// renders a message integer array per the numberFormat option
+func (o *convertOptions) formatNumbers(ns []*big.Int) interface{} {
+	if o.numberFormat != NumberFormatHex {
+		return ns
+	}
+	rendered := make([]interface{}, len(ns))
+	for i, n := range ns {
+		rendered[i] = o.formatNumber(n)
+	}
+	return rendered
+}
+
+// the effective limits, with the defaults filled in
+func (o *convertOptions) limits() (maxDepth int, maxFields int, maxSliceLength int) {
+	maxDepth, maxFields, maxSliceLength = o.maxDepth, o.maxFields, o.maxSliceLength
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxConvertDepth
+	}
+	if maxFields <= 0 {
+		maxFields = DefaultMaxFields
+	}
+	if maxSliceLength <= 0 {
+		maxSliceLength = DefaultMaxSliceLength
+	}
+	return maxDepth, maxFields, maxSliceLength
+}
+
+// whether any domain field other than the name was set
+func (o *convertOptions) hasDomainFields() bool {
+	return o.domainVersion != "" || o.chainID != nil || o.verifyingContract != "" || o.salt != ""
+}
+
+// for integer only schemas: any float in the payload, even a whole one like `1.0`, fails the conversion with
+// ErrFloatsRejected, whatever the float handler (unless WithWholeFloatsAsInts makes whole ones integers first, and
+// fields with their own WithFieldFloatHandlers handler aside)
+//
+// passing a nil float handler does the same. note that JSON decoding makes every number a float, so it's meant for
+// payloads built from Go integer types or CBOR
+func WithRejectFloats() ConvertOption {
+	return func(o *convertOptions) {
+		o.rejectFloats = true
+	}
+}
+
+// overrides the float handler passed positionally to ConvertToEIP712TypedData
+//
+// mostly useful for carrying a float policy around inside a list of options (e.g. on an EthProvider). like the
+// positional one, it's only asked about floats with a fractional part: a whole float is the exact integer it
+// equals without it, so it must round or reject, never scale (a handler multiplying by 100 would make 1.5 into
+// 150 but leave 1.0 as 1). scale with WithFieldFloatHandlers instead, whose handlers get every float
+func WithFloatHandler(floatHandler func(float64) (*big.Int, error)) ConvertOption {
+	return func(o *convertOptions) {
+		o.floatHandler = floatHandler
+	}
+}
+
+// replaces the float handler with one rounding fractional floats per the mode (see FloatRounding)
+//
+// like WithFloatHandler, this overrides the handler passed positionally, and whichever of the two options comes
+// last wins
+func WithFloatRounding(rounding FloatRounding) ConvertOption {
+	return func(o *convertOptions) {
+		o.floatHandler = func(f float64) (*big.Int, error) {
+			return roundFloat(f, rounding)
+		}
+	}
+}
+
+// disables the auto string -> address coercion, so 0x strings are always typed as `string`
+func WithoutAddressCoercion() ConvertOption {
+	return func(o *convertOptions) {
+		o.disableAddressCoercion = true
+	}
+}
+
+// sets the casing of coerced address values in the message (AddressCaseAsIs by default)
+func WithAddressCase(addressCase AddressCase) ConvertOption {
+	return func(o *convertOptions) {
+		o.addressCase = addressCase
+	}
+}
+
+// sets how integer values are rendered in the message (NumberFormatDecimal by default)
+//
+// this must match the signer's rendering when verifying sigs from libraries that hash the message JSON
+func WithNumberFormat(numberFormat NumberFormat) ConvertOption {
+	return func(o *convertOptions) {
+		o.numberFormat = numberFormat
+	}
+}
+
+// converts time.Time values as RFC 3339 strings, or (the default) unix timestamps
+//
+// the two sign differently, so signer and verifier must agree. a timestamp drops anything below a second
+func WithTimeFormat(timeFormat TimeFormat) ConvertOption {
+	return func(o *convertOptions) {
+		o.timeFormat = timeFormat
+	}
+}
+
+// sets what nil field values become (NilPolicyError by default)
+//
+// omitting a field and zeroing it sign differently, so signer and verifier must agree
+func WithNilPolicy(nilPolicy NilPolicy) ConvertOption {
+	return func(o *convertOptions) {
+		o.nilPolicy = nilPolicy
+	}
+}
+
+// treats whole-valued floats (e.g. `1.0`) exactly like the integer they equal (`1`), typed int256
+//
+// clients differ on whether a number arrives as a float or an int (JSON decoding always gives floats), which
+// otherwise changes its type (uint256, or int256 when negative, for whole floats vs always int256 for ints) and so
+// the hash. both signer and verifier must use it
+func WithWholeFloatsAsInts() ConvertOption {
+	return func(o *convertOptions) {
+		o.wholeFloatsAsInts = true
+	}
+}
+
+// converts values implementing fmt.Stringer as a `string` holding their String() result, rather than by their
+// underlying kind
+//
+// meant for enum-like domain types (e.g. `type Op int` with a String method), which would otherwise sign as an
+// int256 or a nested struct. a Stringer that renders an address still gets coerced to `address`
+func WithStringers() ConvertOption {
+	return func(o *convertOptions) {
+		o.stringers = true
+	}
+}
+
+// pins the EIP-712 type of specific fields, keyed by dotted field path relative to the primary type (e.g. "tx.payload.amount")
+//
+// integers can be narrowed this way (e.g. to uint64 to match a contract's schema), in which case a value that
+// doesn't fit the width fails the conversion
+func WithTypeOverrides(overrides map[string]string) ConvertOption {
+	return func(o *convertOptions) {
+		if o.typeOverrides == nil {
+			o.typeOverrides = make(map[string]string, len(overrides))
+		}
+		for path, typ := range overrides {
+			o.typeOverrides[path] = typ
+		}
+	}
+}
+
+// converts the floats of specific fields with their own handler, keyed by dotted field path relative to the
+// primary type (e.g. "tx.payload.amount"), falling back to the float handler for every other field
+//
+// meant for fields with their own precision, e.g. scaling a BTC amount by 1e8 into sats while a HIVE amount scales
+// by 1e3. a field's handler gets all of its floats, whole ones included, and the floats in an array field too
+func WithFieldFloatHandlers(handlers map[string]func(float64) (*big.Int, error)) ConvertOption {
+	return func(o *convertOptions) {
+		if o.fieldFloatHandlers == nil {
+			o.fieldFloatHandlers = make(map[string]func(float64) (*big.Int, error), len(handlers))
+		}
+		for path, handler := range handlers {
+			o.fieldFloatHandlers[path] = handler
+		}
+	}
+}
+
+// caps how deep the payload may nest, counting the primary type as level 1 and each nested struct or array as
+// another (DefaultMaxConvertDepth if <= 0). deeper payloads fail with ErrConvertLimit
+func WithMaxDepth(maxDepth int) ConvertOption {
+	return func(o *convertOptions) {
+		o.maxDepth = maxDepth
+	}
+}
+
+// caps how many struct fields the payload may have in total, nested ones included (DefaultMaxFields if <= 0).
+// more fail with ErrConvertLimit
+func WithMaxFields(maxFields int) ConvertOption {
+	return func(o *convertOptions) {
+		o.maxFields = maxFields
+	}
+}
+
+// caps how many elements any one array in the payload may have (DefaultMaxSliceLength if <= 0). `bytes` values
+// are a single value and aren't limited. longer arrays fail with ErrConvertLimit
+func WithMaxSliceLength(maxSliceLength int) ConvertOption {
+	return func(o *convertOptions) {
+		o.maxSliceLength = maxSliceLength
+	}
+}
+
+// produces a domain with zero fields (an empty `EIP712Domain()` type), ignoring the domain name
+//
+// the domain separator is then just the typeHash of `EIP712Domain()`, as some minimal verifiers expect
+func WithEmptyDomain() ConvertOption {
+	return func(o *convertOptions) {
+		o.emptyDomain = true
+	}
+}
+
+// ===== EIP-712 domain fields =====
+//
+// each domain field is independently optional; the EIP712Domain type only lists the fields that are set
+// (in EIP-712's order), and the domain name itself may be left empty when any of these are set
+
+// adds a `version` field to the domain
+func WithDomainVersion(version string) ConvertOption {
+	return func(o *convertOptions) {
+		o.domainVersion = version
+	}
+}
+
+// adds a `chainId` field to the domain
+func WithChainID(chainID uint64) ConvertOption {
+	return func(o *convertOptions) {
+		o.chainID = new(big.Int).SetUint64(chainID)
+	}
+}
+
+// adds a `verifyingContract` field to the domain, as a 0x-prefixed address (written checksummed)
+func WithVerifyingContract(address string) ConvertOption {
+	return func(o *convertOptions) {
+		o.verifyingContract = address
+	}
+}
+
+// adds a `salt` field to the domain, as a 0x-prefixed hex bytes32
+//
+// like verifyingContract, a malformed value fails the conversion rather than the option
+func WithSalt(salt string) ConvertOption {
+	return func(o *convertOptions) {
+		o.salt = salt
+	}
+}
@@ -4,12 +4,19 @@ import blocks "github.com/ipfs/go-block-format"
 
 // ===== DIDs =====
 
-type DID[T any] interface {
+// what every DID method implements, so code verifying txs can take an EthDID, KeyDID or BlsDID alike (or hold a
+// mix of them in a []DID)
+type DID interface {
 	String() string
-	Identifier() T
 	Verify(block blocks.Block, sig string) (bool, error)
 }
 
+// a DID that can also expose its underlying identifier (address, pub key, etc.)
+type IdentifiedDID[T any] interface {
+	DID
+	Identifier() T
+}
+
 // ===== provider interface (can be passed around later, depending on how DIDs want to be used) =====
 
 type Provider interface {
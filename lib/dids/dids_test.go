@@ -0,0 +1,67 @@
+package dids_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDIDsInterchangeable(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+
+	ethKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	edDID, err := dids.NewKeyDID(edPub)
+	assert.Nil(t, err)
+	secpKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	secpDID, err := dids.NewSecp256k1KeyDID(&secpKey.PublicKey)
+	assert.Nil(t, err)
+	blsProvider, err := dids.GenerateBlsProvider()
+	assert.Nil(t, err)
+	blsDID, err := blsProvider.DID()
+	assert.Nil(t, err)
+
+	// each signer, and the provider that signs for it
+	signers := []dids.DID{
+		dids.NewEthDID(crypto.PubkeyToAddress(ethKey.PublicKey).Hex()),
+		edDID,
+		secpDID,
+		blsDID,
+	}
+	providers := []dids.Provider{
+		dids.NewEthProviderFromKey(ethKey),
+		dids.NewKeyProvider(edPriv),
+		dids.NewSecp256k1KeyProvider(secpKey),
+		blsProvider,
+	}
+
+	sigs := make([]string, len(providers))
+	for i, provider := range providers {
+		sigs[i], err = provider.Sign(block)
+		assert.Nil(t, err)
+	}
+
+	for i, did := range signers {
+		valid, err := did.Verify(block, sigs[i])
+		assert.Nil(t, err, did.String())
+		assert.True(t, valid, did.String())
+
+		// and a DID string parses back to the same implementation
+		parsed, err := dids.ParseDID(did.String())
+		assert.Nil(t, err)
+		assert.Equal(t, did, parsed)
+	}
+
+	// nobody verifies someone else's sig
+	for i, did := range signers {
+		valid, _ := did.Verify(block, sigs[(i+1)%len(sigs)])
+		assert.False(t, valid, did.String())
+	}
+}
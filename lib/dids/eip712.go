@@ -0,0 +1,240 @@
+package dids
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ===== typeHash cache =====
+
+// how many encoded types typeHash remembers hashes for. the cache starts over once it's full, so payloads with
+// ever new schemas can't grow it without bound
+const maxTypeHashCacheSize = 4096
+
+// typeHashes keyed by the encoded type they hash, which is all a typeHash depends on
+var typeHashCache = struct {
+	sync.RWMutex
+	hashes map[string][]byte
+}{hashes: make(map[string][]byte)}
+
+// ===== EIP-712 struct hashing =====
+//
+// go-ethereum's apitypes validates type names as Solidity identifiers before encoding anything, which rejects
+// the dotted nested type names vsc uses (e.g. `tx_container_v0.tx.payload`) as well as the `undefined[]` type
+// used for empty arrays. the encoding itself is plain EIP-712 (and matches what the JS side signs), so the
+// struct level encoding lives here and apitypes is only used for primitive values
+
+// the 32 byte EIP-712 digest (keccak256 of 0x1901 ‖ domainSeparator ‖ hashStruct(message)) of typed data from
+// ConvertToEIP712TypedData
+//
+// this is the value to pass to crypto.Sign for a sig EthDID.Verify accepts. a domain with no fields only hashes
+// when the typed data was converted WithEmptyDomain, so one read back with UnmarshalJSON is an ErrEmptyDomain
+func ComputeEIP712Hash(typedData TypedData) ([]byte, error) {
+	return computeEIP712Hash(typedData.Data, typedData.emptyDomain)
+}
+
+// computes hashStruct for a single named type from the typed data's schema, given just its value
+//
+// handy for figuring out which nested struct's hash diverges from what a contract or wallet expects
+func HashStructFor(typedData TypedData, typeName string, value map[string]interface{}) ([]byte, error) {
+	if _, ok := typedData.Data.Types[typeName]; !ok {
+		return nil, fmt.Errorf("type %q is not defined in the typed data", typeName)
+	}
+	return hashStruct(typedData.Data, typeName, value)
+}
+
+// compares our EIP-712 hash of the typed data with the one a wallet reports (e.g. from eth_signTypedData),
+// returning whether they match and our hash as 0x hex
+//
+// a match means a sig that still won't verify went wrong in signing or key handling, not in hashing
+func VerifyHashMatchesWallet(typedData TypedData, walletHashHex string) (bool, string, error) {
+	computed, err := computeEIP712Hash(typedData.Data, typedData.emptyDomain)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to compute EIP-712 hash: %w", err)
+	}
+	computedHex := "0x" + hex.EncodeToString(computed)
+
+	walletHash, err := hex.DecodeString(normalizeHexInput(walletHashHex))
+	if err != nil {
+		return false, computedHex, fmt.Errorf("wallet hash is not valid hex: %w", err)
+	}
+	if len(walletHash) != len(computed) {
+		return false, computedHex, fmt.Errorf("wallet hash must be %d bytes, got %d", len(computed), len(walletHash))
+	}
+
+	return bytes.Equal(computed, walletHash), computedHex, nil
+}
+
+// the 32 byte domain separator (hashStruct(EIP712Domain)) of a domain, as a contract's DOMAIN_SEPARATOR() reports
+// it, for telling a domain mismatch apart from a message one
+//
+// the EIP712Domain type lists only the fields that are set, like everywhere else here. a domain with none set is an
+// ErrEmptyDomain, unless WithEmptyDomain is among opts, which gives it the typeHash of `EIP712Domain()`
+func ComputeDomainSeparator(domain apitypes.TypedDataDomain, opts ...ConvertOption) ([]byte, error) {
+	separator, err := hashDomain(domain, newConvertOptions(nil, opts).emptyDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash domain: %w", err)
+	}
+	return separator, nil
+}
+
+// keccak256(typeHash ‖ encodeData(value))
+func hashStruct(typedData apitypes.TypedData, typeName string, value map[string]interface{}) ([]byte, error) {
+	encoded, err := encodeData(typedData, typeName, value)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// keccak256(encodeType(typeName))
+//
+// remembered per encoded type, since validators see the same few schemas over and over
+func typeHash(types apitypes.Types, typeName string) []byte {
+	encoded := encodeType(types, typeName)
+
+	typeHashCache.RLock()
+	cached, ok := typeHashCache.hashes[encoded]
+	typeHashCache.RUnlock()
+	if ok {
+		return append([]byte{}, cached...)
+	}
+
+	hash := crypto.Keccak256([]byte(encoded))
+
+	typeHashCache.Lock()
+	if len(typeHashCache.hashes) >= maxTypeHashCacheSize {
+		typeHashCache.hashes = make(map[string][]byte)
+	}
+	typeHashCache.hashes[encoded] = hash
+	typeHashCache.Unlock()
+	return append([]byte{}, hash...)
+}
+
+// `Name(type₁ name₁,…)`, followed by each referenced struct type, sorted by name
+func encodeType(types apitypes.Types, typeName string) string {
+	deps := typeDependencies(types, typeName, nil)
+	sort.Strings(deps[1:])
+
+	var buffer strings.Builder
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String()
+}
+
+// every struct type reachable from typeName, with typeName itself first
+func typeDependencies(types apitypes.Types, typeName string, found []string) []string {
+	typeName = baseTypeName(typeName)
+	if _, ok := types[typeName]; !ok {
+		return found
+	}
+	for _, dep := range found {
+		if dep == typeName {
+			return found
+		}
+	}
+
+	found = append(found, typeName)
+	for _, field := range types[typeName] {
+		found = typeDependencies(types, field.Type, found)
+	}
+	return found
+}
+
+// strips any array suffixes, so `Foo[][2]` becomes `Foo`
+func baseTypeName(typeName string) string {
+	if i := strings.Index(typeName, "["); i >= 0 {
+		return typeName[:i]
+	}
+	return typeName
+}
+
+// typeHash ‖ enc(value₁) ‖ … ‖ enc(valueₙ)
+func encodeData(typedData apitypes.TypedData, typeName string, value map[string]interface{}) ([]byte, error) {
+	fields := typedData.Types[typeName]
+	if len(value) > len(fields) {
+		return nil, fmt.Errorf("there is extra data provided in the message for type %q (%d < %d)", typeName, len(fields), len(value))
+	}
+
+	var buffer bytes.Buffer
+	buffer.Write(typeHash(typedData.Types, typeName))
+
+	for _, field := range fields {
+		encoded, err := encodeValue(typedData, field.Type, value[field.Name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %q of type %q: %w", field.Name, typeName, err)
+		}
+		buffer.Write(encoded)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// encodes a single (32 byte) member value
+//
+// arrays are keccak256 of their concatenated element encodings, structs are their hashStruct
+func encodeValue(typedData apitypes.TypedData, encType string, value interface{}) ([]byte, error) {
+	if strings.HasSuffix(encType, "]") {
+		elemType := encType[:strings.LastIndex(encType, "[")]
+
+		arrayVal := reflect.ValueOf(value)
+		if arrayVal.Kind() != reflect.Slice && arrayVal.Kind() != reflect.Array {
+			return nil, fmt.Errorf("provided data '%v' is not an array for type '%s'", value, encType)
+		}
+
+		var buffer bytes.Buffer
+		for i := 0; i < arrayVal.Len(); i++ {
+			encoded, err := encodeValue(typedData, elemType, arrayVal.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+
+	if _, ok := typedData.Types[encType]; ok {
+		structValue, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("provided data '%v' doesn't match type '%s'", value, encType)
+		}
+		return hashStruct(typedData, encType, structValue)
+	}
+
+	// exact JSON numbers (see TypedData.UnmarshalJSON) are read like decimal strings
+	if n, ok := value.(json.Number); ok {
+		value = n.String()
+	}
+
+	// apitypes reads 0x hex and decimal strings, but not negative hex (as NumberFormatHex renders it)
+	if s, ok := value.(string); ok && strings.HasPrefix(encType, "int") && strings.HasPrefix(s, "-0x") {
+		n, ok := math.ParseBig256(s[1:])
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %q for type '%s'", s, encType)
+		}
+		value = n.Neg(n)
+	}
+
+	return typedData.EncodePrimitiveValue(encType, value, 0)
+}
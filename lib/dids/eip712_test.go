@@ -0,0 +1,397 @@
+package dids_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/assert"
+)
+
+// same payload as TestEIP712RealDataCase
+func realDataCase() map[string]interface{} {
+	return map[string]interface{}{
+		"tx": map[string]interface{}{
+			"op": "transfer",
+			"payload": map[string]interface{}{
+				"tk":     "HIVE",
+				"to":     "hive:XXXXX",
+				"from":   "did:pkh:eip155:1:YYYYY",
+				"amount": uint64(1),
+			},
+		},
+		"__t": "vsc-tx",
+		"__v": "0.2",
+		"headers": map[string]interface{}{
+			"type":    uint64(1),
+			"nonce":   uint64(1),
+			"intents": []interface{}{},
+			"required_auths": []string{
+				"did:pkh:eip155:1:YYYYY",
+			},
+		},
+	}
+}
+
+func TestHashStructForNestedPayload(t *testing.T) {
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", realDataCase(), "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+
+	payload := typedData.Data.Message["tx"].(map[string]interface{})["payload"].(map[string]interface{})
+
+	hash, err := dids.HashStructFor(typedData, "tx_container_v0.tx.payload", payload)
+	assert.Nil(t, err)
+
+	// manual computation: keccak256(typeHash ‖ enc(amount) ‖ enc(from) ‖ enc(tk) ‖ enc(to)), fields being sorted by name
+	expected := crypto.Keccak256(
+		crypto.Keccak256([]byte("tx_container_v0.tx.payload(uint256 amount,string from,string tk,string to)")),
+		math.U256Bytes(big.NewInt(1)),
+		crypto.Keccak256([]byte("did:pkh:eip155:1:YYYYY")),
+		crypto.Keccak256([]byte("HIVE")),
+		crypto.Keccak256([]byte("hive:XXXXX")),
+	)
+	assert.Equal(t, expected, hash)
+
+	// unknown types are an error
+	_, err = dids.HashStructFor(typedData, "tx_container_v0.nope", payload)
+	assert.NotNil(t, err)
+}
+
+func TestTypedDataGoldenString(t *testing.T) {
+	convert := func() dids.TypedData {
+		typedData, err := dids.ConvertToEIP712TypedData("vsc.network", realDataCase(), "tx_container_v0", func(f float64) (*big.Int, error) {
+			return big.NewInt(int64(f)), nil
+		})
+		assert.Nil(t, err)
+		return typedData
+	}
+
+	// map iteration order changes between conversions, the golden string doesn't
+	golden := convert().GoldenString()
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, golden, convert().GoldenString())
+	}
+
+	expected, err := os.ReadFile("testdata/real_data_case.golden")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), golden)
+}
+
+func TestConvertDeterministicTypes(t *testing.T) {
+	// many keys at several levels, so differing map iteration orders would show
+	payload := func() map[string]interface{} {
+		data := realDataCase()
+		data["extra"] = map[string]interface{}{
+			"zeta": "z", "alpha": "a", "mid": map[string]uint64{"b": 2, "a": 1, "c": 3},
+			"omega": []string{"x"}, "beta": uint64(2), "gamma": true,
+		}
+		return data
+	}
+	convert := func() dids.TypedData {
+		typedData, err := dids.ConvertToEIP712TypedData("vsc.network", payload(), "tx_container_v0", func(f float64) (*big.Int, error) {
+			return big.NewInt(int64(f)), nil
+		})
+		assert.Nil(t, err)
+		return typedData
+	}
+
+	first := convert()
+	firstHash, err := dids.ComputeEIP712Hash(first)
+	assert.Nil(t, err)
+
+	// nested type names come from field paths and fields are emitted sorted by name, whatever order the maps give
+	assert.Equal(t, []string{"alpha", "beta", "gamma", "mid", "omega", "zeta"}, typeFieldNames(first.Data.Types["tx_container_v0.extra"]))
+	assert.Equal(t, []string{"a", "b", "c"}, typeFieldNames(first.Data.Types["tx_container_v0.extra.mid"]))
+
+	for i := 0; i < 100; i++ {
+		again := convert()
+		assert.Equal(t, first.Data.Types, again.Data.Types)
+		hash, err := dids.ComputeEIP712Hash(again)
+		assert.Nil(t, err)
+		assert.Equal(t, firstHash, hash)
+	}
+}
+
+func typeFieldNames(fields []apitypes.Type) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+func TestEthDIDVerifyNestedPayload(t *testing.T) {
+	// nested maps produce dotted type names (and the empty array an `undefined[]` type), which must still hash
+	data := map[string]interface{}{
+		"tx": map[string]interface{}{
+			"op":      "transfer",
+			"payload": map[string]interface{}{"to": "hive:bob", "amount": 5},
+		},
+		"headers": map[string]interface{}{
+			"nonce":   2,
+			"intents": []interface{}{},
+		},
+	}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestVerifyHashMatchesWallet(t *testing.T) {
+	typedData, err := dids.NewEthProvider().TypedData(map[string]interface{}{"op": "transfer", "amount": 10})
+	assert.Nil(t, err)
+
+	// the hash a wallet would compute: keccak256(0x1901 ‖ domainSeparator ‖ hashStruct(message))
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name)")),
+		crypto.Keccak256([]byte("vsc.network")),
+	)
+	messageHash, err := dids.HashStructFor(typedData, "tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	walletHash := "0x" + hex.EncodeToString(crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash))
+
+	matches, computed, err := dids.VerifyHashMatchesWallet(typedData, walletHash)
+	assert.Nil(t, err)
+	assert.True(t, matches)
+	assert.Equal(t, walletHash, computed)
+
+	// a wallet that hashed something else
+	mismatched := "0x" + hex.EncodeToString(crypto.Keccak256([]byte("something else")))
+	matches, computed, err = dids.VerifyHashMatchesWallet(typedData, mismatched)
+	assert.Nil(t, err)
+	assert.False(t, matches)
+	assert.Equal(t, walletHash, computed)
+
+	// and garbage isn't a hash at all
+	_, _, err = dids.VerifyHashMatchesWallet(typedData, "0x1234")
+	assert.NotNil(t, err)
+}
+
+func TestComputeDomainSeparator(t *testing.T) {
+	for name, tc := range map[string]struct {
+		domain apitypes.TypedDataDomain
+		fields []apitypes.Type
+	}{
+		"name only": {
+			domain: apitypes.TypedDataDomain{Name: "vsc.network"},
+			fields: []apitypes.Type{{Name: "name", Type: "string"}},
+		},
+		"name and chainId": {
+			domain: apitypes.TypedDataDomain{Name: "vsc.network", ChainId: (*math.HexOrDecimal256)(big.NewInt(137))},
+			fields: []apitypes.Type{{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+		},
+	} {
+		separator, err := dids.ComputeDomainSeparator(tc.domain)
+		assert.Nil(t, err, name)
+		assert.Len(t, separator, 32, name)
+
+		geth := apitypes.TypedData{Types: apitypes.Types{"EIP712Domain": tc.fields}, Domain: tc.domain}
+		expected, err := geth.HashStruct("EIP712Domain", tc.domain.Map())
+		assert.Nil(t, err, name)
+		assert.Equal(t, []byte(expected), separator, name)
+	}
+
+	// it's the separator the full digest is built from
+	typedData, err := dids.NewEthProvider().TypedData(map[string]interface{}{"op": "transfer"})
+	assert.Nil(t, err)
+	separator, err := dids.ComputeDomainSeparator(typedData.Data.Domain)
+	assert.Nil(t, err)
+	messageHash, err := dids.HashStructFor(typedData, "tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	digest, err := dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte("\x19\x01"), separator, messageHash), digest)
+
+	// a domain with no fields only hashes when asked for
+	_, err = dids.ComputeDomainSeparator(apitypes.TypedDataDomain{})
+	assert.ErrorIs(t, err, dids.ErrEmptyDomain)
+	separator, err = dids.ComputeDomainSeparator(apitypes.TypedDataDomain{}, dids.WithEmptyDomain())
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte("EIP712Domain()")), separator)
+}
+
+func TestComputeEIP712HashEmptyDomain(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer"}
+	typedData, err := dids.ConvertToEIP712TypedData("", data, "tx_container_v0", nil, dids.WithEmptyDomain())
+	assert.Nil(t, err)
+	_, err = dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+
+	// the same typed data read back from JSON didn't opt in, so its empty domain doesn't hash
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var unmarshalled dids.TypedData
+	assert.Nil(t, unmarshalled.UnmarshalJSON(marshalled))
+	_, err = dids.ComputeEIP712Hash(unmarshalled)
+	assert.ErrorIs(t, err, dids.ErrEmptyDomain)
+}
+
+func TestTypeHashCached(t *testing.T) {
+	// a schema no other test uses, so the first hash isn't already cached
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"op": "transfer", "nonce": uint64(7)}, "typehash_cache_v0", nil)
+	assert.Nil(t, err)
+
+	expected := crypto.Keccak256(
+		crypto.Keccak256([]byte("typehash_cache_v0(uint256 nonce,string op)")),
+		math.U256Bytes(big.NewInt(7)),
+		crypto.Keccak256([]byte("transfer")),
+	)
+
+	// the first hash computes the typeHash, the rest come from the cache, and all agree
+	for i := 0; i < 3; i++ {
+		hash, err := dids.HashStructFor(typedData, "typehash_cache_v0", typedData.Data.Message)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, hash)
+	}
+}
+
+// a validator verifying many txs of one shape, which only differ in their values
+func BenchmarkVerifySameSchema(b *testing.B) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(b, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	txBlocks := make([]blocks.Block, 100)
+	sigs := make([]string, len(txBlocks))
+	for i := range txBlocks {
+		data := realDataCase()
+		data["headers"].(map[string]interface{})["nonce"] = uint64(i)
+		txBlocks[i], err = dids.EncodeBlock(data)
+		assert.Nil(b, err)
+		sigs[i], err = provider.Sign(txBlocks[i])
+		assert.Nil(b, err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % len(txBlocks)
+		if valid, err := did.Verify(txBlocks[j], sigs[j]); err != nil || !valid {
+			b.Fatal(valid, err)
+		}
+	}
+}
+
+func TestConvertPreEncodedStruct(t *testing.T) {
+	provider := dids.NewEthProvider()
+	full, err := provider.TypedData(realDataCase())
+	assert.Nil(t, err)
+
+	// convert the payload once, under the type name it's embedded with
+	payload := realDataCase()["tx"].(map[string]interface{})["payload"]
+	converted, err := dids.ConvertToEIP712TypedData("vsc.network", payload, "tx_container_v0.tx.payload", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+	preEncoded, err := dids.NewPreEncodedStruct(converted)
+	assert.Nil(t, err)
+
+	// then splice it in instead of the raw payload
+	data := realDataCase()
+	data["tx"].(map[string]interface{})["payload"] = preEncoded
+	spliced, err := provider.TypedData(data)
+	assert.Nil(t, err)
+
+	assert.Equal(t, full.Data.Types, spliced.Data.Types)
+	assert.Equal(t, full.Data.Message, spliced.Data.Message)
+
+	fullHash, err := dids.HashStructFor(full, "tx_container_v0", full.Data.Message)
+	assert.Nil(t, err)
+	splicedHash, err := dids.HashStructFor(spliced, "tx_container_v0", spliced.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, fullHash, splicedHash)
+}
+
+func TestSchemaOnly(t *testing.T) {
+	schemaJSON, err := dids.SchemaOnly("vsc.network", realDataCase(), "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+
+	var schema map[string]interface{}
+	assert.Nil(t, json.Unmarshal(schemaJSON, &schema))
+
+	// no values
+	assert.NotContains(t, schema, "message")
+
+	// but the whole schema
+	assert.Equal(t, "tx_container_v0", schema["primaryType"])
+	assert.Equal(t, map[string]interface{}{"name": "vsc.network"}, schema["domain"])
+	assert.Contains(t, schema, "EIP712Domain")
+
+	types := schema["types"].(map[string]interface{})
+	for _, typeName := range []string{"tx_container_v0", "tx_container_v0.tx", "tx_container_v0.tx.payload", "tx_container_v0.headers"} {
+		assert.Contains(t, types, typeName)
+	}
+	assert.Contains(t, types["tx_container_v0.tx.payload"], map[string]interface{}{"name": "amount", "type": "uint256"})
+}
+
+func TestConvertOrderedMap(t *testing.T) {
+	payload := dids.NewOrderedMap().
+		Set("to", "hive:bob").
+		Set("amount", uint64(1)).
+		Set("asset", "HIVE")
+	data := dids.NewOrderedMap().
+		Set("op", "transfer").
+		Set("payload", payload).
+		Set("nonce", uint64(7))
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+
+	// fields keep their insertion order instead of being sorted
+	var fieldNames []string
+	for _, field := range typedData.Data.Types["tx"] {
+		fieldNames = append(fieldNames, field.Name)
+	}
+	assert.Equal(t, []string{"op", "payload", "nonce"}, fieldNames)
+	fieldNames = nil
+	for _, field := range typedData.Data.Types["tx.payload"] {
+		fieldNames = append(fieldNames, field.Name)
+	}
+	assert.Equal(t, []string{"to", "amount", "asset"}, fieldNames)
+
+	// and the message JSON follows the same order
+	typedDataJSON, err := json.Marshal(typedData)
+	assert.Nil(t, err)
+	var parsed struct {
+		Message json.RawMessage `json:"message"`
+	}
+	assert.Nil(t, json.Unmarshal(typedDataJSON, &parsed))
+	assert.Equal(t, `{"op":"transfer","payload":{"to":"hive:bob","amount":1,"asset":"HIVE"},"nonce":7}`, string(parsed.Message))
+
+	// the order is part of the type, so the hash differs from the sorted conversion of the same data
+	sorted, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"op":      "transfer",
+		"payload": map[string]interface{}{"to": "hive:bob", "amount": uint64(1), "asset": "HIVE"},
+		"nonce":   uint64(7),
+	}, "tx", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+	zeroHash := "0x" + hex.EncodeToString(make([]byte, 32))
+	_, orderedHash, err := dids.VerifyHashMatchesWallet(typedData, zeroHash)
+	assert.Nil(t, err)
+	_, sortedHash, err := dids.VerifyHashMatchesWallet(sorted, zeroHash)
+	assert.Nil(t, err)
+	assert.NotEqual(t, orderedHash, sortedHash)
+}
@@ -0,0 +1,149 @@
+package dids
+
+import (
+	"fmt"
+	"math"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== errors =====
+
+var ErrNotATransaction = fmt.Errorf("block is not a vsc transaction: missing tx object")
+
+// ===== Envelope =====
+
+// the well-known fields of a vsc tx, as signed in a tx_container_v0
+//
+// optional fields that are missing from the block are left as their zero value
+type Envelope struct {
+	Type    string // __t
+	Version string // __v
+
+	Op     string
+	From   string
+	To     string
+	Asset  string // tk
+	Amount uint64
+
+	Nonce         uint64
+	TxType        uint64 // headers.type
+	RequiredAuths []string
+}
+
+// decodes a tx block and pulls out its well-known fields
+//
+// the op args can either be nested under tx.payload (the shape the Bitcoin wrapper UI produces) or
+// sit directly on the tx object
+func ExtractEnvelope(block blocks.Block) (Envelope, error) {
+	var decodedData map[string]interface{}
+	if err := decodeFromCBOR(block.RawData(), &decodedData); err != nil {
+		return Envelope{}, fmt.Errorf("failed to decode CBOR data: %w", err)
+	}
+
+	tx, ok := decodedData["tx"].(map[string]interface{})
+	if !ok {
+		return Envelope{}, ErrNotATransaction
+	}
+
+	envelope := Envelope{}
+	var err error
+
+	if envelope.Type, err = optionalString(decodedData, "__t"); err != nil {
+		return Envelope{}, err
+	}
+	if envelope.Version, err = optionalString(decodedData, "__v"); err != nil {
+		return Envelope{}, err
+	}
+	if envelope.Op, err = optionalString(tx, "op"); err != nil {
+		return Envelope{}, fmt.Errorf("tx.%w", err)
+	}
+
+	// op args are under tx.payload if present, else directly on tx
+	payload := tx
+	payloadPath := "tx"
+	if nested, ok := tx["payload"].(map[string]interface{}); ok {
+		payload = nested
+		payloadPath = "tx.payload"
+	}
+
+	if envelope.From, err = optionalString(payload, "from"); err != nil {
+		return Envelope{}, fmt.Errorf("%s.%w", payloadPath, err)
+	}
+	if envelope.To, err = optionalString(payload, "to"); err != nil {
+		return Envelope{}, fmt.Errorf("%s.%w", payloadPath, err)
+	}
+	if envelope.Asset, err = optionalString(payload, "tk"); err != nil {
+		return Envelope{}, fmt.Errorf("%s.%w", payloadPath, err)
+	}
+	if envelope.Amount, err = optionalUint(payload, "amount"); err != nil {
+		return Envelope{}, fmt.Errorf("%s.%w", payloadPath, err)
+	}
+
+	// headers are optional as a whole
+	if headers, ok := decodedData["headers"].(map[string]interface{}); ok {
+		if envelope.Nonce, err = optionalUint(headers, "nonce"); err != nil {
+			return Envelope{}, fmt.Errorf("headers.%w", err)
+		}
+		if envelope.TxType, err = optionalUint(headers, "type"); err != nil {
+			return Envelope{}, fmt.Errorf("headers.%w", err)
+		}
+		if auths, ok := headers["required_auths"].([]interface{}); ok {
+			for _, auth := range auths {
+				authStr, ok := auth.(string)
+				if !ok {
+					return Envelope{}, fmt.Errorf("headers.required_auths: expected strings, got %T", auth)
+				}
+				envelope.RequiredAuths = append(envelope.RequiredAuths, authStr)
+			}
+		}
+	}
+
+	return envelope, nil
+}
+
+// ===== utils =====
+
+// reads a string field, treating a missing field as empty
+func optionalString(data map[string]interface{}, field string) (string, error) {
+	value, ok := data[field]
+	if !ok || value == nil {
+		return "", nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: expected string, got %T", field, value)
+	}
+	return str, nil
+}
+
+// reads a non-negative integer field, treating a missing field as 0
+func optionalUint(data map[string]interface{}, field string) (uint64, error) {
+	value, ok := data[field]
+	if !ok || value == nil {
+		return 0, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case int64:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case uint64:
+		return v, nil
+	case float64:
+		// JSON-ish sources may hand us whole numbers as floats. math.MaxUint64 rounds up to 2^64 as a float, which
+		// is already out of range
+		if v >= 0 && v < math.MaxUint64 && v == math.Trunc(v) {
+			return uint64(v), nil
+		}
+	default:
+		return 0, fmt.Errorf("%s: expected integer, got %T", field, value)
+	}
+
+	return 0, fmt.Errorf("%s: expected non-negative integer, got %v", field, value)
+}
@@ -0,0 +1,88 @@
+package dids_test
+
+import (
+	"math"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractEnvelopeRealDataCase(t *testing.T) {
+	// same shape as TestEIP712RealDataCase
+	data := map[string]interface{}{
+		"tx": map[string]interface{}{
+			"op": "transfer",
+			"payload": map[string]interface{}{
+				"tk":     "HIVE",
+				"to":     "hive:XXXXX",
+				"from":   "did:pkh:eip155:1:YYYYY",
+				"amount": uint64(1),
+			},
+		},
+		"__t": "vsc-tx",
+		"__v": "0.2",
+		"headers": map[string]interface{}{
+			"type":    uint64(1),
+			"nonce":   uint64(7),
+			"intents": []interface{}{},
+			"required_auths": []string{
+				"did:pkh:eip155:1:YYYYY",
+			},
+		},
+	}
+
+	envelope, err := dids.ExtractEnvelope(createCBORBlock(t, data))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "transfer", envelope.Op)
+	assert.Equal(t, "hive:XXXXX", envelope.To)
+	assert.Equal(t, "did:pkh:eip155:1:YYYYY", envelope.From)
+	assert.Equal(t, "HIVE", envelope.Asset)
+	assert.Equal(t, uint64(1), envelope.Amount)
+	assert.Equal(t, uint64(7), envelope.Nonce)
+	assert.Equal(t, uint64(1), envelope.TxType)
+	assert.Equal(t, "vsc-tx", envelope.Type)
+	assert.Equal(t, "0.2", envelope.Version)
+	assert.Equal(t, []string{"did:pkh:eip155:1:YYYYY"}, envelope.RequiredAuths)
+}
+
+func TestExtractEnvelopeFlatAndMissingFields(t *testing.T) {
+	// op args directly on tx, no headers at all
+	data := map[string]interface{}{
+		"tx": map[string]interface{}{
+			"op": "stake",
+			"to": "hive:alice",
+		},
+	}
+
+	envelope, err := dids.ExtractEnvelope(createCBORBlock(t, data))
+	assert.Nil(t, err)
+	assert.Equal(t, "stake", envelope.Op)
+	assert.Equal(t, "hive:alice", envelope.To)
+	assert.Equal(t, "", envelope.From)
+	assert.Equal(t, uint64(0), envelope.Amount)
+	assert.Equal(t, uint64(0), envelope.Nonce)
+	assert.Nil(t, envelope.RequiredAuths)
+
+	// not a tx at all
+	_, err = dids.ExtractEnvelope(createCBORBlock(t, map[string]interface{}{"foo": "bar"}))
+	assert.ErrorIs(t, err, dids.ErrNotATransaction)
+
+	// wrongly typed fields are errors, not silently dropped
+	_, err = dids.ExtractEnvelope(createCBORBlock(t, map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "amount": "lots"},
+	}))
+	assert.NotNil(t, err)
+
+	// as are floats past uint64, 2^64 included
+	_, err = dids.ExtractEnvelope(createCBORBlock(t, map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "amount": math.Pow(2, 64)},
+	}))
+	assert.NotNil(t, err)
+	envelope, err = dids.ExtractEnvelope(createCBORBlock(t, map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "amount": math.Pow(2, 63)},
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1)<<63, envelope.Amount)
+}
@@ -1,19 +1,27 @@
 package dids
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	cbor "github.com/ipfs/go-ipld-cbor"
 
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
 )
 
 // ===== constants =====
@@ -23,10 +31,44 @@ import (
 // - https://github.com/w3c-ccg/did-pkh/blob/main/did-pkh-method-draft.md
 const EthDIDPrefix = "did:pkh:eip155:1:"
 
+// the EIP-712 domain name and primary type vsc txs are signed under
+const (
+	vscDomainName  = "vsc.network"
+	vscPrimaryType = "tx_container_v0"
+)
+
+// ===== errors =====
+
+var (
+	ErrNoPrivateKey    = fmt.Errorf("provider has no private key to sign with")
+	ErrNotTypedDataDID = fmt.Errorf("DID does not sign EIP-712 typed data")
+	ErrNotEVMDID       = fmt.Errorf("DID is not an EVM address DID")
+	ErrInvalidAddress  = fmt.Errorf("not a 0x-prefixed 20 byte hex address")
+
+	ErrEmptyDomain      = fmt.Errorf("domain name cannot be empty")
+	ErrEmptyPrimaryType = fmt.Errorf("primary type name cannot be empty")
+	ErrFloatHandler     = fmt.Errorf("failed to handle float value")
+	ErrNilValue         = fmt.Errorf("nil value")
+	ErrNonFiniteFloat   = fmt.Errorf("float is NaN or infinite")
+	ErrInexactFloat     = fmt.Errorf("float is not a whole number")
+	ErrFloatsRejected   = fmt.Errorf("floats are not allowed in this payload")
+	ErrConvertLimit     = fmt.Errorf("payload exceeds a conversion limit")
+)
+
+// a payload value that has no EIP-712 type (a func or chan, say), at the dotted path of the field holding it
+type UnsupportedTypeError struct {
+	FieldPath string
+	Kind      reflect.Kind
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported field type %s for field %s", e.Kind, e.FieldPath)
+}
+
 // ===== interface assertions =====
 
 // ethr addr | payload type
-var _ DID[string] = EthDID("")
+var _ IdentifiedDID[string] = EthDID("")
 
 var _ Provider = &EthProvider{}
 
@@ -34,14 +76,102 @@ var _ Provider = &EthProvider{}
 
 type EthDID string
 
+// a valid address is stored EIP-55 checksummed, whatever its casing, so DIDs for the same address are equal
+//
+// anything else is kept as given (and won't verify), use NewEthDIDChecked to reject it instead
 func NewEthDID(ethAddr string) EthDID {
+	if checksummed, err := checksumAddress(ethAddr); err == nil {
+		ethAddr = checksummed
+	}
 	return EthDID(EthDIDPrefix + ethAddr)
 }
 
+// like NewEthDID, but erroring with ErrInvalidAddress unless ethAddr is a 0x-prefixed 20 byte hex address
+func NewEthDIDChecked(ethAddr string) (EthDID, error) {
+	checksummed, err := checksumAddress(ethAddr)
+	if err != nil {
+		return "", err
+	}
+	return EthDID(EthDIDPrefix + checksummed), nil
+}
+
+// creates the DID of the address a secp256k1 pub key controls, from either its compressed (33 byte) or
+// uncompressed (65 byte) encoding
+func NewEthDIDFromPubKey(pubKeyBytes []byte) (EthDID, error) {
+	var pubKey *ecdsa.PublicKey
+	var err error
+	switch len(pubKeyBytes) {
+	case 33:
+		pubKey, err = crypto.DecompressPubkey(pubKeyBytes)
+	case 65:
+		pubKey, err = crypto.UnmarshalPubkey(pubKeyBytes)
+	default:
+		return "", fmt.Errorf("public key must be 33 (compressed) or 65 (uncompressed) bytes, got %d", len(pubKeyBytes))
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid secp256k1 public key: %w", err)
+	}
+
+	return NewEthDID(crypto.PubkeyToAddress(*pubKey).Hex()), nil
+}
+
+// the lowercase 0x address behind an EVM DID string, in any of the forms vsc uses: the EthDID form
+// (did:pkh:eip155:1:0x…), did:pkh on any other eip155 chain, or a bare address
+//
+// non-EVM DIDs (did:key, etc.) are an ErrNotEVMDID
+func AddressFromDID(didStr string) (string, error) {
+	address := strings.TrimSpace(didStr)
+	if strings.HasPrefix(address, "did:") {
+		if !strings.HasPrefix(address, "did:pkh:eip155:") {
+			return "", fmt.Errorf("%w: %q", ErrNotEVMDID, didStr)
+		}
+		// did:pkh:eip155:<chain id>:<address>
+		parts := strings.Split(address, ":")
+		if len(parts) != 5 || parts[3] == "" {
+			return "", fmt.Errorf("malformed did:pkh %q", didStr)
+		}
+		address = parts[4]
+	}
+
+	hasPrefix := strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X")
+	if !hasPrefix || !common.IsHexAddress(address) {
+		return "", fmt.Errorf("invalid address in %q", didStr)
+	}
+	return "0x" + strings.ToLower(address[2:]), nil
+}
+
+// whether two DIDs name the same EVM account, comparing their 20 byte addresses whatever form the DIDs take
+// (EthDID, did:pkh on any chain, any address casing)
+//
+// DIDs that aren't EVM addresses (did:key, etc.) never match, even themselves
+func SameAccount(a, b DID) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	addressA, err := AddressFromDID(a.String())
+	if err != nil {
+		return false
+	}
+	addressB, err := AddressFromDID(b.String())
+	if err != nil {
+		return false
+	}
+	return addressA == addressB
+}
+
 // ===== implementing the DID interface =====
 
+// the DID with its address EIP-55 checksummed, so DIDs for the same address always render (and key maps) the
+// same whatever casing they were made with. anything that isn't a valid EthDID is returned as is
 func (d EthDID) String() string {
-	return string(d)
+	if !strings.HasPrefix(string(d), EthDIDPrefix) {
+		return string(d)
+	}
+	checksummed, err := checksumAddress(d.Identifier())
+	if err != nil {
+		return string(d)
+	}
+	return EthDIDPrefix + checksummed
 }
 
 func (d EthDID) Identifier() string {
@@ -49,90 +179,496 @@ func (d EthDID) Identifier() string {
 	// 0x123...
 	//
 	// remove "did:ethr:" prefix
-	return string(d)[len(EthDIDPrefix):]
+	return strings.TrimPrefix(string(d), EthDIDPrefix)
 }
 
 func (d EthDID) Verify(block blocks.Block, sig string) (bool, error) {
-	// decode the block using CBOR into a generic type of map[string]interface
-	var decodedData map[string]interface{}
-	if err := decodeFromCBOR(block.RawData(), &decodedData); err != nil {
-		return false, fmt.Errorf("failed to decode CBOR data: %v", err)
+	return d.VerifyWithOptions(block, sig)
+}
+
+// ===== other methods =====
+
+// whether the other DID names the same address on chain 1, comparing addresses case-insensitively (so a
+// lowercase EthDID equals its checksummed form, as does a did:pkh chain 1 DID for the address)
+func (d EthDID) Equal(other DID) bool {
+	if other == nil || !strings.HasPrefix(other.String(), EthDIDPrefix) {
+		return false
 	}
+	return SameAccount(d, other)
+}
 
-	// convert the sorted decoded data into EIP-712 typed data
-	payload, err := ConvertToEIP712TypedData("vsc.network", decodedData, "tx_container_v0", func(f float64) (*big.Int, error) {
-		return big.NewInt(int64(f)), nil
-	})
+// the did:pkh:eip155 form of the DID's address on the given chain (for chain 1, the DID's own string)
+func (d EthDID) ToPkh(chainID uint64) string {
+	return NewPkhDID(chainID, d.Identifier()).String()
+}
+
+// verifies like Verify, but lets the caller change how the signed typed data is reconstructed (domain, primary type, conversion options)
+func (d EthDID) VerifyWithOptions(block blocks.Block, sig string, opts ...VerifyOption) (bool, error) {
+	_, result, err := d.verify(block, sig, newVerifyOptions(opts))
+	return result.Valid, err
+}
+
+// verifies like VerifyWithOptions, for callers holding the block's dag-cbor bytes rather than the block
+func (d EthDID) VerifyBytes(cborData []byte, sig string, opts ...VerifyOption) (bool, error) {
+	block, err := blockFromCBOR(cborData)
 	if err != nil {
-		return false, fmt.Errorf("failed to convert block to EIP-712 typed data: %v", err)
+		return false, err
 	}
+	return d.VerifyWithOptions(block, sig, opts...)
+}
 
-	// compute the EIP-712 hash
-	dataHash, err := computeEIP712Hash(payload.Data)
+// verifies like VerifyWithOptions under ctx (as WithContext sets it), so a cancelled or timed out ctx stops the
+// check with ctx's error, including while waiting on a contract wallet's node (EIP-1271/EIP-6492)
+func (d EthDID) VerifyContext(ctx context.Context, block blocks.Block, sig string, opts ...VerifyOption) (bool, error) {
+	return d.VerifyWithOptions(block, sig, append(opts, WithContext(ctx))...)
+}
+
+// the details of a sig check, from EthDID.VerifyDetailed
+type VerifyResult struct {
+	Valid bool
+
+	// the EIP-712 hash the sig was checked against
+	Hash []byte
+
+	// the address the sig recovers to (empty for sigs that aren't ECDSA recoverable, like EIP-6492 ones)
+	RecoveredAddress string
+
+	// the reconstructed message the sig covers, only set with WithMessage
+	Message map[string]interface{}
+
+	// the same message as deterministic sorted-key JSON, only set with WithMessage
+	MessageJSON []byte
+}
+
+// verifies like VerifyWithOptions, but reports what was checked (the hash, the recovered signer and, with
+// WithMessage, the signed message itself) so it can be shown alongside the outcome
+func (d EthDID) VerifyDetailed(block blocks.Block, sig string, opts ...VerifyOption) (VerifyResult, error) {
+	verifyOpts := newVerifyOptions(opts)
+
+	typedData, result, err := d.verify(block, sig, verifyOpts)
 	if err != nil {
-		return false, fmt.Errorf("failed to compute EIP-712 hash: %v", err)
+		return result, err
 	}
 
-	// decode the sig from the hex
-	sigBytes, err := hex.DecodeString(sig)
+	if verifyOpts.includeMessage {
+		result.Message = typedData.Data.Message
+		result.MessageJSON, err = marshalMessageJSON(typedData.Data.Types, typedData.Data.PrimaryType, typedData.Data.Message)
+		if err != nil {
+			return result, fmt.Errorf("failed to marshal message: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// verifies an ERC-191 personal_sign sig (`\x19Ethereum Signed Message:\n<len>` ‖ message) over raw bytes, such as a
+// block's CBOR payload
+//
+// this is a separate scheme from the EIP-712 sigs Verify checks, so a sig made one way never passes the other. v
+// may be 0/1 or 27/28
+func (d EthDID) VerifyPersonalSign(message []byte, sig string) (bool, error) {
+	sigBytes, err := decodeSigHex(sig, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(sigBytes) != crypto.SignatureLength {
+		return false, fmt.Errorf("personal_sign signature must be %d bytes, got %d", crypto.SignatureLength, len(sigBytes))
+	}
+	sigBytes, err = recoverableSig(sigBytes, nil)
 	if err != nil {
-		return false, fmt.Errorf("failed to decode signature: %v", err)
+		return false, err
 	}
 
-	// recover the pub key from the signature and data hash
-	pubKey, err := crypto.SigToPub(dataHash, sigBytes)
+	pubKey, err := crypto.SigToPub(accounts.TextHash(message), sigBytes)
 	if err != nil {
 		return false, fmt.Errorf("failed to recover public key from signature: %v", err)
 	}
 
-	// extract the recovered addr
-	recoveredAddress := crypto.PubkeyToAddress(*pubKey).Hex()
+	return strings.EqualFold(crypto.PubkeyToAddress(*pubKey).Hex(), "0x"+normalizeHexInput(d.Identifier())), nil
+}
+
+// verifies the sig, also returning the typed data it was checked against (once the block got that far) and the details
+//
+// the options' context is checked before starting and again before the sig is checked, besides being passed on to
+// anything that reaches out over the network (contract wallets, link fetching)
+func (d EthDID) verify(block blocks.Block, sig string, verifyOpts *verifyOptions) (TypedData, VerifyResult, error) {
+	if err := verifyOpts.ctx.Err(); err != nil {
+		return TypedData{}, VerifyResult{}, err
+	}
+
+	// rebuild the typed data the sig should cover, and its EIP-712 hash
+	payload, dataHash, err := blockTypedDataHash(block, verifyOpts)
+	if err != nil {
+		return payload, VerifyResult{}, err
+	}
+	if err := verifyOpts.ctx.Err(); err != nil {
+		return payload, VerifyResult{Hash: dataHash}, err
+	}
+
+	result, err := d.verifyHash(dataHash, sig, verifyOpts)
+	return payload, result, err
+}
+
+// checks the sig against an already computed EIP-712 hash
+func (d EthDID) verifyHash(dataHash []byte, sig string, verifyOpts *verifyOptions) (VerifyResult, error) {
+	result := VerifyResult{Hash: dataHash}
+
+	// decode the sig from hex (accepting any casing, an optional 0x prefix and stray whitespace unless strict) or
+	// base64
+	sigBytes, err := decodeSig(sig, verifyOpts.sigEncoding, verifyOpts.strictInputs)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode signature: %w", err)
+	}
 
 	// get the expected addr from the DID
 	expectedAddress := d.Identifier()
+	if !verifyOpts.strictInputs {
+		expectedAddress = "0x" + normalizeHexInput(expectedAddress)
+	}
+
+	// counterfactual smart wallets can't be ECDSA recovered, so their wrapped sig is validated via the deploy data
+	if IsEIP6492Signature(sigBytes) {
+		result.Valid, err = verifyEIP6492(verifyOpts.ctx, verifyOpts.counterfactualValidator, common.HexToAddress(expectedAddress), dataHash, sigBytes)
+		return result, err
+	}
+
+	// anything else is checked as an ECDSA sig (65 bytes, or 64 in EIP-2098 form) before trying to recover it,
+	// unless a contract wallet is there to judge sigs of its own format
+	ecdsaSig, err := recoverableSig(sigBytes, verifyOpts.chainID())
+	if err != nil {
+		if verifyOpts.contractCaller != nil {
+			result.Valid, err = verifyEIP1271(verifyOpts.ctx, verifyOpts.contractCaller, common.HexToAddress(expectedAddress), dataHash, sigBytes)
+			return result, err
+		}
+		return result, err
+	}
+
+	// recover the pub key from the signature and data hash
+	pubKey, err := crypto.SigToPub(dataHash, ecdsaSig)
+	if err != nil {
+		// contract wallet sigs needn't be ECDSA sigs at all
+		if verifyOpts.contractCaller != nil {
+			result.Valid, err = verifyEIP1271(verifyOpts.ctx, verifyOpts.contractCaller, common.HexToAddress(expectedAddress), dataHash, sigBytes)
+			return result, err
+		}
+		return result, fmt.Errorf("failed to recover public key from signature: %v", err)
+	}
+
+	// extract the recovered addr
+	recoveredAddress := crypto.PubkeyToAddress(*pubKey).Hex()
+	result.RecoveredAddress = recoveredAddress
 
 	// compare the recovered address to the expected addr
 	//
 	// if they are equal, the signature is valid
-	return strings.EqualFold(recoveredAddress, expectedAddress), nil
+	result.Valid = strings.EqualFold(recoveredAddress, expectedAddress)
+
+	// otherwise the DID may be a contract wallet, which decides for itself
+	if !result.Valid && verifyOpts.contractCaller != nil {
+		result.Valid, err = verifyEIP1271(verifyOpts.ctx, verifyOpts.contractCaller, common.HexToAddress(expectedAddress), dataHash, sigBytes)
+		return result, err
+	}
+	return result, nil
+}
+
+// rebuilds the typed data a sig over the block covers (as verifyOpts describe it) and computes its EIP-712 hash
+func blockTypedDataHash(block blocks.Block, verifyOpts *verifyOptions) (TypedData, []byte, error) {
+	if verifyOpts.requireDagCBOR && block.Cid().Type() != cid.DagCBOR {
+		return TypedData{}, nil, fmt.Errorf("%w: got codec 0x%x", ErrNotDagCBOR, block.Cid().Type())
+	}
+	if verifyOpts.strictCBOR {
+		canonical, err := IsCanonicalCBOR(block)
+		if err != nil {
+			return TypedData{}, nil, fmt.Errorf("failed to check CBOR encoding: %w", err)
+		}
+		if !canonical {
+			return TypedData{}, nil, ErrNonCanonicalCBOR
+		}
+	}
+
+	// decode the block using CBOR into a generic type of map[string]interface (wrapping a top-level array)
+	decodedData, err := decodeBlockPayload(block.RawData())
+	if err != nil {
+		return TypedData{}, nil, err
+	}
+
+	// swap links for the content they point to, if the sig was made over the resolved data
+	if verifyOpts.linkFetcher != nil {
+		resolved, err := ResolveLinksWithBudget(
+			verifyOpts.ctx,
+			decodedData,
+			verifyOpts.linkFetcher,
+			verifyOpts.linkMaxDepth,
+			verifyOpts.linkMaxBlocks,
+			verifyOpts.linkMaxBytes,
+		)
+		if err != nil {
+			return TypedData{}, nil, fmt.Errorf("failed to resolve IPLD links: %w", err)
+		}
+		decodedData = resolved
+	}
+
+	// convert the sorted decoded data into EIP-712 typed data
+	payload, err := ConvertToEIP712TypedData(
+		verifyOpts.domainName,
+		decodedData,
+		verifyOpts.primaryType,
+		verifyOpts.floatHandler,
+		verifyOpts.convertOpts...,
+	)
+	if err != nil {
+		return TypedData{}, nil, fmt.Errorf("failed to convert block to EIP-712 typed data: %w", err)
+	}
+
+	// compute the EIP-712 hash
+	dataHash, err := computeEIP712Hash(payload.Data, payload.emptyDomain)
+	if err != nil {
+		return payload, nil, fmt.Errorf("failed to compute EIP-712 hash: %v", err)
+	}
+	return payload, dataHash, nil
+}
+
+// recovers the checksummed 0x address that made an EIP-712 sig over the block, for when the signer isn't known
+// up front
+//
+// the hash is rebuilt exactly as EthDID.Verify does (opts change it the same way). the sig may be 65 bytes,
+// with a v of 0/1 or 27/28 (or EIP-155 style when the domain has a chain ID), or 64 byte EIP-2098 compact form,
+// which packs the recovery id into s
+func RecoverSigner(block blocks.Block, sig string, opts ...VerifyOption) (string, error) {
+	verifyOpts := newVerifyOptions(opts)
+
+	_, dataHash, err := blockTypedDataHash(block, verifyOpts)
+	if err != nil {
+		return "", err
+	}
+
+	sigBytes, err := decodeSig(sig, verifyOpts.sigEncoding, verifyOpts.strictInputs)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode signature: %w", err)
+	}
+	sigBytes, err = recoverableSig(sigBytes, verifyOpts.chainID())
+	if err != nil {
+		return "", err
+	}
+
+	pubKey, err := crypto.SigToPub(dataHash, sigBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to recover public key from signature: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
+
+// recovers the signer like RecoverSigner, as an EthDID that can be printed or verified with straight away
+//
+// a recovered DID always verifies the same block and sig (under the same opts), so this only says who signed,
+// not whether they were meant to
+func DIDFromSignature(block blocks.Block, sig string, opts ...VerifyOption) (EthDID, error) {
+	address, err := RecoverSigner(block, sig, opts...)
+	if err != nil {
+		return "", err
+	}
+	return NewEthDID(address), nil
+}
+
+// a DID and the sig it's expected to have made, for VerifyBatch
+type EthDIDSig struct {
+	DID EthDID
+	Sig string
+}
+
+// verifies many sigs over the same block, building its typed data and EIP-712 hash only once
+//
+// the results line up with pairs. a sig that is malformed or made by someone else is just false there, so the
+// error is only for a block that can't be hashed at all. opts apply to every pair, as they would to Verify
+func VerifyBatch(block blocks.Block, pairs []EthDIDSig, opts ...VerifyOption) ([]bool, error) {
+	verifyOpts := newVerifyOptions(opts)
+
+	_, dataHash, err := blockTypedDataHash(block, verifyOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(pairs))
+	for i, pair := range pairs {
+		result, err := pair.DID.verifyHash(dataHash, pair.Sig, verifyOpts)
+		results[i] = err == nil && result.Valid
+	}
+	return results, nil
+}
+
+// verifies a sig only if it was made under the given domain name and primary type
+//
+// the typed data is always rebuilt with expectedPrimaryType, so a sig the signer was tricked into making under
+// some other schema hashes differently and fails. a nil floatHandler uses the default one
+func VerifyWithExpectedType(
+	block blocks.Block,
+	sig string,
+	did DID,
+	domain string,
+	expectedPrimaryType string,
+	floatHandler func(float64) (*big.Int, error),
+) (bool, error) {
+	ethDID, ok := did.(EthDID)
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrNotTypedDataDID, did)
+	}
+	if expectedPrimaryType == "" {
+		return false, fmt.Errorf("expected %w", ErrEmptyPrimaryType)
+	}
+
+	opts := []VerifyOption{WithDomainName(domain), WithPrimaryType(expectedPrimaryType)}
+	if floatHandler != nil {
+		opts = append(opts, WithConvertOptions(WithFloatHandler(floatHandler)))
+	}
+	return ethDID.VerifyWithOptions(block, sig, opts...)
 }
 
 // ===== EthProvider =====
 
 type EthProvider struct {
-	// todo: in the future, we may want to store some sort of priv key here (future-proofing)
+	privKey *ecdsa.PrivateKey
+
+	// conversion policy applied to every payload this provider signs
+	convertOpts []ConvertOption
+
+	// when set, payloads that don't list the provider in required_auths are refused
+	requireSelfInAuths bool
+}
+
+// creates a provider without a key, which can still build typed data but not sign
+func NewEthProvider(opts ...ConvertOption) *EthProvider {
+	return &EthProvider{convertOpts: opts}
 }
 
-func NewEthProvider() *EthProvider {
-	return &EthProvider{}
+// creates a provider that signs with the given key, applying opts to every conversion it does
+func NewEthProviderFromKey(privKey *ecdsa.PrivateKey, opts ...ConvertOption) *EthProvider {
+	return &EthProvider{privKey: privKey, convertOpts: opts}
 }
 
 // ===== implementing the Provider interface =====
 
+// signs the EIP-712 hash of the block's CBOR payload, as EthDID.Verify rebuilds it
+//
+// the sig is made over the payload as it decodes from the block (like SignMany), under this provider's
+// conversion policy, so it verifies with an EthDID for the key as long as the verifier uses the same policy
 func (e *EthProvider) Sign(block blocks.Block) (string, error) {
-	// todo: implement a way to sign the payload from the EthProvider
-	panic("unimplemented")
+	if e.privKey == nil {
+		return "", ErrNoPrivateKey
+	}
+
+	decodedData, err := decodeBlockPayload(block.RawData())
+	if err != nil {
+		return "", err
+	}
+
+	return e.SignData(decodedData)
 }
 
-// ===== utils =====
+// ===== other methods =====
 
-func computeEIP712Hash(typedData apitypes.TypedData) ([]byte, error) {
-	// add the EIP712Domain type to the types
-	typedData.Types["EIP712Domain"] = []apitypes.Type{
-		{
-			Name: "name",
-			Type: "string",
-		},
+// converts the payload into EIP-712 typed data using this provider's conversion policy
+func (e *EthProvider) TypedData(data interface{}) (TypedData, error) {
+	return ConvertToEIP712TypedData(vscDomainName, data, vscPrimaryType, defaultFloatHandler, e.convertOpts...)
+}
+
+// signs the EIP-712 hash of the payload, returning the hex sig in the same format EthDID.Verify expects
+func (e *EthProvider) SignData(data interface{}) (string, error) {
+	if e.privKey == nil {
+		return "", ErrNoPrivateKey
+	}
+
+	if err := e.checkSelfInAuths(data); err != nil {
+		return "", err
+	}
+
+	typedData, err := e.TypedData(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert data to EIP-712 typed data: %w", err)
+	}
+
+	return e.signTypedData(typedData)
+}
+
+// a payload signed by SignMany: the block it was encoded into and its sig, or why either failed
+type SignedResult struct {
+	Block blocks.Block
+	Sig   string
+	Err   error
+}
+
+// CBOR encodes and signs each payload, for signing lots of sub-items at once
+//
+// each sig is made over the payload as it decodes from its block, so every result verifies on its own with
+// EthDID.Verify. payloads fail individually via their result's Err; the error is only for the provider itself
+func (e *EthProvider) SignMany(payloads []any) ([]SignedResult, error) {
+	if e.privKey == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	// the conversion policy is the same for every payload, so it's only built once
+	opts := newConvertOptions(defaultFloatHandler, e.convertOpts)
+
+	results := make([]SignedResult, len(payloads))
+	for i, payload := range payloads {
+		block, err := EncodeBlock(payload)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Block = block
+
+		decodedData, err := decodeBlockPayload(block.RawData())
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if err := e.checkSelfInAuths(decodedData); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		typedData, err := convertToEIP712TypedData(vscDomainName, decodedData, vscPrimaryType, opts)
+		if err != nil {
+			results[i].Err = fmt.Errorf("failed to convert data to EIP-712 typed data: %w", err)
+			continue
+		}
+
+		results[i].Sig, results[i].Err = e.signTypedData(typedData)
 	}
+	return results, nil
+}
+
+// signs the EIP-712 hash of already converted typed data
+func (e *EthProvider) signTypedData(typedData TypedData) (string, error) {
+	dataHash, err := computeEIP712Hash(typedData.Data, typedData.emptyDomain)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute EIP-712 hash: %w", err)
+	}
+
+	sigBytes, err := crypto.Sign(dataHash, e.privKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign data: %w", err)
+	}
+
+	return hex.EncodeToString(sigBytes), nil
+}
 
+// ===== utils =====
+
+// standard (default) conversion of float to big int, truncating any fractional part
+func defaultFloatHandler(f float64) (*big.Int, error) {
+	return roundFloat(f, FloatRoundingTruncate)
+}
+
+func computeEIP712Hash(typedData apitypes.TypedData, emptyDomain bool) ([]byte, error) {
 	// hash the domain
-	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	domainSeparator, err := hashDomain(typedData.Domain, emptyDomain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash domain separator: %v", err)
+		return nil, fmt.Errorf("failed to hash domain separator: %w", err)
 	}
 
 	// hash the message
-	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	messageHash, err := hashStruct(typedData, typedData.PrimaryType, typedData.Message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash message: %v", err)
 	}
@@ -147,6 +683,72 @@ func computeEIP712Hash(typedData apitypes.TypedData) ([]byte, error) {
 	return finalHash, nil
 }
 
+// the EIP712Domain type fields matching what's set on the domain
+//
+// every field is optional, but the ones present are always in the order EIP-712 defines
+// (name, version, chainId, verifyingContract, salt) since the separator depends on it
+func domainTypes(domain apitypes.TypedDataDomain) []apitypes.Type {
+	domainFields := []apitypes.Type{}
+	if domain.Name != "" {
+		domainFields = append(domainFields, apitypes.Type{Name: "name", Type: "string"})
+	}
+	if domain.Version != "" {
+		domainFields = append(domainFields, apitypes.Type{Name: "version", Type: "string"})
+	}
+	if domain.ChainId != nil {
+		domainFields = append(domainFields, apitypes.Type{Name: "chainId", Type: "uint256"})
+	}
+	if domain.VerifyingContract != "" {
+		domainFields = append(domainFields, apitypes.Type{Name: "verifyingContract", Type: "address"})
+	}
+	if domain.Salt != "" {
+		domainFields = append(domainFields, apitypes.Type{Name: "salt", Type: "bytes32"})
+	}
+	return domainFields
+}
+
+// the JSON "domain" object, only carrying the fields that are set
+func domainJSON(domain apitypes.TypedDataDomain) map[string]interface{} {
+	domainMap := make(map[string]interface{})
+	if domain.Name != "" {
+		domainMap["name"] = domain.Name
+	}
+	if domain.Version != "" {
+		domainMap["version"] = domain.Version
+	}
+	if domain.ChainId != nil {
+		// serialized as a plain JSON number rather than go-ethereum's hex form
+		domainMap["chainId"] = (*big.Int)(domain.ChainId)
+	}
+	if domain.VerifyingContract != "" {
+		domainMap["verifyingContract"] = domain.VerifyingContract
+	}
+	if domain.Salt != "" {
+		domainMap["salt"] = domain.Salt
+	}
+	return domainMap
+}
+
+// hashes the domain into the domain separator, with the EIP712Domain type built from whichever fields are set
+//
+// a domain with no fields at all is an ErrEmptyDomain unless emptyDomain (WithEmptyDomain) says it's meant to be
+func hashDomain(domain apitypes.TypedDataDomain, emptyDomain bool) ([]byte, error) {
+	domainTypedData := apitypes.TypedData{
+		Types: apitypes.Types{"EIP712Domain": domainTypes(domain)},
+	}
+
+	// an empty domain uses just the typeHash of `EIP712Domain()` as its separator, matching the minimal verifiers
+	// we interop with, rather than hashStruct's keccak256(typeHash)
+	if len(domainTypedData.Types["EIP712Domain"]) == 0 {
+		if !emptyDomain {
+			return nil, fmt.Errorf("%w: the domain has no fields (see WithEmptyDomain)", ErrEmptyDomain)
+		}
+		return typeHash(domainTypedData.Types, "EIP712Domain"), nil
+	}
+
+	return hashStruct(domainTypedData, "EIP712Domain", domain.Map())
+}
+
 // decode CBOR back into a map[string]interface{}
 func decodeFromCBOR(data []byte, out interface{}) error {
 	var tempData map[string]interface{}
@@ -167,6 +769,9 @@ func decodeFromCBOR(data []byte, out interface{}) error {
 // struct wrapper for EIP-712 typed data
 type TypedData struct {
 	Data apitypes.TypedData
+
+	// set when converted WithEmptyDomain, the only way a domain with no fields gets hashed
+	emptyDomain bool
 }
 
 type EIP712DomainType struct {
@@ -190,69 +795,242 @@ func (d EIP712DomainType) MarshalJSON() ([]byte, error) {
 // marshals typed data into JSON, handling the domain field separately
 func (d TypedData) MarshalJSON() ([]byte, error) {
 	type Alias struct {
-		Types        apitypes.Types            `json:"types"`
-		PrimaryType  string                    `json:"primaryType"`
-		Domain       map[string]interface{}    `json:"domain"`
-		Message      apitypes.TypedDataMessage `json:"message"`
-		EIP712Domain EIP712DomainType          `json:"EIP712Domain"`
+		Types        apitypes.Types         `json:"types"`
+		PrimaryType  string                 `json:"primaryType"`
+		Domain       map[string]interface{} `json:"domain"`
+		Message      json.RawMessage        `json:"message"`
+		EIP712Domain []apitypes.Type        `json:"EIP712Domain"`
 	}
 
-	// serializes only the "name" field for the domain
-	domain := make(map[string]interface{})
-	if d.Data.Domain.Name != "" {
-		domain["name"] = d.Data.Domain.Name
+	// the message's fields are written in the order of their types, which OrderedMap payloads set
+	message, err := marshalMessageJSON(d.Data.Types, d.Data.PrimaryType, d.Data.Message)
+	if err != nil {
+		return nil, err
 	}
 
 	alias := Alias{
 		Types:       d.Data.Types,
 		PrimaryType: d.Data.PrimaryType,
-		Domain:      domain,
-		Message:     d.Data.Message,
+		Domain:      domainJSON(d.Data.Domain),
+		Message:     message,
 		// this allows us to serialize the EIP-712 domain field separately outside of the types field and instead in the main object
-		EIP712Domain: EIP712DomainType{
-			Name: "name",
-			Type: "string",
-		},
+		EIP712Domain: domainTypes(d.Data.Domain),
 	}
 
 	return json.Marshal(alias)
 }
 
+// marshals like MarshalJSON, but with every object's keys sorted at any depth (the message's too, even for
+// OrderedMap payloads), so the same typed data always gives byte-identical JSON for snapshots and diffs
+//
+// the types arrays keep their order, which is already deterministic and is part of what gets hashed. the
+// output parses back with UnmarshalJSON to typed data with the same EIP-712 hash
+func (d TypedData) MarshalJSONSorted() ([]byte, error) {
+	marshalled, err := d.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal typed data: %w", err)
+	}
+
+	// round trip through generic maps, which encoding/json always writes in sorted key order
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(marshalled))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to canonicalize typed data: %w", err)
+	}
+
+	return json.Marshal(generic)
+}
+
+// the MarshalJSONSorted JSON indented two spaces a level and ending in a newline, for committing as a golden file
+// that reviewers can read a schema or message change off of
+//
+// typed data that can't be marshalled gives a one line error note instead, which won't match any golden file
+func (d TypedData) GoldenString() string {
+	sorted, err := d.MarshalJSONSorted()
+	if err != nil {
+		return fmt.Sprintf("<invalid typed data: %v>\n", err)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, sorted, "", "  "); err != nil {
+		return fmt.Sprintf("<invalid typed data: %v>\n", err)
+	}
+	indented.WriteByte('\n')
+	return indented.String()
+}
+
+// parses typed data back from the JSON MarshalJSON produces
+//
+// numbers are kept as exact json.Number values rather than float64, so large values hash the same as before. an
+// empty domain is read back as is, but won't hash (see ComputeEIP712Hash)
+func (d *TypedData) UnmarshalJSON(data []byte) error {
+	var alias struct {
+		Types       apitypes.Types            `json:"types"`
+		PrimaryType string                    `json:"primaryType"`
+		Domain      map[string]interface{}    `json:"domain"`
+		Message     apitypes.TypedDataMessage `json:"message"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&alias); err != nil {
+		return err
+	}
+
+	// the EIP712Domain type is implied by which domain fields are set, so it isn't read back
+	domain := apitypes.TypedDataDomain{}
+	for field, value := range alias.Domain {
+		switch field {
+		case "name":
+			domain.Name, _ = value.(string)
+		case "version":
+			domain.Version, _ = value.(string)
+		case "verifyingContract":
+			domain.VerifyingContract, _ = value.(string)
+		case "salt":
+			domain.Salt, _ = value.(string)
+		case "chainId":
+			chainID, ok := new(big.Int).SetString(fmt.Sprint(value), 10)
+			if !ok {
+				return fmt.Errorf("invalid domain chainId %v", value)
+			}
+			domain.ChainId = (*math.HexOrDecimal256)(chainID)
+		default:
+			return fmt.Errorf("unknown domain field %q", field)
+		}
+	}
+
+	d.Data = apitypes.TypedData{
+		Types:       alias.Types,
+		PrimaryType: alias.PrimaryType,
+		Domain:      domain,
+		Message:     alias.Message,
+	}
+	return nil
+}
+
+// converts a payload into EIP-712 typed data under the domain and primary type
+//
+// floatHandler converts floats with a fractional part, which whole ones skip (see WithFloatHandler), and a nil
+// one rejects floats altogether
 func ConvertToEIP712TypedData(
 	domainName string,
 	data interface{},
 	primaryTypeName string,
 	floatHandler func(float64) (*big.Int, error),
+	opts ...ConvertOption,
 ) (TypedData, error) {
 
-	if domainName == "" || primaryTypeName == "" {
-		return TypedData{}, fmt.Errorf("domain name or primary type name cannot be empty")
+	convertOpts := newConvertOptions(floatHandler, opts)
+	if err := validateDomainAndPrimaryType(domainName, primaryTypeName, convertOpts); err != nil {
+		return TypedData{}, err
 	}
 
+	return convertToEIP712TypedData(domainName, data, primaryTypeName, convertOpts)
+}
+
+// converts many payloads sharing the same domain and primary type, validating those once up front
+//
+// the returned slices are index-aligned with payloads; a failed payload gets a zero TypedData and its error
+func ConvertBatch(
+	domainName string,
+	primaryTypeName string,
+	payloads []interface{},
+	floatHandler func(float64) (*big.Int, error),
+	opts ...ConvertOption,
+) ([]TypedData, []error) {
+	results := make([]TypedData, len(payloads))
+	errs := make([]error, len(payloads))
+
+	// if the shared domain is bad, every payload is bad
+	convertOpts := newConvertOptions(floatHandler, opts)
+	if err := validateDomainAndPrimaryType(domainName, primaryTypeName, convertOpts); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	for i, payload := range payloads {
+		results[i], errs[i] = convertToEIP712TypedData(domainName, payload, primaryTypeName, convertOpts)
+	}
+
+	return results, errs
+}
+
+func validateDomainAndPrimaryType(domainName string, primaryTypeName string, opts *convertOptions) error {
+	// the name can only be left out when the domain is explicitly empty or has some other field to carry it
+	if primaryTypeName == "" {
+		return ErrEmptyPrimaryType
+	}
+	if domainName == "" && !opts.emptyDomain && !opts.hasDomainFields() {
+		return ErrEmptyDomain
+	}
+	return opts.validateDomainFields()
+}
+
+// does the actual conversion, assuming the domain and primary type were already validated
+func convertToEIP712TypedData(
+	domainName string,
+	data interface{},
+	primaryTypeName string,
+	opts *convertOptions,
+) (TypedData, error) {
 	// try to assert data as map[string]interface{} first
 	dataMap, ok := data.(map[string]interface{})
+	var fieldTypes map[string]string
+	var fieldOrder []string
+
+	// ordered maps keep their key order as the field order
+	if ordered, isOrdered := asOrderedMap(data); isOrdered {
+		dataMap, fieldOrder, ok = ordered.values, ordered.keys, true
+	}
+
+	// typed maps (e.g. map[string]uint64) keep their values as is, rather than as whatever JSON turns them into
+	if !ok {
+		dataMap, ok = stringKeyedMap(data)
+	}
+
+	// EIP-712 needs a struct root, so a top-level array gets wrapped into one (see TopLevelArrayField)
 	if !ok {
+		dataMap, ok = wrapTopLevelArray(data)
+	}
+	if structVal := reflect.Indirect(reflect.ValueOf(data)); !ok && structVal.Kind() == reflect.Struct {
+		// structs are walked directly so their eip712 tags can pin field names and types
+		var err error
+		dataMap, fieldTypes, err = structToMap(structVal)
+		if err != nil {
+			return TypedData{}, fmt.Errorf("failed to convert struct: %w", err)
+		}
+	} else if !ok {
 		// if not ok, try to marshal and then unmarshal the data into a map
 		jsonBytes, err := json.Marshal(data)
 		if err != nil {
 			return TypedData{}, fmt.Errorf("failed to marshal struct: %v", err)
 		}
 
-		err = json.Unmarshal(jsonBytes, &dataMap)
+		decoded, err := decodeJSONExact(jsonBytes)
 		if err != nil {
 			return TypedData{}, fmt.Errorf("failed to unmarshal into map: %v", err)
 		}
+		// a JSON null is an empty payload, as it always was
+		if dataMap, ok = decoded.(map[string]interface{}); !ok && decoded != nil {
+			return TypedData{}, fmt.Errorf("failed to unmarshal into map: %T payload isn't a JSON object", data)
+		}
 	}
 
 	// gen the msg and types
-	message, types, err := generateTypedDataWithPath(dataMap, primaryTypeName, floatHandler)
+	message, types, err := generateTypedDataWithPath(dataMap, primaryTypeName, "", opts, fieldTypes, fieldOrder)
 	if err != nil {
-		return TypedData{}, fmt.Errorf("failed to generate typed data: %v", err)
+		return TypedData{}, fmt.Errorf("failed to generate typed data: %w", err)
 	}
 
 	// populate the typed data struct
-	typedData := TypedData{}
-	typedData.Data.Domain = apitypes.TypedDataDomain{Name: domainName}
+	typedData := TypedData{emptyDomain: opts.emptyDomain}
+	if !opts.emptyDomain {
+		typedData.Data.Domain = opts.domain(domainName)
+	}
 	typedData.Data.PrimaryType = primaryTypeName
 	typedData.Data.Message = message
 	typedData.Data.Types = types
@@ -260,6 +1038,32 @@ func ConvertToEIP712TypedData(
 	return typedData, nil
 }
 
+// decodes a json.RawMessage into the same generic form a JSON round trip of the payload would give
+func decodeRawMessage(raw json.RawMessage) (interface{}, error) {
+	return decodeJSONExact(raw)
+}
+
+// the String() form of a fmt.Stringer (that isn't a nil pointer), or the value untouched
+func stringerValue(value interface{}) interface{} {
+	stringer, ok := value.(fmt.Stringer)
+	if !ok {
+		return value
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return value
+	}
+	return stringer.String()
+}
+
+// the EIP-55 checksummed form of a 0x (or 0X) prefixed address in any casing
+func checksumAddress(address string) (string, error) {
+	hasPrefix := strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X")
+	if !hasPrefix || !common.IsHexAddress(address) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	return common.HexToAddress(address).Hex(), nil
+}
+
 // is the string an Ethereum addr?
 func isEthAddr(s string) bool {
 	if len(s) != 42 || !strings.HasPrefix(s, "0x") {
@@ -273,143 +1077,239 @@ func isEthAddr(s string) bool {
 func generateTypedDataWithPath(
 	data map[string]interface{},
 	typeName string,
-	floatHandler func(float64) (*big.Int, error),
+	path string,
+	opts *convertOptions,
+	fieldTypes map[string]string,
+	fieldOrder []string,
 ) (map[string]interface{}, map[string][]apitypes.Type, error) {
-
-	message := make(map[string]interface{})
 	types := make(map[string][]apitypes.Type)
-	types[typeName] = []apitypes.Type{}
+	message, err := collectTypedData(types, data, typeName, path, opts, fieldTypes, fieldOrder, &convertWalk{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, types, nil
+}
+
+// how far into the payload a conversion is, checked against the limits so a hostile payload can't run away with it
+type convertWalk struct {
+	depth  int
+	fields int
+}
 
-	// collects and sorts field names
-	var fieldNames []string
-	for fieldName := range data {
-		fieldNames = append(fieldNames, fieldName)
+// steps into a nested struct or array at fieldPath, which leave steps back out of
+func (w *convertWalk) enter(fieldPath string, opts *convertOptions) error {
+	w.depth++
+	if maxDepth, _, _ := opts.limits(); w.depth > maxDepth {
+		return fmt.Errorf("%w: field '%s' nests deeper than %d levels", ErrConvertLimit, fieldPath, maxDepth)
+	}
+	return nil
+}
+
+func (w *convertWalk) leave() {
+	w.depth--
+}
+
+// counts a struct level's fields toward the total
+func (w *convertWalk) addFields(n int, fieldPath string, opts *convertOptions) error {
+	w.fields += n
+	if _, maxFields, _ := opts.limits(); w.fields > maxFields {
+		return fmt.Errorf("%w: more than %d fields in total, reached at field '%s'", ErrConvertLimit, maxFields, fieldPath)
+	}
+	return nil
+}
+
+// converts one struct level of the payload, adding its type (and those of any nested structs) to types
+//
+// nested structs share the one types map rather than each building their own to be merged in, which adds up
+// for payloads with many nested maps
+func collectTypedData(
+	types map[string][]apitypes.Type,
+	data map[string]interface{},
+	typeName string,
+	path string,
+	opts *convertOptions,
+	fieldTypes map[string]string,
+	fieldOrder []string,
+	walk *convertWalk,
+) (map[string]interface{}, error) {
+	// limits are checked before any of the level is converted
+	if err := walk.enter(path, opts); err != nil {
+		return nil, err
+	}
+	defer walk.leave()
+	if err := walk.addFields(len(data), path, opts); err != nil {
+		return nil, err
+	}
+
+	message := make(map[string]interface{}, len(data))
+	typeFields := make([]apitypes.Type, 0, len(data))
+
+	// collects and sorts field names, unless the payload gave an explicit order
+	fieldNames := fieldOrder
+	if fieldNames == nil {
+		fieldNames = make([]string, 0, len(data))
+		for fieldName := range data {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		sort.Strings(fieldNames)
 	}
-	sort.Strings(fieldNames)
 
 	for _, fieldName := range fieldNames {
 		fieldValue := data[fieldName]
+		fieldPath := joinFieldPath(path, fieldName)
+
+		// pre-serialized JSON fragments are converted as whatever they decode to, rather than as bytes
+		if raw, ok := fieldValue.(json.RawMessage); ok {
+			decoded, err := decodeRawMessage(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid json.RawMessage for field '%s': %v", fieldPath, err)
+			}
+			fieldValue = decoded
+		}
+
+		// pointers convert as what they point at, and nil ones per the nil policy
+		fieldValue = derefValue(fieldValue, opts.stringers)
+		if isNilValue(fieldValue) {
+			switch opts.nilPolicy {
+			case NilPolicyOmit:
+				continue
+			case NilPolicyZero:
+				if fieldValue == nil {
+					return nil, fmt.Errorf("%w for field '%s' has no type to take a zero value of", ErrNilValue, fieldPath)
+				}
+				fieldValue = reflect.Zero(reflect.TypeOf(fieldValue).Elem()).Interface()
+			default:
+				return nil, fmt.Errorf("%w for field '%s'", ErrNilValue, fieldPath)
+			}
+		}
+
+		// (fields with their own float handler keep their floats for it)
+		if _, ownHandler := opts.fieldFloatHandlers[fieldPath]; opts.wholeFloatsAsInts && !ownHandler {
+			fieldValue = normalizeWholeFloats(fieldValue)
+		}
+
+		// time.Time and big number values, before they're mistaken for structs (or Stringers)
+		normalized, err := normalizeCommonValue(fieldValue, opts.timeFormat)
+		if err != nil {
+			return nil, fmt.Errorf("value of field '%s': %w", fieldPath, err)
+		}
+		fieldValue = normalized
+
+		if opts.stringers {
+			fieldValue = stringerValue(fieldValue)
+		}
+
+		// commitments to hidden values sign as their hash
+		if commitment, ok := asCommitment(fieldValue); ok {
+			if len(commitment.Hash) != 32 {
+				return nil, fmt.Errorf("commitment for field '%s' must be 32 bytes, got %d", fieldPath, len(commitment.Hash))
+			}
+			message[fieldName] = commitment.Hash
+			typeFields = append(typeFields, apitypes.Type{Name: fieldName, Type: "bytes32"})
+			continue
+		}
+
+		// already converted sub-structs are spliced in as-is, trusting their declared type
+		if preEncoded, ok := asPreEncodedStruct(fieldValue); ok {
+			preEncodedTypes, err := preEncoded.types()
+			if err != nil {
+				return nil, fmt.Errorf("invalid pre-encoded struct for field '%s': %v", fieldPath, err)
+			}
+			for k, v := range preEncodedTypes {
+				types[k] = v
+			}
+			message[fieldName] = preEncoded.Message
+			typeFields = append(typeFields, apitypes.Type{Name: fieldName, Type: preEncoded.TypeName})
+			continue
+		}
+
 		fieldKind := reflect.ValueOf(fieldValue).Kind()
 		var fieldType string
 
+		// ordered maps convert like maps, in their own key order
+		ordered, isOrdered := asOrderedMap(fieldValue)
+		if isOrdered {
+			fieldKind = reflect.Map
+		}
+
 		switch fieldKind {
 		case reflect.Slice, reflect.Array:
-			// checks if the array | slice is empty
-			arrayVal := reflect.ValueOf(fieldValue)
-			if arrayVal.Len() == 0 {
-				fieldType = "undefined[]" // allow undefined for empty arrays, as per the JS version in the Bitcoin wrapper UI
-				message[fieldName] = fieldValue
-			} else {
-				// check the first elem to infer the inner type of the slice/array
-				firstElem := arrayVal.Index(0).Interface()
-				elemKind := reflect.TypeOf(firstElem).Kind()
-
-				switch elemKind {
-				case reflect.String:
-					fieldType = "string[]"
-					message[fieldName] = fieldValue
-
-				case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-					fieldType = "uint256[]"
-					uintArrayValues := make([]*big.Int, arrayVal.Len())
-					for i := 0; i < arrayVal.Len(); i++ {
-						uintVal := arrayVal.Index(i).Interface()
-						var u64 uint64
-						switch v := uintVal.(type) {
-						case uint, uint8, uint16, uint32, uint64:
-							u64 = v.(uint64)
-						default:
-							return nil, nil, fmt.Errorf("unsupported uint type in array for field %s", fieldName)
-						}
-						uintArrayValues[i] = new(big.Int).SetUint64(u64)
-					}
-					message[fieldName] = uintArrayValues
-
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					fieldType = "int256[]"
-					intArrayValues := make([]*big.Int, arrayVal.Len())
-					for i := 0; i < arrayVal.Len(); i++ {
-						intVal := arrayVal.Index(i).Interface()
-						var i64 int64
-						switch v := intVal.(type) {
-						case int, int8, int16, int32, int64:
-							i64 = reflect.ValueOf(v).Int()
-						default:
-							return nil, nil, fmt.Errorf("unsupported int type in array for field %s", fieldName)
-						}
-						intArrayValues[i] = big.NewInt(i64)
-					}
-					message[fieldName] = intArrayValues
-
-				case reflect.Float64:
-					fieldType = "uint256[]"
-					bigIntArray := make([]*big.Int, arrayVal.Len())
-					for i := 0; i < arrayVal.Len(); i++ {
-						floatVal := arrayVal.Index(i).Interface()
-						if f64, ok := floatVal.(float64); ok {
-							bigInt, err := floatHandler(f64)
-							if err != nil {
-								return nil, nil, fmt.Errorf("failed to handle float array value: %v", err)
-							}
-							bigIntArray[i] = bigInt
-						} else {
-							return nil, nil, fmt.Errorf("invalid float value in array")
-						}
-					}
-					message[fieldName] = bigIntArray
-
-				case reflect.Uint8:
-					// treat []uint8 as bytes
-					fieldType = "bytes"
-					message[fieldName] = fieldValue
-
-				default:
-					// fallback/default for unrecognized slice types
-					fieldType = fmt.Sprintf("%s[]", elemKind.String())
-					message[fieldName] = fieldValue
-				}
+			var err error
+			fieldType, message[fieldName], err = generateArrayType(reflect.ValueOf(fieldValue), fieldPath, opts, walk)
+			if err != nil {
+				return nil, err
 			}
 
-		case reflect.Map:
-			// nested maps gen new type names and processes recursively
+		case reflect.Map, reflect.Struct:
+			// nested maps (and structs) gen new type names and processes recursively
 			nestedTypeName := fmt.Sprintf("%s.%s", typeName, fieldName)
-			nestedData, ok := fieldValue.(map[string]interface{})
-			if !ok {
-				return nil, nil, fmt.Errorf("expected map[string]interface{} for field '%s'", fieldName)
+			var nestedData map[string]interface{}
+			var nestedFieldTypes map[string]string
+			var nestedFieldOrder []string
+			if isOrdered {
+				nestedData, nestedFieldOrder = ordered.values, ordered.keys
+			} else if fieldKind == reflect.Struct {
+				var err error
+				nestedData, nestedFieldTypes, err = structToMap(reflect.ValueOf(fieldValue))
+				if err != nil {
+					return nil, fmt.Errorf("failed to convert struct for field '%s': %w", fieldPath, err)
+				}
+			} else {
+				var ok bool
+				nestedData, ok = stringKeyedMap(fieldValue)
+				if !ok {
+					return nil, fmt.Errorf("expected a map with string keys for field '%s', got %T", fieldPath, fieldValue)
+				}
 			}
-			nestedMessage, nestedTypes, err := generateTypedDataWithPath(nestedData, nestedTypeName, floatHandler)
+			nestedMessage, err := collectTypedData(types, nestedData, nestedTypeName, fieldPath, opts, nestedFieldTypes, nestedFieldOrder, walk)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to generate typed data for nested map: %v", err)
+				// the error already names the nested field's full path
+				return nil, err
 			}
 			fieldType = nestedTypeName
 			message[fieldName] = nestedMessage
-			for k, v := range nestedTypes {
-				types[k] = v
-			}
 
 		case reflect.String:
 			// handle eth addr or regular strings
 			//
 			// if the string is an Ethereum address, use the "address" type
-			if isEthAddr(fieldValue.(string)) {
+			if !opts.disableAddressCoercion && isEthAddr(fieldValue.(string)) {
 				fieldType = "address"
+				message[fieldName] = opts.formatAddress(fieldValue.(string))
 			} else {
 				fieldType = "string"
+				message[fieldName] = fieldValue
 			}
-			message[fieldName] = fieldValue
 
 		case reflect.Float64:
-			// use the float handler for all float values
+			// whole floats are the integer they equal, and only fractional ones go through the float handler (unless
+			// the field has a handler of its own)
 			if floatValue, ok := fieldValue.(float64); ok {
-				bigIntValue, err := floatHandler(floatValue)
+				bigIntValue, err := opts.floatToBigInt(floatValue, fieldPath)
+				if err != nil {
+					return nil, fmt.Errorf("%w of field '%s': %w", ErrFloatHandler, fieldPath, err)
+				}
+				fieldType, err = bigIntType(bigIntValue)
 				if err != nil {
-					return nil, nil, fmt.Errorf("failed to handle float value: %v", err)
+					return nil, fmt.Errorf("float value of field '%s': %w", fieldPath, err)
 				}
-				message[fieldName] = bigIntValue
-				fieldType = "uint256"
+				message[fieldName] = opts.formatNumber(bigIntValue)
 			} else {
-				return nil, nil, fmt.Errorf("expected float64 for field '%s'", fieldName)
+				return nil, fmt.Errorf("expected float64 for field '%s'", fieldPath)
+			}
+
+		case reflect.Ptr:
+			// big ints are typed by sign, like the float handler's output
+			bigIntValue, ok := fieldValue.(*big.Int)
+			if !ok || bigIntValue == nil {
+				return nil, &UnsupportedTypeError{FieldPath: fieldPath, Kind: fieldKind}
+			}
+			var err error
+			fieldType, err = bigIntType(bigIntValue)
+			if err != nil {
+				return nil, fmt.Errorf("value of field '%s': %w", fieldPath, err)
 			}
+			message[fieldName] = opts.formatNumber(new(big.Int).Set(bigIntValue))
 
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			// convert all integers to big int using type assertions
@@ -418,9 +1318,9 @@ func generateTypedDataWithPath(
 			case int, int8, int16, int32, int64:
 				i64 = reflect.ValueOf(v).Int()
 			default:
-				return nil, nil, fmt.Errorf("unsupported integer type for field '%s'", fieldName)
+				return nil, fmt.Errorf("unsupported integer type for field '%s'", fieldPath)
 			}
-			message[fieldName] = big.NewInt(i64)
+			message[fieldName] = opts.formatNumber(big.NewInt(i64))
 			fieldType = "int256"
 
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
@@ -428,11 +1328,11 @@ func generateTypedDataWithPath(
 			var u64 uint64
 			switch v := fieldValue.(type) {
 			case uint, uint8, uint16, uint32, uint64:
-				u64 = v.(uint64)
+				u64 = reflect.ValueOf(v).Uint()
 			default:
-				return nil, nil, fmt.Errorf("unsupported unsigned integer type for field '%s'", fieldName)
+				return nil, fmt.Errorf("unsupported unsigned integer type for field '%s'", fieldPath)
 			}
-			message[fieldName] = new(big.Int).SetUint64(u64)
+			message[fieldName] = opts.formatNumber(new(big.Int).SetUint64(u64))
 			fieldType = "uint256"
 
 		case reflect.Bool:
@@ -440,19 +1340,303 @@ func generateTypedDataWithPath(
 			message[fieldName] = fieldValue
 
 		default:
-			return nil, nil, fmt.Errorf("unsupported field type %s for field %s", fieldKind.String(), fieldName)
+			return nil, &UnsupportedTypeError{FieldPath: fieldPath, Kind: fieldKind}
+		}
+
+		// types pinned by struct tags win over whatever we inferred
+		if tagged, ok := fieldTypes[fieldName]; ok {
+			fieldType = tagged
+		}
+
+		// and explicit per-path types win over everything
+		if override, ok := opts.typeOverrides[fieldPath]; ok {
+			fieldType = override
+		}
+
+		// a pinned integer type may be narrower than what was inferred, so it has to actually hold the value
+		if _, pinned := fieldTypes[fieldName]; pinned || opts.typeOverrides[fieldPath] != "" {
+			if err := checkIntegerFits(fieldType, message[fieldName]); err != nil {
+				return nil, fmt.Errorf("value of field '%s' doesn't fit its type: %w", fieldPath, err)
+			}
 		}
 
 		// append field and its type to the types array
-		types[typeName] = append(types[typeName], apitypes.Type{Name: fieldName, Type: fieldType})
+		typeFields = append(typeFields, apitypes.Type{Name: fieldName, Type: fieldType})
 	}
 
-	// sort the `types[typeName]` slice to ensure consistent ordering
+	// sort the type's fields to ensure consistent ordering
 	//
-	// this is primarily for deterministic EIP-712 hash generation, else, tests "sometimes" pass
-	sort.Slice(types[typeName], func(i, j int) bool {
-		return types[typeName][i].Name < types[typeName][j].Name
-	})
+	// this is primarily for deterministic EIP-712 hash generation, else, tests "sometimes" pass. an explicit
+	// field order is already deterministic, and is the point of giving one
+	if fieldOrder == nil {
+		sort.Slice(typeFields, func(i, j int) bool {
+			return typeFields[i].Name < typeFields[j].Name
+		})
+	}
+	types[typeName] = typeFields
 
-	return message, types, nil
+	return message, nil
+}
+
+// infers the EIP-712 type of a slice/array field from its elements, returning it along with the converted value
+//
+// nested slices recurse, so `[][]int` becomes `int256[][]`. fieldPath is the dotted path of the array, with the
+// index appended for inner arrays (e.g. `tx.ops[1]`), so errors point at the offending value
+func generateArrayType(arrayVal reflect.Value, fieldPath string, opts *convertOptions, walk *convertWalk) (string, interface{}, error) {
+	if err := walk.enter(fieldPath, opts); err != nil {
+		return "", nil, err
+	}
+	defer walk.leave()
+	if _, _, maxSliceLength := opts.limits(); arrayVal.Type().Elem().Kind() != reflect.Uint8 && arrayVal.Len() > maxSliceLength {
+		return "", nil, fmt.Errorf("%w: array of field %s has %d elements, more than %d", ErrConvertLimit, fieldPath, arrayVal.Len(), maxSliceLength)
+	}
+
+	// fixed size byte arrays ([N]byte) are EIP-712's bytes1 to bytes32, carried as 0x hex
+	if arrayVal.Kind() == reflect.Array && arrayVal.Type().Elem().Kind() == reflect.Uint8 {
+		size := arrayVal.Len()
+		if size < 1 || size > 32 {
+			return "", nil, fmt.Errorf("byte array of field %s is %d bytes, but fixed bytes types only go from bytes1 to bytes32", fieldPath, size)
+		}
+		fixed := make([]byte, size)
+		reflect.Copy(reflect.ValueOf(fixed), arrayVal)
+		return fmt.Sprintf("bytes%d", size), "0x" + hex.EncodeToString(fixed), nil
+	}
+
+	// checks if the array | slice is empty
+	if arrayVal.Len() == 0 {
+		return "undefined[]", arrayVal.Interface(), nil // allow undefined for empty arrays, as per the JS version in the Bitcoin wrapper UI
+	}
+
+	// nulls have no type to infer anything from, so they go by the nil policy first
+	arrayVal, err := applyNilPolicyToArray(arrayVal, fieldPath, opts.nilPolicy)
+	if err != nil {
+		return "", nil, err
+	}
+	if arrayVal.Len() == 0 {
+		return "undefined[]", []interface{}{}, nil
+	}
+
+	// check the first elem to infer the inner type of the slice/array
+	firstElem := arrayVal.Index(0).Interface()
+	elemKind := reflect.TypeOf(firstElem).Kind()
+
+	switch elemKind {
+	case reflect.String:
+		return "string[]", arrayVal.Interface(), nil
+
+	case reflect.Bool:
+		boolValues := make([]bool, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			b, ok := arrayVal.Index(i).Interface().(bool)
+			if !ok {
+				return "", nil, fmt.Errorf("mixed bool and non-bool elements in array for field %s", fieldPath)
+			}
+			boolValues[i] = b
+		}
+		return "bool[]", boolValues, nil
+
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintArrayValues := make([]*big.Int, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			uintVal := arrayVal.Index(i).Interface()
+			var u64 uint64
+			switch v := uintVal.(type) {
+			case uint, uint8, uint16, uint32, uint64:
+				u64 = reflect.ValueOf(v).Uint()
+			default:
+				return "", nil, fmt.Errorf("unsupported uint type in array for field %s", fieldPath)
+			}
+			uintArrayValues[i] = new(big.Int).SetUint64(u64)
+		}
+		return "uint256[]", opts.formatNumbers(uintArrayValues), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intArrayValues := make([]*big.Int, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			intVal := arrayVal.Index(i).Interface()
+			var i64 int64
+			switch v := intVal.(type) {
+			case int, int8, int16, int32, int64:
+				i64 = reflect.ValueOf(v).Int()
+			default:
+				return "", nil, fmt.Errorf("unsupported int type in array for field %s", fieldPath)
+			}
+			intArrayValues[i] = big.NewInt(i64)
+		}
+		return "int256[]", opts.formatNumbers(intArrayValues), nil
+
+	case reflect.Float64:
+		bigIntArray := make([]*big.Int, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			floatVal := arrayVal.Index(i).Interface()
+			if f64, ok := floatVal.(float64); ok {
+				bigInt, err := opts.floatToBigInt(f64, fieldPath)
+				if err != nil {
+					return "", nil, fmt.Errorf("%w in array of field %s: %w", ErrFloatHandler, fieldPath, err)
+				}
+				bigIntArray[i] = bigInt
+			} else {
+				return "", nil, fmt.Errorf("invalid float value in array")
+			}
+		}
+		arrayType, err := bigIntsType(bigIntArray)
+		if err != nil {
+			return "", nil, fmt.Errorf("float array value of field %s: %w", fieldPath, err)
+		}
+		return arrayType + "[]", opts.formatNumbers(bigIntArray), nil
+
+	case reflect.Ptr:
+		bigIntArray := make([]*big.Int, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			bigInt, ok := arrayVal.Index(i).Interface().(*big.Int)
+			if !ok || bigInt == nil {
+				return "", nil, &UnsupportedTypeError{FieldPath: fmt.Sprintf("%s[%d]", fieldPath, i), Kind: reflect.ValueOf(arrayVal.Index(i).Interface()).Kind()}
+			}
+			bigIntArray[i] = new(big.Int).Set(bigInt)
+		}
+		arrayType, err := bigIntsType(bigIntArray)
+		if err != nil {
+			return "", nil, fmt.Errorf("array value of field %s: %w", fieldPath, err)
+		}
+		return arrayType + "[]", opts.formatNumbers(bigIntArray), nil
+
+	case reflect.Uint8:
+		// treat []uint8 as bytes
+		return "bytes", arrayVal.Interface(), nil
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return "", nil, &UnsupportedTypeError{FieldPath: fieldPath + "[0]", Kind: elemKind}
+
+	case reflect.Slice, reflect.Array:
+		// every inner array must agree on its type, though empty ones go along with whatever the others are
+		innerType := ""
+		innerValues := make([]interface{}, arrayVal.Len())
+		for i := 0; i < arrayVal.Len(); i++ {
+			elem := reflect.ValueOf(arrayVal.Index(i).Interface())
+			if elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array {
+				return "", nil, fmt.Errorf("mixed array and non-array elements in array for field %s", fieldPath)
+			}
+			elemType, elemValue, err := generateArrayType(elem, fmt.Sprintf("%s[%d]", fieldPath, i), opts, walk)
+			if err != nil {
+				return "", nil, err
+			}
+			innerValues[i] = elemValue
+
+			switch {
+			case innerType == "" || innerType == "undefined[]":
+				innerType = elemType
+			case elemType != "undefined[]" && elemType != innerType:
+				return "", nil, fmt.Errorf("inconsistent element types %s and %s in nested array for field %s", innerType, elemType, fieldPath)
+			}
+		}
+		return innerType + "[]", innerValues, nil
+	}
+
+	// fallback/default for unrecognized slice types
+	return fmt.Sprintf("%s[]", elemKind.String()), arrayVal.Interface(), nil
+}
+
+// the type a big int value is inferred as: uint256, or int256 when negative so it doesn't wrap around
+func bigIntType(n *big.Int) (string, error) {
+	if n.Sign() < 0 {
+		// -2^255 is the smallest int256
+		if n.BitLen() > 255 && new(big.Int).Neg(n).Cmp(new(big.Int).Lsh(big.NewInt(1), 255)) != 0 {
+			return "", fmt.Errorf("%s is below the int256 range", n)
+		}
+		return "int256", nil
+	}
+	if n.Cmp(math.MaxBig256) > 0 {
+		return "", fmt.Errorf("%s is above the uint256 range", n)
+	}
+	return "uint256", nil
+}
+
+// the shared element type of big int array values: int256 if any is negative (and all fit it), otherwise uint256
+func bigIntsType(ns []*big.Int) (string, error) {
+	arrayType := "uint256"
+	for _, n := range ns {
+		typ, err := bigIntType(n)
+		if err != nil {
+			return "", err
+		}
+		if typ == "int256" {
+			arrayType = typ
+		}
+	}
+	if arrayType == "int256" {
+		for _, n := range ns {
+			if n.Sign() > 0 && n.BitLen() > 255 {
+				return "", fmt.Errorf("%s is above the int256 range of the array's negative values", n)
+			}
+		}
+	}
+	return arrayType, nil
+}
+
+// checks that an integer message value (or each one, for arrays) is in range of a sized (u)intN type
+//
+// non-integer types, and values that aren't converted integers, are left for the encoder to judge
+func checkIntegerFits(fieldType string, value interface{}) error {
+	if strings.HasSuffix(fieldType, "]") {
+		elemType := fieldType[:strings.LastIndex(fieldType, "[")]
+		arrayVal := reflect.ValueOf(value)
+		if arrayVal.Kind() != reflect.Slice && arrayVal.Kind() != reflect.Array {
+			return nil
+		}
+		for i := 0; i < arrayVal.Len(); i++ {
+			if err := checkIntegerFits(elemType, arrayVal.Index(i).Interface()); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	signed := strings.HasPrefix(fieldType, "int")
+	if !signed && !strings.HasPrefix(fieldType, "uint") {
+		return nil
+	}
+	bits := 256
+	if width := strings.TrimPrefix(strings.TrimPrefix(fieldType, "u"), "int"); width != "" {
+		var err error
+		if bits, err = strconv.Atoi(width); err != nil || bits <= 0 || bits > 256 || bits%8 != 0 {
+			return fmt.Errorf("invalid integer type %s", fieldType)
+		}
+	}
+
+	var n *big.Int
+	switch v := value.(type) {
+	case *big.Int:
+		n = v
+	case string:
+		// as NumberFormatHex renders them
+		negative := strings.HasPrefix(v, "-")
+		parsed, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok {
+			return nil
+		}
+		if negative {
+			parsed.Neg(parsed)
+		}
+		n = parsed
+	default:
+		return nil
+	}
+
+	lower, upper := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if signed {
+		upper.Rsh(upper, 1)
+		lower.Neg(upper)
+	}
+	if n.Cmp(lower) < 0 || n.Cmp(upper) >= 0 {
+		return fmt.Errorf("%s is out of range for %s", n, fieldType)
+	}
+	return nil
+}
+
+// joins a parent dotted field path with a child field name
+func joinFieldPath(path string, fieldName string) string {
+	if path == "" {
+		return fieldName
+	}
+	return path + "." + fieldName
 }
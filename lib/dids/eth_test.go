@@ -1,14 +1,22 @@
 package dids_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 	"vsc-node/lib/dids"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	blocks "github.com/ipfs/go-block-format"
@@ -44,18 +52,19 @@ func TestEthDIDVerify(t *testing.T) {
 	// create a dummy temporary function to sign the data
 	sign := func(data map[string]any) (string, error) {
 		// convert the data to EIP-712 typed data
-		_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", func(f float64) (*big.Int, error) {
+		payload, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", func(f float64) (*big.Int, error) {
 			// standard (default) conversion of float to big int
 			return big.NewInt(int64(f)), nil
 		})
 		assert.Nil(t, err)
 
 		// compute the EIP-712 hash
-		//
-		// normally would be `dataHash, err := dids.ComputeEIP712Hash(payload.Data)` but
-		// we want to keep this method private, so we'll just hardcode the hash here for this
-		// particular unit test case
-		dataHash := []byte{15, 233, 134, 98, 193, 209, 180, 13, 124, 237, 174, 183, 79, 181, 206, 254, 125, 138, 91, 249, 230, 243, 91, 195, 137, 142, 164, 209, 201, 90, 216, 177}
+		dataHash, err := dids.ComputeEIP712Hash(payload)
+		assert.Nil(t, err)
+
+		// which is pinned, so a change in the encoding shows up here
+		expectedHash := []byte{15, 233, 134, 98, 193, 209, 180, 13, 124, 237, 174, 183, 79, 181, 206, 254, 125, 138, 91, 249, 230, 243, 91, 195, 137, 142, 164, 209, 201, 90, 216, 177}
+		assert.Equal(t, expectedHash, dataHash)
 
 		// sign the data hash using the priv key
 		bytesOfSig, err := crypto.Sign(dataHash, privateKey)
@@ -89,7 +98,7 @@ func TestConvertToEIP712TypedDataInvalidDomain(t *testing.T) {
 	_, err := dids.ConvertToEIP712TypedData("", data, "tx_container_v0", func(f float64) (*big.Int, error) {
 		return big.NewInt(int64(f)), nil
 	})
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, dids.ErrEmptyDomain)
 }
 
 func TestConvertToEIP712TypedDataInvalidPrimaryTypename(t *testing.T) {
@@ -98,7 +107,7 @@ func TestConvertToEIP712TypedDataInvalidPrimaryTypename(t *testing.T) {
 	_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "", func(f float64) (*big.Int, error) {
 		return big.NewInt(int64(f)), nil
 	})
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, dids.ErrEmptyPrimaryType)
 }
 
 func TestEIP712InvalidTypes(t *testing.T) {
@@ -113,6 +122,45 @@ func TestEIP712InvalidTypes(t *testing.T) {
 
 	// invalid types SHOULD throw errors
 	assert.NotNil(t, err)
+
+	// naming the first bad field, in sorted order
+	var typeErr *dids.UnsupportedTypeError
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "myChan", typeErr.FieldPath)
+	assert.Equal(t, reflect.Chan, typeErr.Kind)
+}
+
+func TestEIP712InvalidTypePath(t *testing.T) {
+	data := map[string]interface{}{
+		"tx": map[string]interface{}{
+			"op": "call",
+			"payload": map[string]interface{}{
+				"contract": "hive:bob",
+				"callback": func() {},
+			},
+		},
+	}
+
+	_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "tx.payload.callback")
+	assert.Contains(t, err.Error(), "func")
+
+	var typeErr *dids.UnsupportedTypeError
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "tx.payload.callback", typeErr.FieldPath)
+	assert.Equal(t, reflect.Func, typeErr.Kind)
+
+	// values inside arrays get their index too
+	data = map[string]interface{}{
+		"tx": map[string]interface{}{
+			"ops": []interface{}{[]interface{}{"a"}, []interface{}{make(chan int)}},
+		},
+	}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "tx.ops[1][0]", typeErr.FieldPath)
+	assert.Equal(t, reflect.Chan, typeErr.Kind)
 }
 
 func TestEIP712ComplexSliceArrayData(t *testing.T) {
@@ -358,10 +406,202 @@ func TestEIP712FloatHandlerError(t *testing.T) {
 
 	// we expect an error because we have a float in our data and we specify in our
 	// handler that we don't want floats
-	_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", func(f float64) (*big.Int, error) {
-		return nil, fmt.Errorf("we decide to throw this error if we accidently put a float in our data")
-	})
+	handlerErr := fmt.Errorf("we decide to throw this error if we accidently put a float in our data")
+	floatHandler := func(f float64) (*big.Int, error) {
+		return nil, handlerErr
+	}
+	_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", floatHandler)
 	assert.NotNil(t, err)
+
+	// the handler's own error comes through, marked as a float handler failure
+	assert.ErrorIs(t, err, dids.ErrFloatHandler)
+	assert.ErrorIs(t, err, handlerErr)
+
+	// same for floats in arrays
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"ages": []interface{}{1.5}}, "tx_container_v0", floatHandler)
+	assert.ErrorIs(t, err, dids.ErrFloatHandler)
+	assert.ErrorIs(t, err, handlerErr)
+}
+
+func TestEIP712RejectFloats(t *testing.T) {
+	truncate := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}
+
+	// integers of any Go type are fine
+	_, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"age": 1, "nonce": uint64(2)}, "tx_container_v0", truncate, dids.WithRejectFloats())
+	assert.Nil(t, err)
+
+	// but every float fails, whole or not, in arrays too, with the option or with no handler at all
+	for name, data := range map[string]map[string]interface{}{
+		"fractional": {"age": 1.5},
+		"whole":      {"age": 1.0},
+		"array":      {"ages": []interface{}{2.0}},
+	} {
+		_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", truncate, dids.WithRejectFloats())
+		assert.ErrorIs(t, err, dids.ErrFloatsRejected, name)
+		assert.ErrorContains(t, err, "age", name)
+
+		_, err = dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+		assert.ErrorIs(t, err, dids.ErrFloatsRejected, name)
+		assert.ErrorContains(t, err, "age", name)
+	}
+
+	// unless whole floats are made integers first
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"age": 1.0}, "tx_container_v0", nil, dids.WithWholeFloatsAsInts())
+	assert.Nil(t, err)
+}
+
+func TestEIP712WholeFloatsSkipFloatHandler(t *testing.T) {
+	var data map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": 1, "delta": -2, "fees": [3, 4], "rate": 1.5}`), &data))
+
+	// only the fractional value is the float handler's business
+	var handled []float64
+	floatHandler := func(f float64) (*big.Int, error) {
+		handled = append(handled, f)
+		return big.NewInt(int64(f * 10)), nil
+	}
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", floatHandler)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{1.5}, handled)
+
+	assert.Equal(t, []apitypes.Type{
+		{Name: "amount", Type: "uint256"},
+		{Name: "delta", Type: "int256"},
+		{Name: "fees", Type: "uint256[]"},
+		{Name: "rate", Type: "uint256"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, big.NewInt(1), typedData.Data.Message["amount"])
+	assert.Equal(t, big.NewInt(-2), typedData.Data.Message["delta"])
+	assert.Equal(t, []*big.Int{big.NewInt(3), big.NewInt(4)}, typedData.Data.Message["fees"])
+	assert.Equal(t, big.NewInt(15), typedData.Data.Message["rate"])
+
+	// whole floats past int64 are still exact
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"supply": 1e20}, "tx_container_v0", floatHandler)
+	assert.Nil(t, err)
+	expected, _ := new(big.Int).SetString("100000000000000000000", 10)
+	assert.Equal(t, expected, typedData.Data.Message["supply"])
+}
+
+func TestScalingFloatHandlerWholeFloats(t *testing.T) {
+	var data map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"whole": 1.0, "fractional": 1.5}`), &data))
+	scale := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(math.Round(f * 100))), nil
+	}
+
+	// the global handler only sees the fractional value, so scaling there treats the two inconsistently, as
+	// documented on WithFloatHandler
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", scale)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), typedData.Data.Message["whole"])
+	assert.Equal(t, big.NewInt(150), typedData.Data.Message["fractional"])
+
+	// while a field handler, which is where scaling belongs, sees both
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil,
+		dids.WithFieldFloatHandlers(map[string]func(float64) (*big.Int, error){"whole": scale, "fractional": scale}))
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), typedData.Data.Message["whole"])
+	assert.Equal(t, big.NewInt(150), typedData.Data.Message["fractional"])
+}
+
+func TestConvertFloatRounding(t *testing.T) {
+	convert := func(value float64, opts ...dids.ConvertOption) (interface{}, error) {
+		typedData, err := dids.NewEthProvider(opts...).TypedData(map[string]interface{}{"amount": value})
+		if err != nil {
+			return nil, err
+		}
+		return typedData.Data.Message["amount"], nil
+	}
+
+	// a huge float is exact rather than overflowing int64
+	huge, err := convert(1e30)
+	assert.Nil(t, err)
+	expected, _ := new(big.Int).SetString("1000000000000000019884624838656", 10)
+	assert.Equal(t, expected, huge)
+
+	for _, tc := range []struct {
+		rounding dids.FloatRounding
+		value    float64
+		expected int64
+	}{
+		{dids.FloatRoundingTruncate, 2.7, 2},
+		{dids.FloatRoundingTruncate, -2.7, -2},
+		{dids.FloatRoundingNearest, 2.5, 3},
+		{dids.FloatRoundingNearest, -2.5, -3},
+		{dids.FloatRoundingNearest, 2.4, 2},
+	} {
+		rounded, err := convert(tc.value, dids.WithFloatRounding(tc.rounding))
+		assert.Nil(t, err)
+		assert.Equal(t, big.NewInt(tc.expected), rounded, tc.value)
+	}
+
+	// truncation is the default
+	truncated, err := convert(2.7)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(2), truncated)
+
+	// the error mode refuses fractions, but not whole floats
+	_, err = convert(2.5, dids.WithFloatRounding(dids.FloatRoundingError))
+	assert.ErrorIs(t, err, dids.ErrInexactFloat)
+	whole, err := convert(3, dids.WithFloatRounding(dids.FloatRoundingError))
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(3), whole)
+
+	// NaN and Inf error in every mode, and with any handler
+	lenient := dids.WithFloatHandler(func(f float64) (*big.Int, error) { return big.NewInt(0), nil })
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for _, opt := range []dids.ConvertOption{
+			dids.WithFloatRounding(dids.FloatRoundingTruncate),
+			dids.WithFloatRounding(dids.FloatRoundingNearest),
+			dids.WithFloatRounding(dids.FloatRoundingError),
+			lenient,
+		} {
+			_, err := convert(value, opt)
+			assert.ErrorIs(t, err, dids.ErrNonFiniteFloat, value)
+		}
+	}
+}
+
+func TestConvertFieldFloatHandlers(t *testing.T) {
+	scale := func(decimals float64) func(float64) (*big.Int, error) {
+		return func(f float64) (*big.Int, error) {
+			return big.NewInt(int64(math.Round(f * math.Pow(10, decimals)))), nil
+		}
+	}
+	data := map[string]interface{}{
+		"tx": map[string]interface{}{
+			"payload": map[string]interface{}{"amount": 0.5, "fee": 1.0},
+			"hive":    1.234,
+			"splits":  []interface{}{0.25, 1.0},
+		},
+		"rate": 2.5,
+	}
+	handlers := dids.WithFieldFloatHandlers(map[string]func(float64) (*big.Int, error){
+		"tx.payload.amount": scale(8),
+		"tx.payload.fee":    scale(8),
+		"tx.hive":           scale(3),
+		"tx.splits":         scale(2),
+	})
+
+	for _, opts := range [][]dids.ConvertOption{{handlers}, {handlers, dids.WithWholeFloatsAsInts()}} {
+		typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", func(f float64) (*big.Int, error) {
+			return big.NewInt(int64(f)), nil
+		}, opts...)
+		assert.Nil(t, err)
+
+		tx := typedData.Data.Message["tx"].(map[string]interface{})
+		payload := tx["payload"].(map[string]interface{})
+		assert.Equal(t, big.NewInt(50000000), payload["amount"])
+		// whole values are scaled too
+		assert.Equal(t, big.NewInt(100000000), payload["fee"])
+		assert.Equal(t, big.NewInt(1234), tx["hive"])
+		assert.Equal(t, []*big.Int{big.NewInt(25), big.NewInt(100)}, tx["splits"])
+
+		// everything else falls back to the global handler
+		assert.Equal(t, big.NewInt(2), typedData.Data.Message["rate"])
+	}
 }
 
 func TestEIP712EmptyData(t *testing.T) {
@@ -483,3 +723,1476 @@ func TestEIP712ConvertStringToAddr(t *testing.T) {
 	assert.Contains(t, walletField, "type")
 	assert.Equal(t, walletField["type"], "address")
 }
+
+func TestConvertAddressCase(t *testing.T) {
+	data := map[string]interface{}{
+		"wallet": "0xCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC",
+	}
+
+	convert := func(opts ...dids.ConvertOption) dids.TypedData {
+		typedData, err := dids.NewEthProvider(opts...).TypedData(data)
+		assert.Nil(t, err)
+		assert.Equal(t, "address", typedData.Data.Types["tx_container_v0"][0].Type)
+		return typedData
+	}
+
+	asIs := convert()
+	lower := convert(dids.WithAddressCase(dids.AddressCaseLower))
+	checksum := convert(dids.WithAddressCase(dids.AddressCaseChecksum))
+
+	assert.Equal(t, "0xCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC", asIs.Data.Message["wallet"])
+	assert.Equal(t, "0xcccccccccccccccccccccccccccccccccccccccc", lower.Data.Message["wallet"])
+	assert.Equal(t, "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC", checksum.Data.Message["wallet"])
+
+	// the struct hash encodes the 20 byte value, so the casing doesn't change it
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	lowerSig, err := dids.NewEthProviderFromKey(privateKey, dids.WithAddressCase(dids.AddressCaseLower)).SignData(data)
+	assert.Nil(t, err)
+	checksumSig, err := dids.NewEthProviderFromKey(privateKey, dids.WithAddressCase(dids.AddressCaseChecksum)).SignData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, lowerSig, checksumSig)
+}
+
+func TestEthProviderWithoutAddressCoercion(t *testing.T) {
+	data := map[string]interface{}{
+		"wallet": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	}
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+
+	// a provider for a subsystem that never deals with addresses
+	provider := dids.NewEthProviderFromKey(privateKey, dids.WithoutAddressCoercion())
+
+	typedData, err := provider.TypedData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "string", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	// the policy applies to signing too
+	sig, err := provider.SignData(data)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, sig)
+
+	// a default provider still coerces
+	typedData, err = dids.NewEthProvider().TypedData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "address", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	// type overrides are carried by the provider as well
+	typedData, err = dids.NewEthProvider(dids.WithTypeOverrides(map[string]string{"wallet": "string"})).TypedData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "string", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	// and can't sign without a key
+	_, err = dids.NewEthProvider().SignData(data)
+	assert.ErrorIs(t, err, dids.ErrNoPrivateKey)
+}
+
+func TestConvertBatch(t *testing.T) {
+	payloads := []interface{}{
+		map[string]interface{}{"op": "transfer", "amount": uint64(1)},
+		map[string]interface{}{"op": "stake", "callback": func() {}}, // invalid, funcs can't be typed
+		map[string]interface{}{"op": "withdraw", "amount": uint64(3)},
+	}
+
+	results, errs := dids.ConvertBatch("vsc.network", "tx_container_v0", payloads, func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Len(t, results, 3)
+	assert.Len(t, errs, 3)
+
+	// only the middle payload fails
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+	assert.Nil(t, errs[2])
+
+	assert.Equal(t, "transfer", results[0].Data.Message["op"])
+	assert.Equal(t, "withdraw", results[2].Data.Message["op"])
+
+	// an invalid shared domain fails every payload
+	_, errs = dids.ConvertBatch("", "tx_container_v0", payloads, func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	for _, err := range errs {
+		assert.NotNil(t, err)
+	}
+}
+
+func TestEthDIDVerifyEmptyDomain(t *testing.T) {
+	data := map[string]interface{}{
+		"op":     "transfer",
+		"amount": 10,
+	}
+
+	cborData, err := cbor.WrapObject(data, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	block, err := blocks.NewBlockWithCid(cborData.RawData(), cborData.Cid())
+	assert.Nil(t, err)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	// the empty domain has no fields at all
+	typedData, err := dids.ConvertToEIP712TypedData("", data, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}, dids.WithEmptyDomain())
+	assert.Nil(t, err)
+
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(marshalled, &result))
+	assert.Empty(t, result["domain"])
+	assert.Empty(t, result["EIP712Domain"])
+
+	// sign by hand with the separator being just the typeHash of `EIP712Domain()`
+	// (the message hash doesn't depend on the domain, but go-ethereum refuses to hash without one)
+	withDomain := typedData.Data
+	withDomain.Domain.Name = "vsc.network"
+	messageHash, err := withDomain.HashStruct("tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	dataHash := crypto.Keccak256([]byte("\x19\x01"), crypto.Keccak256([]byte("EIP712Domain()")), messageHash)
+	sigBytes, err := crypto.Sign(dataHash, privateKey)
+	assert.Nil(t, err)
+	sig := hex.EncodeToString(sigBytes)
+
+	// the provider signs the same way
+	providerSig, err := dids.NewEthProviderFromKey(privateKey, dids.WithEmptyDomain()).SignData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, sig, providerSig)
+
+	// verifies under the empty domain
+	valid, err := ethDID.VerifyWithOptions(block, sig, dids.WithConvertOptions(dids.WithEmptyDomain()))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// but not under the default vsc.network domain
+	valid, err = ethDID.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+// wraps the data into a CBOR block, as a tx would be
+func createCBORBlock(t *testing.T, data map[string]interface{}) blocks.Block {
+	block, err := dids.EncodeBlock(data)
+	assert.Nil(t, err)
+	return block
+}
+
+func TestEthDIDVerifyBytes(t *testing.T) {
+	data := map[string]interface{}{"foo": "bar", "baz": 12345}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	sig, err := dids.NewEthProviderFromKey(privateKey).Sign(block)
+	assert.Nil(t, err)
+
+	// the bytes verify exactly as the block does
+	fromBlock, err := ethDID.Verify(block, sig)
+	assert.Nil(t, err)
+	fromBytes, err := ethDID.VerifyBytes(block.RawData(), sig)
+	assert.Nil(t, err)
+	assert.True(t, fromBytes)
+	assert.Equal(t, fromBlock, fromBytes)
+
+	// and other bytes don't
+	other := createCBORBlock(t, map[string]interface{}{"foo": "bar", "baz": 12346})
+	valid, err := ethDID.VerifyBytes(other.RawData(), sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	_, err = ethDID.VerifyBytes([]byte("not cbor"), sig)
+	assert.NotNil(t, err)
+}
+
+func TestEthDIDEqual(t *testing.T) {
+	checksummed := dids.NewEthDID("0x553Cb1F25f8409360E081E5e015812d1FB238d22")
+	lower := dids.EthDID(dids.EthDIDPrefix + "0x553cb1f25f8409360e081e5e015812d1fb238d22")
+	upper := dids.EthDID(dids.EthDIDPrefix + "0x553CB1F25F8409360E081E5E015812D1FB238D22")
+
+	// mixed casings of one address are equal, and render the same
+	assert.True(t, checksummed.Equal(lower))
+	assert.True(t, lower.Equal(upper))
+	assert.Equal(t, checksummed.String(), lower.String())
+	assert.Equal(t, checksummed.String(), upper.String())
+
+	// so their strings work as map keys
+	seen := map[string]bool{checksummed.String(): true}
+	assert.True(t, seen[lower.String()])
+
+	// as does a did:pkh for the address on chain 1, but not on another chain
+	assert.True(t, checksummed.Equal(dids.NewPkhDID(1, "0x553cb1f25f8409360e081e5e015812d1fb238d22")))
+	assert.False(t, checksummed.Equal(dids.NewPkhDID(137, "0x553cb1f25f8409360e081e5e015812d1fb238d22")))
+
+	// distinct addresses aren't equal
+	assert.False(t, checksummed.Equal(dids.NewEthDID("0x00000000000000000000000000000000000000a1")))
+	assert.False(t, checksummed.Equal(nil))
+
+	// malformed DIDs render as given
+	assert.Equal(t, "did:pkh:eip155:1:0x1234", dids.EthDID("did:pkh:eip155:1:0x1234").String())
+}
+
+func TestEthDIDVerifyNonCanonicalSigHex(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	// the same sig formatted differently still verifies
+	for _, formatted := range []string{sig, "0x" + sig, strings.ToUpper(sig), "0x" + strings.ToUpper(sig)} {
+		valid, err := ethDID.Verify(block, formatted)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	}
+}
+
+func TestEthDIDVerifyTrimsInputs(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	// stray whitespace and mixed prefixes on the sig are tolerated by default
+	sloppySigs := []string{" " + sig + " ", "\t0x" + sig + "\n", "  0X" + strings.ToUpper(sig)}
+	for _, formatted := range sloppySigs {
+		valid, err := dids.NewEthDID(address).Verify(block, formatted)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	}
+
+	// and on the DID's address too
+	sloppyDID := dids.NewEthDID(" 0X" + address[2:] + " ")
+	valid, err := sloppyDID.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// strict mode takes inputs as given
+	for _, formatted := range sloppySigs {
+		valid, err := dids.NewEthDID(address).VerifyWithOptions(block, formatted, dids.WithStrictInputs())
+		assert.NotNil(t, err)
+		assert.False(t, valid)
+	}
+	valid, err = sloppyDID.VerifyWithOptions(block, sig, dids.WithStrictInputs())
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// while well-formed inputs still verify strictly
+	for _, formatted := range []string{sig, "0x" + sig} {
+		valid, err := dids.NewEthDID(address).VerifyWithOptions(block, formatted, dids.WithStrictInputs())
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	}
+}
+
+func TestEthDIDVerifyChainIDOnlyDomain(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	// no name, just a chainId
+	typedData, err := dids.ConvertToEIP712TypedData("", data, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}, dids.WithChainID(1))
+	assert.Nil(t, err)
+
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(marshalled, &result))
+	assert.Equal(t, map[string]interface{}{"chainId": float64(1)}, result["domain"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "chainId", "type": "uint256"}}, result["EIP712Domain"])
+
+	// separator = keccak256(typeHash ‖ uint256(chainId)), with no name in the type
+	chainID := make([]byte, 32)
+	chainID[31] = 1
+	domainSeparator := crypto.Keccak256(crypto.Keccak256([]byte("EIP712Domain(uint256 chainId)")), chainID)
+
+	messageHash, err := typedData.Data.HashStruct("tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	sigBytes, err := crypto.Sign(crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash), privateKey)
+	assert.Nil(t, err)
+	sig := hex.EncodeToString(sigBytes)
+
+	valid, err := ethDID.VerifyWithOptions(block, sig, dids.WithDomainName(""), dids.WithConvertOptions(dids.WithChainID(1)))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a name+chainId domain produces a different separator
+	valid, err = ethDID.VerifyWithOptions(block, sig, dids.WithConvertOptions(dids.WithChainID(1)))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestChainIDDomainSeparatorMatchesGoEthereum(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}, dids.WithChainID(42161))
+	assert.Nil(t, err)
+
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(marshalled, &result))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "name", "type": "string"},
+		map[string]interface{}{"name": "chainId", "type": "uint256"},
+	}, result["EIP712Domain"])
+
+	// go-ethereum's own encoder, given the domain type explicitly
+	reference := typedData.Data
+	reference.Types = apitypes.Types{
+		"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+	}
+	for name, fields := range typedData.Data.Types {
+		reference.Types[name] = fields
+	}
+	domainSeparator, err := reference.HashStruct("EIP712Domain", reference.Domain.Map())
+	assert.Nil(t, err)
+
+	referenceHash, _, err := apitypes.TypedDataAndHash(reference)
+	assert.Nil(t, err)
+
+	// the chainId is folded into the separator, and so the final hash
+	matches, computedHex, err := dids.VerifyHashMatchesWallet(typedData, hex.EncodeToString(referenceHash))
+	assert.Nil(t, err)
+	assert.True(t, matches, computedHex)
+	messageHash, err := reference.HashStruct("tx_container_v0", reference.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash), referenceHash)
+}
+
+func TestDomainTypeCanonicalOrder(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	floatHandler := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}
+
+	// options given out of order still give name, chainId, verifyingContract
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", floatHandler,
+		dids.WithVerifyingContract("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"), dids.WithChainID(137))
+	assert.Nil(t, err)
+
+	canonicalOrder := []interface{}{
+		map[string]interface{}{"name": "name", "type": "string"},
+		map[string]interface{}{"name": "chainId", "type": "uint256"},
+		map[string]interface{}{"name": "verifyingContract", "type": "address"},
+	}
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(marshalled, &result))
+	assert.Equal(t, canonicalOrder, result["EIP712Domain"])
+
+	// typed data parsed back from JSON listing the fields in another order is emitted in the canonical one
+	shuffled := strings.Replace(string(marshalled), `"EIP712Domain":[{"name":"name","type":"string"},{"name":"chainId","type":"uint256"},{"name":"verifyingContract","type":"address"}]`,
+		`"EIP712Domain":[{"name":"verifyingContract","type":"address"},{"name":"name","type":"string"},{"name":"chainId","type":"uint256"}]`, 1)
+	assert.NotEqual(t, string(marshalled), shuffled)
+	var parsed dids.TypedData
+	assert.Nil(t, json.Unmarshal([]byte(shuffled), &parsed))
+	remarshalled, err := parsed.MarshalJSON()
+	assert.Nil(t, err)
+	result = nil
+	assert.Nil(t, json.Unmarshal(remarshalled, &result))
+	assert.Equal(t, canonicalOrder, result["EIP712Domain"])
+
+	// and the separator is the one for the canonical type string
+	reference := typedData.Data
+	reference.Types = apitypes.Types{"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}}
+	for name, fields := range typedData.Data.Types {
+		reference.Types[name] = fields
+	}
+	assert.Equal(t, "EIP712Domain(string name,uint256 chainId,address verifyingContract)", string(reference.EncodeType("EIP712Domain")))
+	referenceHash, _, err := apitypes.TypedDataAndHash(reference)
+	assert.Nil(t, err)
+	matches, _, err := dids.VerifyHashMatchesWallet(parsed, hex.EncodeToString(referenceHash))
+	assert.Nil(t, err)
+	assert.True(t, matches)
+}
+
+func TestVerifyingContractAndSaltDomain(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	floatHandler := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}
+	contract := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	salt := "0x" + strings.Repeat("ab", 32)
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", floatHandler,
+		dids.WithVerifyingContract(contract), dids.WithSalt(salt))
+	assert.Nil(t, err)
+
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(marshalled, &result))
+	assert.Equal(t, map[string]interface{}{
+		"name":              "vsc.network",
+		"verifyingContract": "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"salt":              salt,
+	}, result["domain"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "name", "type": "string"},
+		map[string]interface{}{"name": "verifyingContract", "type": "address"},
+		map[string]interface{}{"name": "salt", "type": "bytes32"},
+	}, result["EIP712Domain"])
+
+	// same hash as go-ethereum's encoder
+	reference := typedData.Data
+	reference.Types = apitypes.Types{"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "verifyingContract", Type: "address"},
+		{Name: "salt", Type: "bytes32"},
+	}}
+	for name, fields := range typedData.Data.Types {
+		reference.Types[name] = fields
+	}
+	referenceHash, _, err := apitypes.TypedDataAndHash(reference)
+	assert.Nil(t, err)
+	matches, _, err := dids.VerifyHashMatchesWallet(typedData, hex.EncodeToString(referenceHash))
+	assert.Nil(t, err)
+	assert.True(t, matches)
+
+	// malformed fields are rejected up front
+	for _, opt := range []dids.ConvertOption{
+		dids.WithVerifyingContract("0x1234"),
+		dids.WithVerifyingContract("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"),
+		dids.WithSalt("0x1234"),
+		dids.WithSalt(strings.Repeat("ab", 32)),
+	} {
+		_, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", floatHandler, opt)
+		assert.ErrorContains(t, err, "invalid domain")
+	}
+}
+
+func TestVerifyWithExpectedType(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	// signed as a vsc tx
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.VerifyWithExpectedType(block, sig, ethDID, "vsc.network", "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the same sig doesn't pass as some other schema
+	valid, err = dids.VerifyWithExpectedType(block, sig, ethDID, "vsc.network", "decoy_v0", nil)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// DIDs that don't sign typed data can't be checked this way
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	keyDID, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+	_, err = dids.VerifyWithExpectedType(block, sig, keyDID, "vsc.network", "tx_container_v0", nil)
+	assert.ErrorIs(t, err, dids.ErrNotTypedDataDID)
+}
+
+func TestConvertNumberFormat(t *testing.T) {
+	data := map[string]interface{}{
+		"amount": uint64(255),
+		"delta":  -16,
+		"nonces": []uint64{1, 2},
+	}
+
+	decimal, err := dids.NewEthProvider().TypedData(data)
+	assert.Nil(t, err)
+	hexed, err := dids.NewEthProvider(dids.WithNumberFormat(dids.NumberFormatHex)).TypedData(data)
+	assert.Nil(t, err)
+
+	// the message renders the numbers differently
+	decimalJSON, err := decimal.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(decimalJSON), `"amount":255`)
+	assert.Contains(t, string(decimalJSON), `"delta":-16`)
+	assert.Contains(t, string(decimalJSON), `"nonces":[1,2]`)
+
+	hexJSON, err := hexed.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(hexJSON), `"amount":"0xff"`)
+	assert.Contains(t, string(hexJSON), `"delta":"-0x10"`)
+	assert.Contains(t, string(hexJSON), `"nonces":["0x1","0x2"]`)
+
+	// but each hashes to the same struct, since the numbers are encoded by value
+	decimalHash, err := dids.HashStructFor(decimal, "tx_container_v0", decimal.Data.Message)
+	assert.Nil(t, err)
+	hexHash, err := dids.HashStructFor(hexed, "tx_container_v0", hexed.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, decimalHash, hexHash)
+
+	// and a hex-rendering signer's sig verifies under the matching option
+	data = map[string]interface{}{"op": "transfer", "amount": 10}
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey, dids.WithNumberFormat(dids.NumberFormatHex)).SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).VerifyWithOptions(
+		createCBORBlock(t, data), sig,
+		dids.WithConvertOptions(dids.WithNumberFormat(dids.NumberFormatHex)),
+	)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestEthProviderSignMany(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	payloads := []any{
+		map[string]interface{}{"op": "transfer", "amount": uint64(1)},
+		map[string]interface{}{"op": "stake", "amount": uint64(2), "headers": map[string]interface{}{"nonce": 7}},
+		[]interface{}{"hive:alice", "hive:bob"},
+		// not a map or array, so has no typed data form
+		"just a string",
+	}
+
+	results, err := provider.SignMany(payloads)
+	assert.Nil(t, err)
+	assert.Len(t, results, len(payloads))
+
+	// each good payload verifies against its own block
+	for _, result := range results[:3] {
+		assert.Nil(t, result.Err)
+		valid, err := ethDID.Verify(result.Block, result.Sig)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	}
+
+	// while the bad one fails alone
+	assert.NotNil(t, results[3].Err)
+
+	// no key, no sigs
+	_, err = dids.NewEthProvider().SignMany(payloads)
+	assert.ErrorIs(t, err, dids.ErrNoPrivateKey)
+}
+
+func BenchmarkEthProviderSignMany(b *testing.B) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(b, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	payloads := make([]any, 100)
+	for i := range payloads {
+		payloads[i] = map[string]interface{}{
+			"op":      "transfer",
+			"amount":  i,
+			"headers": map[string]interface{}{"nonce": i},
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := provider.SignMany(payloads); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewEthDIDFromPubKey(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	expected := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	// compressed and uncompressed encodings of the same key give the same DID
+	fromCompressed, err := dids.NewEthDIDFromPubKey(crypto.CompressPubkey(&privateKey.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fromCompressed)
+
+	fromUncompressed, err := dids.NewEthDIDFromPubKey(crypto.FromECDSAPub(&privateKey.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fromUncompressed)
+
+	// which verifies the key's sigs
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	valid, err := fromCompressed.Verify(createCBORBlock(t, data), sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// anything else is rejected
+	_, err = dids.NewEthDIDFromPubKey(make([]byte, 20))
+	assert.NotNil(t, err)
+	_, err = dids.NewEthDIDFromPubKey(make([]byte, 33))
+	assert.NotNil(t, err)
+}
+
+func TestConvertRawMessage(t *testing.T) {
+	type envelope struct {
+		Op      string          `json:"op"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	data := envelope{
+		Op:      "transfer",
+		Payload: json.RawMessage(`{"to": "hive:bob", "memo": "hi"}`),
+	}
+
+	typedData, err := dids.NewEthProvider().TypedData(data)
+	assert.Nil(t, err)
+
+	// the fragment becomes a proper sub-struct, not `bytes`
+	assert.Equal(t, []apitypes.Type{
+		{Name: "op", Type: "string"},
+		{Name: "payload", Type: "tx_container_v0.payload"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, []apitypes.Type{
+		{Name: "memo", Type: "string"},
+		{Name: "to", Type: "string"},
+	}, typedData.Data.Types["tx_container_v0.payload"])
+	assert.Equal(t, map[string]interface{}{"to": "hive:bob", "memo": "hi"}, typedData.Data.Message["payload"])
+
+	// invalid JSON is an error rather than silently becoming bytes
+	_, err = dids.NewEthProvider().TypedData(map[string]interface{}{"payload": json.RawMessage(`{nope`)})
+	assert.NotNil(t, err)
+}
+
+func TestEthDIDVerifyDetailed(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	results, err := provider.SignMany([]any{realDataCase()})
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	block, sig := results[0].Block, results[0].Sig
+
+	// without WithMessage only the outcome is reported
+	result, err := dids.NewEthDID(address).VerifyDetailed(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, address, result.RecoveredAddress)
+	assert.Len(t, result.Hash, 32)
+	assert.Nil(t, result.Message)
+	assert.Nil(t, result.MessageJSON)
+
+	// with it, the signed message comes back too
+	result, err = dids.NewEthDID(address).VerifyDetailed(block, sig, dids.WithMessage())
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+
+	expected, err := provider.TypedData(realDataCase())
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprint(expected.Data.Message), fmt.Sprint(result.Message))
+
+	inputJSON, err := json.Marshal(realDataCase())
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(inputJSON), string(result.MessageJSON))
+
+	// an invalid sig still reports who it recovers to
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	result, err = dids.NewEthDID(crypto.PubkeyToAddress(otherKey.PublicKey).Hex()).VerifyDetailed(block, sig)
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, address, result.RecoveredAddress)
+}
+
+func TestConvertWholeFloatsAsInts(t *testing.T) {
+	asFloat := map[string]interface{}{"amount": 1.0, "fees": []interface{}{2.0, 3.0}, "rate": 1.5}
+	asInt := map[string]interface{}{"amount": 1, "fees": []interface{}{2, 3}, "rate": 1.5}
+
+	// by default the float and int forms are typed differently
+	floatTyped, err := dids.NewEthProvider().TypedData(asFloat)
+	assert.Nil(t, err)
+	intTyped, err := dids.NewEthProvider().TypedData(asInt)
+	assert.Nil(t, err)
+	assert.NotEqual(t, floatTyped.Data.Types, intTyped.Data.Types)
+
+	// normalized, they're the same type and hash
+	normalizing := dids.NewEthProvider(dids.WithWholeFloatsAsInts())
+	floatTyped, err = normalizing.TypedData(asFloat)
+	assert.Nil(t, err)
+	intTyped, err = normalizing.TypedData(asInt)
+	assert.Nil(t, err)
+	assert.Equal(t, intTyped.Data.Types, floatTyped.Data.Types)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "amount", Type: "int256"},
+		{Name: "fees", Type: "int256[]"},
+		// still a fraction, so still through the float handler
+		{Name: "rate", Type: "uint256"},
+	}, floatTyped.Data.Types["tx_container_v0"])
+
+	floatHash, err := dids.HashStructFor(floatTyped, "tx_container_v0", floatTyped.Data.Message)
+	assert.Nil(t, err)
+	intHash, err := dids.HashStructFor(intTyped, "tx_container_v0", intTyped.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, intHash, floatHash)
+}
+
+// an enum-like op, as domain code would define it
+type testOp int
+
+const (
+	testOpTransfer testOp = iota
+	testOpStake
+)
+
+func (o testOp) String() string {
+	return [...]string{"transfer", "stake"}[o]
+}
+
+// a struct with a canonical string form
+type testAsset struct {
+	Symbol string
+	Chain  string
+}
+
+func (a testAsset) String() string {
+	return a.Chain + ":" + a.Symbol
+}
+
+func TestConvertStringers(t *testing.T) {
+	data := map[string]interface{}{
+		"op":    testOpStake,
+		"asset": testAsset{Symbol: "HIVE", Chain: "hive"},
+	}
+
+	// by default they convert by their underlying kind, which named ints can't
+	_, err := dids.NewEthProvider().TypedData(data)
+	assert.NotNil(t, err)
+	typedData, err := dids.NewEthProvider().TypedData(map[string]interface{}{"asset": data["asset"]})
+	assert.Nil(t, err)
+	assert.Equal(t, "tx_container_v0.asset", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	// with the option, by their string form
+	typedData, err = dids.NewEthProvider(dids.WithStringers()).TypedData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "asset", Type: "string"},
+		{Name: "op", Type: "string"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, "stake", typedData.Data.Message["op"])
+	assert.Equal(t, "hive:HIVE", typedData.Data.Message["asset"])
+	assert.NotContains(t, typedData.Data.Types, "tx_container_v0.asset")
+}
+
+func TestAddressFromDID(t *testing.T) {
+	expected := "0x553cb1f25f8409360e081e5e015812d1fb238d22"
+
+	for _, form := range []string{
+		// EthDID form
+		"did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22",
+		dids.NewEthDID("0x553Cb1F25f8409360E081E5e015812d1FB238d22").String(),
+		// did:pkh on another chain
+		"did:pkh:eip155:137:0x553CB1F25F8409360E081E5E015812D1FB238D22",
+		// bare
+		"0x553Cb1F25f8409360E081E5e015812d1FB238d22",
+		" 0X553cb1f25f8409360e081e5e015812d1fb238d22 ",
+	} {
+		address, err := dids.AddressFromDID(form)
+		assert.Nil(t, err, form)
+		assert.Equal(t, expected, address, form)
+	}
+
+	// non-EVM DIDs
+	_, err := dids.AddressFromDID("did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK")
+	assert.ErrorIs(t, err, dids.ErrNotEVMDID)
+	_, err = dids.AddressFromDID("did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:7S3P4HxJpyyigGzodYwHtCxZyUQe9JiBMHyRWXArAaKv")
+	assert.ErrorIs(t, err, dids.ErrNotEVMDID)
+
+	// and things that aren't addresses at all
+	for _, bad := range []string{"", "hive:alice", "0x1234", "did:pkh:eip155:1:", "did:pkh:eip155::0x553Cb1F25f8409360E081E5e015812d1FB238d22"} {
+		_, err := dids.AddressFromDID(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestRecoverSigner(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	expected := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	sigBytes, err := hex.DecodeString(sig)
+	assert.Nil(t, err)
+
+	// v as 27/28, like most wallets produce
+	walletSig := append([]byte{}, sigBytes...)
+	walletSig[64] += 27
+
+	// EIP-2098 compact form, with v folded into the top bit of s
+	compactSig := append([]byte{}, sigBytes[:64]...)
+	compactSig[32] |= sigBytes[64] << 7
+
+	for _, formatted := range []string{
+		sig,
+		"0x" + hex.EncodeToString(walletSig),
+		hex.EncodeToString(compactSig),
+	} {
+		recovered, err := dids.RecoverSigner(block, formatted)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, recovered)
+	}
+
+	// a sig over other data recovers to some other address
+	otherBlock := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 11})
+	recovered, err := dids.RecoverSigner(otherBlock, sig)
+	assert.Nil(t, err)
+	assert.NotEqual(t, expected, recovered)
+
+	_, err = dids.RecoverSigner(block, sig[:10])
+	assert.NotNil(t, err)
+}
+
+func TestDIDFromSignature(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	did, err := dids.DIDFromSignature(block, sig)
+	assert.Nil(t, err)
+	assert.Equal(t, dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()), did)
+
+	valid, err := did.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	_, err = dids.DIDFromSignature(block, sig[:10])
+	assert.ErrorIs(t, err, dids.ErrMalformedSignature)
+}
+
+func TestEthDIDVerifyPersonalSign(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	message := block.RawData()
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	// what a wallet's personal_sign produces, with v as 27/28
+	sigBytes, err := crypto.Sign(accounts.TextHash(message), privateKey)
+	assert.Nil(t, err)
+	sigBytes[64] += 27
+	sig := "0x" + hex.EncodeToString(sigBytes)
+
+	valid, err := did.VerifyPersonalSign(message, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a tampered message recovers to someone else
+	tampered := append([]byte{}, message...)
+	tampered[len(tampered)-1] ^= 0xff
+	valid, err = did.VerifyPersonalSign(tampered, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// and the schemes don't mix: a personal_sign sig isn't an EIP-712 one
+	sigBytes[64] -= 27
+	valid, err = did.Verify(block, hex.EncodeToString(sigBytes))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestNewEthDIDChecksums(t *testing.T) {
+	checksummed := "did:pkh:eip155:1:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+
+	for _, address := range []string{
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	} {
+		did, err := dids.NewEthDIDChecked(address)
+		assert.Nil(t, err)
+		assert.Equal(t, checksummed, did.String())
+		assert.Equal(t, did, dids.NewEthDID(address))
+	}
+
+	for _, address := range []string{
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz",
+	} {
+		_, err := dids.NewEthDIDChecked(address)
+		assert.ErrorIs(t, err, dids.ErrInvalidAddress, address)
+
+		// the unchecked constructor keeps it as given
+		assert.Equal(t, dids.EthDIDPrefix+address, dids.NewEthDID(address).String())
+	}
+}
+
+func TestConvertNestedArrays(t *testing.T) {
+	data := map[string]interface{}{
+		"coords": [][]int{{1, 2}, {3, 4}},
+		"pairs":  [][]string{{"a", "b"}, {}},
+		"empty":  [][]string{{}, {}},
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "coords", Type: "int256[][]"},
+		{Name: "empty", Type: "undefined[][]"},
+		{Name: "pairs", Type: "string[][]"},
+	}, typedData.Data.Types["tx"])
+	assert.Equal(t, []interface{}{
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		[]*big.Int{big.NewInt(3), big.NewInt(4)},
+	}, typedData.Data.Message["coords"])
+
+	// and it round trips through a signed block
+	block := createCBORBlock(t, data)
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// inner arrays have to agree on their type
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"mixed": []interface{}{[]interface{}{1}, []interface{}{"a"}},
+	}, "tx", nil)
+	assert.NotNil(t, err)
+}
+
+func TestConvertNarrowedIntegerOverride(t *testing.T) {
+	floatHandler := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}
+	overrides := dids.WithTypeOverrides(map[string]string{"amount": "uint64", "headers.nonce": "uint8"})
+
+	data := map[string]interface{}{
+		"amount":  uint64(18446744073709551615),
+		"headers": map[string]interface{}{"nonce": 255},
+	}
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx", floatHandler, overrides)
+	assert.Nil(t, err)
+	assert.Contains(t, typedData.Data.Types["tx"], apitypes.Type{Name: "amount", Type: "uint64"})
+	assert.Contains(t, typedData.Data.Types["tx.headers"], apitypes.Type{Name: "nonce", Type: "uint8"})
+
+	// the narrowed types hash like go-ethereum's encoder
+	_, _, err = dids.VerifyHashMatchesWallet(typedData, "0x"+strings.Repeat("00", 32))
+	assert.Nil(t, err)
+
+	// values that don't fit the narrower width are rejected
+	data["headers"] = map[string]interface{}{"nonce": 256}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", data, "tx", floatHandler, overrides)
+	assert.ErrorContains(t, err, "out of range for uint8")
+
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"amount": 2e19}, "tx", func(f float64) (*big.Int, error) {
+		n, _ := new(big.Float).SetFloat64(f).Int(nil)
+		return n, nil
+	}, overrides)
+	assert.ErrorContains(t, err, "out of range for uint64")
+
+	// as are negatives for unsigned types
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"amount": -1}, "tx", floatHandler, overrides)
+	assert.ErrorContains(t, err, "out of range for uint64")
+}
+
+func TestConvertNegativeIntegers(t *testing.T) {
+	floatHandler := func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	}
+	minInt256 := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 255))
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"delta":    -42,
+		"big":      new(big.Int).Set(minInt256),
+		"positive": big.NewInt(42),
+		"float":    -1.0,
+		"deltas":   []*big.Int{big.NewInt(1), big.NewInt(-1)},
+	}, "tx", floatHandler)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "big", Type: "int256"},
+		{Name: "delta", Type: "int256"},
+		{Name: "deltas", Type: "int256[]"},
+		{Name: "float", Type: "int256"},
+		{Name: "positive", Type: "uint256"},
+	}, typedData.Data.Types["tx"])
+
+	// the message holds signed decimals rather than wrapped around uint256 values
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(marshalled), `"delta":-42`)
+	assert.Contains(t, string(marshalled), `"big":`+minInt256.String())
+	assert.Contains(t, string(marshalled), `"deltas":[1,-1]`)
+
+	// and hashes like go-ethereum's encoder, at the edge of the range too
+	reference := typedData.Data
+	reference.Types = apitypes.Types{"EIP712Domain": {{Name: "name", Type: "string"}}, "tx": typedData.Data.Types["tx"]}
+	referenceHash, _, err := apitypes.TypedDataAndHash(reference)
+	assert.Nil(t, err)
+	matches, _, err := dids.VerifyHashMatchesWallet(typedData, hex.EncodeToString(referenceHash))
+	assert.Nil(t, err)
+	assert.True(t, matches)
+
+	// one past the edge doesn't fit
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"big": new(big.Int).Sub(minInt256, big.NewInt(1)),
+	}, "tx", floatHandler)
+	assert.ErrorContains(t, err, "below the int256 range")
+}
+
+func TestEthProviderSign(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{
+		"op":      "transfer",
+		"amount":  10,
+		"payload": map[string]interface{}{"to": "hive:bob", "memo": "thanks"},
+	})
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	valid, err := did.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the sig is for this block only
+	otherBlock := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 11})
+	valid, err = did.Verify(otherBlock, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// and needs a key
+	_, err = dids.NewEthProvider().Sign(block)
+	assert.ErrorIs(t, err, dids.ErrNoPrivateKey)
+}
+
+func TestVerifyBatch(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	keyA, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	keyB, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	didA := dids.NewEthDID(crypto.PubkeyToAddress(keyA.PublicKey).Hex())
+	didB := dids.NewEthDID(crypto.PubkeyToAddress(keyB.PublicKey).Hex())
+
+	sigA, err := dids.NewEthProviderFromKey(keyA).Sign(block)
+	assert.Nil(t, err)
+	sigB, err := dids.NewEthProviderFromKey(keyB).Sign(block)
+	assert.Nil(t, err)
+
+	results, err := dids.VerifyBatch(block, []dids.EthDIDSig{
+		{DID: didA, Sig: sigA},
+		{DID: didB, Sig: sigA},
+		{DID: didB, Sig: sigB},
+		{DID: didA, Sig: "not hex"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false, true, false}, results)
+
+	// each result matches what Verify says alone
+	for i, pair := range []struct {
+		DID dids.EthDID
+		Sig string
+	}{{didA, sigA}, {didB, sigA}} {
+		valid, _ := pair.DID.Verify(block, pair.Sig)
+		assert.Equal(t, results[i], valid)
+	}
+
+	results, err = dids.VerifyBatch(block, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+func benchmarkVerifyPairs(b *testing.B, n int) (blocks.Block, []dids.EthDIDSig) {
+	data := map[string]interface{}{
+		"op":      "transfer",
+		"amount":  10,
+		"headers": map[string]interface{}{"nonce": 1, "required_auths": []interface{}{"a", "b"}},
+	}
+	cborData, err := cbor.WrapObject(data, multihash.SHA2_256, -1)
+	assert.Nil(b, err)
+	block, err := blocks.NewBlockWithCid(cborData.RawData(), cborData.Cid())
+	assert.Nil(b, err)
+
+	pairs := make([]dids.EthDIDSig, n)
+	for i := range pairs {
+		privateKey, err := crypto.GenerateKey()
+		assert.Nil(b, err)
+		sig, err := dids.NewEthProviderFromKey(privateKey).Sign(block)
+		assert.Nil(b, err)
+		pairs[i] = dids.EthDIDSig{DID: dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()), Sig: sig}
+	}
+	return block, pairs
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	block, pairs := benchmarkVerifyPairs(b, 20)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dids.VerifyBatch(block, pairs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyEach(b *testing.B) {
+	block, pairs := benchmarkVerifyPairs(b, 20)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, pair := range pairs {
+			if _, err := pair.DID.Verify(block, pair.Sig); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// a payload with thousands of fields, as a large tx batch would have
+func largeSyntheticPayload() map[string]interface{} {
+	payload := make(map[string]interface{}, 2200)
+	for i := 0; i < 2000; i++ {
+		payload[fmt.Sprintf("field_%04d", i)] = i
+	}
+	for i := 0; i < 200; i++ {
+		payload[fmt.Sprintf("op_%03d", i)] = map[string]interface{}{
+			"op":     "transfer",
+			"to":     "hive:bob",
+			"amount": i,
+			"memo":   strings.Repeat("x", i%16),
+			"tags":   []interface{}{"a", "b"},
+		}
+	}
+	return payload
+}
+
+func BenchmarkConvertLargePayload(b *testing.B) {
+	payload := largeSyntheticPayload()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dids.ConvertToEIP712TypedData("vsc.network", payload, "tx_container_v0", nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConvertFixedBytes(t *testing.T) {
+	var hash [32]byte
+	for i := range hash {
+		hash[i] = byte(i)
+	}
+	data := map[string]interface{}{
+		"hash":     hash,
+		"selector": [4]byte{0xa9, 0x05, 0x9c, 0xbb},
+		"tags":     [][4]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "hash", Type: "bytes32"},
+		{Name: "selector", Type: "bytes4"},
+		{Name: "tags", Type: "bytes4[]"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, "0x"+hex.EncodeToString(hash[:]), typedData.Data.Message["hash"])
+	assert.Equal(t, "0xa9059cbb", typedData.Data.Message["selector"])
+
+	// hashed the same as go-ethereum does
+	hashed, err := dids.HashStructFor(typedData, "tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	expected, err := typedData.Data.HashStruct("tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(expected), hashed)
+
+	// past bytes32 there's no fixed bytes type
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"blob": [33]byte{}}, "tx_container_v0", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "blob")
+}
+
+func TestConvertBools(t *testing.T) {
+	data := map[string]interface{}{"confirmed": true, "flags": []bool{true, false}}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "confirmed", Type: "bool"},
+		{Name: "flags", Type: "bool[]"},
+	}, typedData.Data.Types["tx_container_v0"])
+
+	// the values stay JSON booleans
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(marshalled), `"message":{"confirmed":true,"flags":[true,false]}`)
+
+	hashed, err := dids.HashStructFor(typedData, "tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	expected, err := typedData.Data.HashStruct("tx_container_v0", typedData.Data.Message)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(expected), hashed)
+
+	// bools decoded from a block convert the same way, and sign and verify
+	block := createCBORBlock(t, data)
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).Sign(block)
+	assert.Nil(t, err)
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// an array can't mix bools with anything else
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"flags": []interface{}{true, "yes"}}, "tx_container_v0", nil)
+	assert.ErrorContains(t, err, "flags")
+}
+
+func TestConvertTimeValues(t *testing.T) {
+	expiry := time.Date(2024, 6, 1, 12, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	data := map[string]interface{}{
+		"expiry":  expiry,
+		"created": &expiry,
+		"epoch":   time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	// unix timestamps by default
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "created", Type: "uint256"},
+		{Name: "epoch", Type: "int256"},
+		{Name: "expiry", Type: "uint256"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, big.NewInt(1717237800), typedData.Data.Message["expiry"])
+	assert.Equal(t, big.NewInt(-86400), typedData.Data.Message["epoch"])
+
+	// or RFC 3339 strings in UTC
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil, dids.WithTimeFormat(dids.TimeFormatRFC3339))
+	assert.Nil(t, err)
+	assert.Equal(t, "string", typedData.Data.Types["tx_container_v0"][2].Type)
+	assert.Equal(t, "2024-06-01T10:30:00Z", typedData.Data.Message["expiry"])
+
+	// time fields of structs too
+	type order struct {
+		Expiry time.Time `json:"expiry"`
+	}
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", order{Expiry: expiry}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{{Name: "expiry", Type: "uint256"}}, typedData.Data.Types["tx_container_v0"])
+}
+
+func TestConvertBigValues(t *testing.T) {
+	data := map[string]interface{}{
+		"supply": *big.NewInt(1000),
+		"price":  big.NewFloat(25),
+		"debt":   *big.NewFloat(-3),
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "debt", Type: "int256"},
+		{Name: "price", Type: "uint256"},
+		{Name: "supply", Type: "uint256"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, big.NewInt(25), typedData.Data.Message["price"])
+
+	// a fractional big.Float has no exact integer form
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"price": big.NewFloat(2.5)}, "tx_container_v0", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "price")
+}
+
+func TestConvertLimits(t *testing.T) {
+	// a map nested far past any real payload trips the default depth limit
+	deep := map[string]interface{}{"leaf": "value"}
+	for i := 0; i < 1000; i++ {
+		deep = map[string]interface{}{"inner": deep}
+	}
+	_, err := dids.ConvertToEIP712TypedData("vsc.network", deep, "tx_container_v0", nil)
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+
+	// and a configured one
+	shallow := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "value"}}}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", shallow, "tx_container_v0", nil, dids.WithMaxDepth(3))
+	assert.Nil(t, err)
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", shallow, "tx_container_v0", nil, dids.WithMaxDepth(2))
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+	assert.ErrorContains(t, err, "a.b")
+
+	// nested arrays count as levels too
+	nestedArrays := map[string]interface{}{"grid": [][]int{{1, 2}, {3}}}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", nestedArrays, "tx_container_v0", nil, dids.WithMaxDepth(2))
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+
+	// an oversized slice, by default and configured
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"ids": make([]int, dids.DefaultMaxSliceLength+1)}, "tx_container_v0", nil)
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"ids": []int{1, 2, 3}}, "tx_container_v0", nil, dids.WithMaxSliceLength(3))
+	assert.Nil(t, err)
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"ids": []int{1, 2, 3, 4}}, "tx_container_v0", nil, dids.WithMaxSliceLength(3))
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+
+	// bytes are one value, however long
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"blob": make([]byte, 16)}, "tx_container_v0", nil, dids.WithMaxSliceLength(3))
+	assert.Nil(t, err)
+
+	// fields are counted across every nested struct
+	fields := map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2, "d": 3}}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", fields, "tx_container_v0", nil, dids.WithMaxFields(4))
+	assert.Nil(t, err)
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", fields, "tx_container_v0", nil, dids.WithMaxFields(3))
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+
+	// verification is guarded the same way
+	block := createCBORBlock(t, deep)
+	_, err = dids.NewEthDID("0x553Cb1F25f6409e3C9A5B8bD6fd4A6A3B5cA2a8f").Verify(block, "0x"+strings.Repeat("00", 65))
+	assert.ErrorIs(t, err, dids.ErrConvertLimit)
+}
+
+func TestConvertTypedMaps(t *testing.T) {
+	data := map[string]interface{}{
+		"labels":   map[string]string{"to": "hive:bob", "memo": "rent"},
+		"balances": map[string]uint64{"hive": 10, "hbd": 1 << 60},
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+
+	// each key becomes a field of a nested struct, typed from its value
+	assert.Equal(t, []apitypes.Type{
+		{Name: "balances", Type: "tx_container_v0.balances"},
+		{Name: "labels", Type: "tx_container_v0.labels"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, []apitypes.Type{
+		{Name: "memo", Type: "string"},
+		{Name: "to", Type: "string"},
+	}, typedData.Data.Types["tx_container_v0.labels"])
+	assert.Equal(t, []apitypes.Type{
+		{Name: "hbd", Type: "uint256"},
+		{Name: "hive", Type: "uint256"},
+	}, typedData.Data.Types["tx_container_v0.balances"])
+
+	// uint64 values are kept exact, even past float64 precision
+	balances := typedData.Data.Message["balances"].(map[string]interface{})
+	assert.Equal(t, "1152921504606846976", fmt.Sprint(balances["hbd"]))
+	assert.Equal(t, "rent", typedData.Data.Message["labels"].(map[string]interface{})["memo"])
+
+	_, err = dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+
+	// a typed map converts like the equivalent map[string]interface{}
+	untyped, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"labels": map[string]interface{}{"to": "hive:bob", "memo": "rent"},
+	}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	typed, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"labels": map[string]string{"to": "hive:bob", "memo": "rent"},
+	}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	untypedHash, err := dids.ComputeEIP712Hash(untyped)
+	assert.Nil(t, err)
+	typedHash, err := dids.ComputeEIP712Hash(typed)
+	assert.Nil(t, err)
+	assert.Equal(t, untypedHash, typedHash)
+
+	// as the root too
+	root, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]uint64{"nonce": 1 << 60}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "1152921504606846976", fmt.Sprint(root.Data.Message["nonce"]))
+
+	// maps without string keys have no field names
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"ids": map[int]string{1: "a"}}, "tx_container_v0", nil)
+	assert.ErrorContains(t, err, "string keys")
+}
+
+func TestConvertBigIntPassthrough(t *testing.T) {
+	amount := new(big.Int).Lsh(big.NewInt(1), 200)
+	decimal := "1606938044258990275541962092341162602522202993782792835301376"
+	assert.Equal(t, decimal, amount.String())
+
+	data := map[string]interface{}{
+		"amount":  amount,
+		"amounts": []*big.Int{amount},
+		"debt":    new(big.Int).Neg(amount),
+	}
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "amount", Type: "uint256"},
+		{Name: "amounts", Type: "uint256[]"},
+		{Name: "debt", Type: "int256"},
+	}, typedData.Data.Types["tx_container_v0"])
+
+	// the full value is kept, not squeezed through an int64 or a float
+	assert.Equal(t, decimal, fmt.Sprint(typedData.Data.Message["amount"]))
+	marshalled, err := typedData.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(marshalled), `"amount":`+decimal+`,"amounts":[`+decimal+`],"debt":-`+decimal)
+
+	// and it comes back exactly from JSON, hashing the same
+	var parsed dids.TypedData
+	assert.Nil(t, json.Unmarshal(marshalled, &parsed))
+	expected, err := dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+	parsedHash, err := dids.ComputeEIP712Hash(parsed)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, parsedHash)
+
+	// numbers that arrive as JSON are kept exact too, rather than rounded through a float64
+	raw, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{
+		"amount": json.RawMessage(decimal),
+	}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "uint256", raw.Data.Types["tx_container_v0"][0].Type)
+	assert.Equal(t, decimal, fmt.Sprint(raw.Data.Message["amount"]))
+	rawHash, err := dids.ComputeEIP712Hash(raw)
+	assert.Nil(t, err)
+	direct, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"amount": amount}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	directHash, err := dids.ComputeEIP712Hash(direct)
+	assert.Nil(t, err)
+	assert.Equal(t, directHash, rawHash)
+
+	// as are payloads only convertible through JSON, like a pointer to a map
+	viaJSON, err := dids.ConvertToEIP712TypedData("vsc.network", &map[string]interface{}{"amount": amount}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, decimal, fmt.Sprint(viaJSON.Data.Message["amount"]))
+}
@@ -0,0 +1,123 @@
+package dids
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== errors =====
+
+var (
+	ErrSignatureExpired     = fmt.Errorf("signature is older than the allowed max age")
+	ErrSignatureNotYetValid = fmt.Errorf("signature is not valid before its not_before time")
+	ErrMissingIssuedAt      = fmt.Errorf("payload has no issued_at time to check its age against")
+	ErrIssuedInFuture       = fmt.Errorf("signature's issued_at is further in the future than the allowed clock skew")
+	ErrPayloadTimeRange     = fmt.Errorf("payload time is out of range")
+)
+
+// ===== constants =====
+
+// how far ahead of our clock a payload's issued_at may be when no skew is given
+const DefaultMaxClockSkew = time.Minute
+
+// ===== types =====
+
+// the validity window a signed payload must fall in
+//
+// times are read from the payload as unix seconds, from `headers.<field>` or else a top-level `<field>`:
+//   - issued_at: when the payload was signed, checked against MaxAge
+//   - not_before: the earliest time the payload may be presented
+type FreshnessPolicy struct {
+	// how old (by issued_at) a payload may be; 0 disables the check
+	MaxAge time.Duration
+
+	// how far in the future issued_at may be, for signers whose clocks run ahead of ours, when MaxAge is checked;
+	// <= 0 uses DefaultMaxClockSkew. without it a payload issued far in the future would never age
+	MaxClockSkew time.Duration
+
+	// the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// ===== verification =====
+
+// verifies the sig like did.Verify, then checks the payload is inside its validity window
+func VerifyFresh(block blocks.Block, sig string, did DID, policy FreshnessPolicy) (bool, error) {
+	valid, err := did.Verify(block, sig)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	var decodedData map[string]interface{}
+	if err := decodeFromCBOR(block.RawData(), &decodedData); err != nil {
+		return false, fmt.Errorf("failed to decode CBOR data: %w", err)
+	}
+
+	now := time.Now()
+	if policy.Now != nil {
+		now = policy.Now()
+	}
+
+	// too early?
+	notBefore, ok, err := payloadTime(decodedData, "not_before")
+	if err != nil {
+		return false, err
+	}
+	if ok && now.Before(notBefore) {
+		return false, ErrSignatureNotYetValid
+	}
+
+	// too old?
+	if policy.MaxAge > 0 {
+		issuedAt, ok, err := payloadTime(decodedData, "issued_at")
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, ErrMissingIssuedAt
+		}
+		maxClockSkew := policy.MaxClockSkew
+		if maxClockSkew <= 0 {
+			maxClockSkew = DefaultMaxClockSkew
+		}
+		if issuedAt.Sub(now) > maxClockSkew {
+			return false, ErrIssuedInFuture
+		}
+		if now.Sub(issuedAt) > policy.MaxAge {
+			return false, ErrSignatureExpired
+		}
+	}
+
+	return true, nil
+}
+
+// ===== utils =====
+
+// reads a unix seconds timestamp from headers.<field>, or else the top-level <field>
+//
+// times past what time.Unix takes (math.MaxInt64 seconds) are an ErrPayloadTimeRange, rather than wrapping around
+// into the past
+func payloadTime(data map[string]interface{}, field string) (time.Time, bool, error) {
+	source := data
+	if headers, ok := data["headers"].(map[string]interface{}); ok {
+		if _, ok := headers[field]; ok {
+			source = headers
+		}
+	}
+
+	if _, ok := source[field]; !ok {
+		return time.Time{}, false, nil
+	}
+
+	seconds, err := optionalUint(source, field)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if seconds > math.MaxInt64 {
+		return time.Time{}, false, fmt.Errorf("%w: %s %d", ErrPayloadTimeRange, field, seconds)
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
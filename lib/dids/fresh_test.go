@@ -0,0 +1,107 @@
+package dids_test
+
+import (
+	"math"
+	"testing"
+	"time"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyFreshNotBefore(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	now := time.Unix(1_700_000_000, 0)
+	policy := dids.FreshnessPolicy{Now: func() time.Time { return now }}
+
+	// not_before in the future gets rejected
+	future := map[string]interface{}{
+		"op":      "transfer",
+		"headers": map[string]interface{}{"not_before": int(now.Add(time.Hour).Unix())},
+	}
+	sig, err := provider.SignData(future)
+	assert.Nil(t, err)
+	valid, err := dids.VerifyFresh(createCBORBlock(t, future), sig, did, policy)
+	assert.ErrorIs(t, err, dids.ErrSignatureNotYetValid)
+	assert.False(t, valid)
+
+	// not_before in the past is accepted
+	past := map[string]interface{}{
+		"op":      "transfer",
+		"headers": map[string]interface{}{"not_before": int(now.Add(-time.Hour).Unix())},
+	}
+	sig, err = provider.SignData(past)
+	assert.Nil(t, err)
+	valid, err = dids.VerifyFresh(createCBORBlock(t, past), sig, did, policy)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestVerifyFreshMaxAge(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	now := time.Unix(1_700_000_000, 0)
+
+	// a full window: valid from an hour ago, issued 10 minutes ago
+	data := map[string]interface{}{
+		"op":         "transfer",
+		"not_before": int(now.Add(-time.Hour).Unix()),
+		"issued_at":  int(now.Add(-10 * time.Minute).Unix()),
+	}
+	block := createCBORBlock(t, data)
+	sig, err := provider.SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.VerifyFresh(block, sig, did, dids.FreshnessPolicy{
+		MaxAge: 15 * time.Minute,
+		Now:    func() time.Time { return now },
+	})
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	valid, err = dids.VerifyFresh(block, sig, did, dids.FreshnessPolicy{
+		MaxAge: 5 * time.Minute,
+		Now:    func() time.Time { return now },
+	})
+	assert.ErrorIs(t, err, dids.ErrSignatureExpired)
+	assert.False(t, valid)
+}
+
+func TestVerifyFreshTimeBounds(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	provider := dids.NewEthProviderFromKey(privateKey)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	now := time.Unix(1_700_000_000, 0)
+	policy := dids.FreshnessPolicy{MaxAge: 15 * time.Minute, Now: func() time.Time { return now }}
+	verify := func(data map[string]interface{}) (bool, error) {
+		block := createCBORBlock(t, data)
+		sig, err := provider.Sign(block)
+		assert.Nil(t, err)
+		return dids.VerifyFresh(block, sig, did, policy)
+	}
+
+	// a not_before past int64 seconds (here as a float, like JSON sources give) doesn't wrap around into the past
+	valid, err := verify(map[string]interface{}{"op": "transfer", "not_before": math.Pow(2, 63)})
+	assert.ErrorIs(t, err, dids.ErrPayloadTimeRange)
+	assert.False(t, valid)
+
+	// an issued_at far in the future would never age, so it's rejected
+	valid, err = verify(map[string]interface{}{"op": "transfer", "issued_at": int(now.Add(time.Hour).Unix())})
+	assert.ErrorIs(t, err, dids.ErrIssuedInFuture)
+	assert.False(t, valid)
+
+	// while one within the clock skew is fine
+	valid, err = verify(map[string]interface{}{"op": "transfer", "issued_at": int(now.Add(30 * time.Second).Unix())})
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,140 @@
+package dids
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== constants =====
+
+// the JWS alg of EthProvider.SignJWS: ECDSA over secp256k1 with SHA-256 (RFC 8812)
+const JWSAlgES256K = "ES256K"
+
+// ===== errors =====
+
+var (
+	ErrInvalidJWS     = fmt.Errorf("invalid JWS")
+	ErrJWSKidMismatch = fmt.Errorf("JWS kid is another signer")
+)
+
+// ===== types =====
+
+// the protected header of a JWS from EthProvider.SignJWS
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// ===== signing =====
+
+// signs the block as a compact JWS (header.payload.sig) for tools from the wider DID/VC ecosystem, rather than
+// the bare hex EIP-712 sig of Sign
+//
+// the payload is the block's canonical dag-cbor bytes, so the JWS carries everything needed to verify it. it's an
+// ordinary ES256K JWS: the sig is the 64 byte [R || S] over sha256 of the encoded header and payload, so the
+// header (alg and the signer's DID as kid) is signed too. it's not an EIP-712 sig, which Sign makes
+func (e *EthProvider) SignJWS(block blocks.Block) (string, error) {
+	if e.privKey == nil {
+		return "", ErrNoPrivateKey
+	}
+
+	canonical, err := IsCanonicalCBOR(block)
+	if err != nil {
+		return "", fmt.Errorf("failed to check CBOR encoding: %w", err)
+	}
+	if !canonical {
+		return "", ErrNonCanonicalCBOR
+	}
+
+	header, err := json.Marshal(jwsHeader{
+		Alg: JWSAlgES256K,
+		Kid: NewEthDID(crypto.PubkeyToAddress(e.privKey.PublicKey).Hex()).String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWS header: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(block.RawData())
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := crypto.Sign(digest[:], e.privKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWS: %w", err)
+	}
+
+	// ES256K sigs are [R || S], without the recovery ID
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig[:crypto.SignatureLength-1]), nil
+}
+
+// ===== verification =====
+
+// verifies a compact JWS from EthProvider.SignJWS: that its sig is this DID's ES256K sig over the JWS signing
+// input, and its payload a canonical dag-cbor block
+//
+// a JWS naming another signer as its kid errors with ErrJWSKidMismatch, and malformed JWSs with ErrInvalidJWS.
+// a well formed JWS signed by some other key is just invalid
+func (d EthDID) VerifyJWS(jws string) (bool, error) {
+	segments := strings.Split(jws, ".")
+	if len(segments) != 3 {
+		return false, fmt.Errorf("%w: expected 3 segments, got %d", ErrInvalidJWS, len(segments))
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil {
+		return false, fmt.Errorf("%w: header: %v", ErrInvalidJWS, err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return false, fmt.Errorf("%w: header: %v", ErrInvalidJWS, err)
+	}
+	if header.Alg != JWSAlgES256K {
+		return false, fmt.Errorf("%w: unsupported alg %q", ErrInvalidJWS, header.Alg)
+	}
+	if header.Kid != "" && !strings.EqualFold(header.Kid, d.String()) {
+		return false, fmt.Errorf("%w: %q, not %s", ErrJWSKidMismatch, header.Kid, d)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return false, fmt.Errorf("%w: payload: %v", ErrInvalidJWS, err)
+	}
+	sigBytes, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return false, fmt.Errorf("%w: signature: %v", ErrInvalidJWS, err)
+	}
+	if len(sigBytes) != crypto.SignatureLength-1 {
+		return false, fmt.Errorf("%w: ES256K signature must be %d bytes, got %d", ErrInvalidJWS, crypto.SignatureLength-1, len(sigBytes))
+	}
+
+	block, err := blockFromCBOR(payload)
+	if err != nil {
+		return false, err
+	}
+	canonical, err := IsCanonicalCBOR(block)
+	if err != nil {
+		return false, fmt.Errorf("failed to check CBOR encoding: %w", err)
+	}
+	if !canonical {
+		return false, ErrNonCanonicalCBOR
+	}
+
+	// the DID is an address rather than a key, so the sig is checked by recovering the key under either recovery
+	// ID. only low-s sigs are accepted, so each has one encoding
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	for recoveryID := byte(0); recoveryID < 2; recoveryID++ {
+		recovered, err := crypto.SigToPub(digest[:], append(append([]byte{}, sigBytes...), recoveryID))
+		if err != nil {
+			continue
+		}
+		if crypto.VerifySignature(crypto.CompressPubkey(recovered), digest[:], sigBytes) &&
+			strings.EqualFold(crypto.PubkeyToAddress(*recovered).Hex(), d.Identifier()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,92 @@
+package dids_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthJWSRoundTrip(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{
+		"tx": map[string]interface{}{"op": "transfer", "amount": 10, "to": "hive:bob"},
+	})
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	provider := dids.NewEthProviderFromKey(privateKey)
+
+	jws, err := provider.SignJWS(block)
+	assert.Nil(t, err)
+
+	valid, err := ethDID.VerifyJWS(jws)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	segments := strings.Split(jws, ".")
+	assert.Len(t, segments, 3)
+
+	// the payload is the block itself, and the sig an ordinary ES256K one over the header and payload
+	header, err := base64.RawURLEncoding.DecodeString(segments[0])
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"alg":"ES256K","kid":"`+ethDID.String()+`"}`, string(header))
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	assert.Nil(t, err)
+	assert.Equal(t, block.RawData(), payload)
+
+	sigBytes, err := base64.RawURLEncoding.DecodeString(segments[2])
+	assert.Nil(t, err)
+	assert.Len(t, sigBytes, 64)
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	assert.True(t, crypto.VerifySignature(crypto.CompressPubkey(&privateKey.PublicKey), digest[:], sigBytes))
+
+	// a JWS naming someone else as its kid is an error for this DID
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	otherDID := dids.NewEthDID(crypto.PubkeyToAddress(otherKey.PublicKey).Hex())
+	valid, err = otherDID.VerifyJWS(jws)
+	assert.ErrorIs(t, err, dids.ErrJWSKidMismatch)
+	assert.False(t, valid)
+
+	// and the header is signed, so swapping the kid (or dropping it) breaks the sig
+	for _, forgedHeader := range []string{`{"alg":"ES256K","kid":"` + otherDID.String() + `"}`, `{"alg":"ES256K"}`} {
+		forged := base64.RawURLEncoding.EncodeToString([]byte(forgedHeader)) + "." + segments[1] + "." + segments[2]
+		valid, err = otherDID.VerifyJWS(forged)
+		assert.Nil(t, err)
+		assert.False(t, valid)
+	}
+}
+
+func TestEthJWSTamperedPayload(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	tampered := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 1000})
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	jws, err := dids.NewEthProviderFromKey(privateKey).SignJWS(block)
+	assert.Nil(t, err)
+
+	segments := strings.Split(jws, ".")
+	segments[1] = base64.RawURLEncoding.EncodeToString(tampered.RawData())
+	valid, err := ethDID.VerifyJWS(strings.Join(segments, "."))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// a payload that isn't a block at all errors
+	segments[1] = "not-cbor"
+	_, err = ethDID.VerifyJWS(strings.Join(segments, "."))
+	assert.NotNil(t, err)
+
+	// as do malformed JWSs
+	_, err = ethDID.VerifyJWS(segments[0] + "." + segments[2])
+	assert.ErrorIs(t, err, dids.ErrInvalidJWS)
+	_, err = ethDID.VerifyJWS(base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256K-R"}`)) + "." + segments[1] + "." + segments[2])
+	assert.ErrorIs(t, err, dids.ErrInvalidJWS)
+}
@@ -26,14 +26,14 @@ const KeyDIDPrefix = "did:key:"
 
 // ===== interface assertions =====
 
-var _ DID[ed25519.PublicKey] = KeyDID("")
+var _ IdentifiedDID[ed25519.PublicKey] = KeyDID("")
 var _ Provider = KeyProvider{}
 
 // ===== KeyDID =====
 
 type KeyDID string
 
-func NewKeyDID(pubKey ed25519.PublicKey) (DID[ed25519.PublicKey], error) {
+func NewKeyDID(pubKey ed25519.PublicKey) (IdentifiedDID[ed25519.PublicKey], error) {
 
 	if pubKey == nil {
 		return KeyDID(""), fmt.Errorf("invalid public key")
@@ -57,21 +57,35 @@ func (d KeyDID) String() string {
 	return string(d)
 }
 
+// the DID's ed25519 pub key, or nil if it holds some other kind of key (see KeyMaterial)
 func (d KeyDID) Identifier() ed25519.PublicKey {
-	// remove the "did:key:" prefix
-	base58Encoded := string(d)[len(KeyDIDPrefix):]
-
-	// decoding the base58 encoded string
-	_, data, err := multibase.Decode(base58Encoded)
-	if err != nil {
+	algorithm, keyBytes, err := d.KeyMaterial()
+	if err != nil || algorithm != KeyAlgorithmEd25519 {
 		return nil
 	}
+	return ed25519.PublicKey(keyBytes)
+}
 
-	// remove the indicator bytes
-	return ed25519.PublicKey(data[2:])
+// how the DID's key signs the JWS signing input ("<base64 header>.<base64 payload>") by default: ed25519 keys
+// use pure ed25519 (EdDSA) with no pre-hashing, and secp256k1 keys ES256K-R over its SHA-256 digest
+//
+// a sig's header alg can name another pre-hash for the key algorithm (Ed25519ph's SHA-512 for ed25519,
+// ES256K-R-DSHA256's double SHA-256 for secp256k1), which Verify then checks it under
+func (d KeyDID) PreHash() PreHash {
+	algorithm, _, err := d.KeyMaterial()
+	if err != nil {
+		return PreHashNone
+	}
+	return defaultPreHash(algorithm)
 }
 
 func (d KeyDID) Verify(block blocks.Block, sig string) (bool, error) {
+	// get the public key from the DID, whose multicodec prefix also says which scheme it signs with
+	algorithm, keyBytes, err := d.KeyMaterial()
+	if err != nil {
+		return false, fmt.Errorf("invalid DID identifier: %w", err)
+	}
+
 	// split the JWT-like signature into 3 parts: header, payload, and signature
 	parts := strings.Split(sig, ".")
 	if len(parts) != 3 {
@@ -99,6 +113,12 @@ func (d KeyDID) Verify(block blocks.Block, sig string) (bool, error) {
 		return false, fmt.Errorf("kid in the header does not match current DID")
 	}
 
+	// the 'alg' says how the signing input was pre-hashed, and must be one for the DID's key algorithm
+	alg, ok := header["alg"].(string)
+	if !ok {
+		return false, fmt.Errorf("invalid or missing alg in header")
+	}
+
 	// decode the payload and extract the CID (string format)
 	decodedPayload, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
@@ -126,14 +146,24 @@ func (d KeyDID) Verify(block blocks.Block, sig string) (bool, error) {
 		return false, fmt.Errorf("invalid signature encoding: %w", err)
 	}
 
-	// get the public key from the DID
-	pubKey := d.Identifier()
-	if pubKey == nil {
-		return false, fmt.Errorf("invalid DID identifier: nil public key")
-	}
-
 	// verify the signature
-	verified := ed25519.Verify(pubKey, []byte(signingInput), decodedSig)
+	var verified bool
+	switch algorithm {
+	case KeyAlgorithmEd25519, KeyAlgorithmSecp256k1:
+		preHash, err := jwsAlgPreHash(algorithm, alg)
+		if err != nil {
+			return false, err
+		}
+		if algorithm == KeyAlgorithmEd25519 {
+			verified = verifyEd25519(ed25519.PublicKey(keyBytes), []byte(signingInput), decodedSig, preHash)
+		} else {
+			verified = verifySecp256k1(keyBytes, []byte(signingInput), decodedSig, preHash)
+		}
+	case KeyAlgorithmBLS12381G1:
+		return false, fmt.Errorf("BLS keys don't sign JWS, verify with BlsDID instead")
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnknownKeyCodec, algorithm)
+	}
 
 	if !verified {
 		return false, fmt.Errorf("signature verification failed")
@@ -146,27 +176,49 @@ func (d KeyDID) Verify(block blocks.Block, sig string) (bool, error) {
 
 type KeyProvider struct {
 	privKey ed25519.PrivateKey
+	preHash PreHash
 }
 
+// signs pure ed25519 (EdDSA), over the raw signing input (see WithPreHash for ed25519ph)
 func NewKeyProvider(privKey ed25519.PrivateKey) KeyProvider {
-	return KeyProvider{privKey: privKey}
+	return KeyProvider{privKey: privKey, preHash: PreHashNone}
+}
+
+// a copy of the provider that signs over the given pre-hash of the signing input, either PreHashNone (EdDSA) or
+// PreHashSHA512 (Ed25519ph)
+func (k KeyProvider) WithPreHash(preHash PreHash) KeyProvider {
+	k.preHash = preHash
+	return k
 }
 
 // ===== implementing the Provider and KeyDIDProvider interfaces =====
 
 func (k KeyProvider) Sign(block blocks.Block) (string, error) {
-	// get the string representation of the CID
-	cidStr := block.Cid().String()
-
 	did, err := NewKeyDID(k.privKey.Public().(ed25519.PublicKey))
 	if err != nil {
 		return "", err
 	}
 
+	// the alg in the header says which pre-hash was signed over
+	alg, err := jwsAlgName(KeyAlgorithmEd25519, k.preHash)
+	if err != nil {
+		return "", err
+	}
+
+	return signJWS(block, alg, did.String(), func(signingInput []byte) ([]byte, error) {
+		return signEd25519(k.privKey, signingInput, k.preHash)
+	})
+}
+
+// builds the JWT-like sig over a block's CID that KeyDID.Verify checks, signing with the given function
+func signJWS(block blocks.Block, alg string, kid string, sign func(signingInput []byte) ([]byte, error)) (string, error) {
+	// get the string representation of the CID
+	cidStr := block.Cid().String()
+
 	// create the JWT header
 	header := map[string]interface{}{
-		"alg": "EdDSA",
-		"kid": did.String(),
+		"alg": alg,
+		"kid": kid,
 		"typ": "JWT",
 		"cty": "JWT",
 	}
@@ -188,7 +240,10 @@ func (k KeyProvider) Sign(block blocks.Block) (string, error) {
 
 	// signing the encoded header and payload
 	signingInput := encodedHeader + "." + encodedPayload
-	sig := ed25519.Sign(k.privKey, []byte(signingInput))
+	sig, err := sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
 
 	// base64 encode the signature
 	encodedSig := base64.StdEncoding.EncodeToString(sig)
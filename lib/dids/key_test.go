@@ -4,11 +4,16 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"testing"
 	"vsc-node/lib/dids"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
 	mh "github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/assert"
 )
@@ -157,3 +162,118 @@ func TestSignVerify(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.False(t, valid)
 }
+
+func TestKeyDIDVerifyDetectsAlgorithm(t *testing.T) {
+	block := createDummyBlock([]byte("dummy data"))
+
+	// ed25519
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edDID, err := dids.NewKeyDID(edPub)
+	assert.Nil(t, err)
+	algorithm, _, err := edDID.(dids.KeyDID).KeyMaterial()
+	assert.Nil(t, err)
+	assert.Equal(t, dids.KeyAlgorithmEd25519, algorithm)
+
+	edSig, err := dids.NewKeyProvider(edPriv).Sign(block)
+	assert.Nil(t, err)
+	valid, err := edDID.Verify(block, edSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// secp256k1
+	secpPriv, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	secpDID, err := dids.NewSecp256k1KeyDID(&secpPriv.PublicKey)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(secpDID.String(), "did:key:zQ3s"))
+	algorithm, _, err = secpDID.KeyMaterial()
+	assert.Nil(t, err)
+	assert.Equal(t, dids.KeyAlgorithmSecp256k1, algorithm)
+	assert.Nil(t, secpDID.Identifier())
+
+	secpSig, err := dids.NewSecp256k1KeyProvider(secpPriv).Sign(block)
+	assert.Nil(t, err)
+	valid, err = secpDID.Verify(block, secpSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// someone else's sig under this DID's header doesn't pass
+	otherPriv, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	otherSig, err := dids.NewSecp256k1KeyProvider(otherPriv).Sign(block)
+	assert.Nil(t, err)
+	forged := secpSig[:strings.LastIndex(secpSig, ".")] + otherSig[strings.LastIndex(otherSig, "."):]
+	valid, err = secpDID.Verify(block, forged)
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	parsed, err := dids.Parse(secpDID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, secpDID, parsed)
+}
+
+func TestKeyDIDVerifyUnknownCodec(t *testing.T) {
+	// a made up 0x1234 multicodec prefix
+	encoded, err := multibase.Encode(multibase.Base58BTC, append([]byte{0xB4, 0x24}, make([]byte, 32)...))
+	assert.Nil(t, err)
+	did := dids.KeyDID(dids.KeyDIDPrefix + encoded)
+
+	_, _, err = did.KeyMaterial()
+	assert.ErrorIs(t, err, dids.ErrUnknownKeyCodec)
+
+	_, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	block := createDummyBlock([]byte("dummy data"))
+	sig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+
+	valid, err := did.Verify(block, sig)
+	assert.ErrorIs(t, err, dids.ErrUnknownKeyCodec)
+	assert.False(t, valid)
+}
+
+func TestKeyDIDKnownVector(t *testing.T) {
+	// RFC 8032 test vector 1
+	seed, err := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	assert.Nil(t, err)
+	privKey := ed25519.NewKeyFromSeed(seed)
+
+	did, err := dids.NewKeyDID(privKey.Public().(ed25519.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, "did:key:z6MktwupdmLXVVqTzCw4i46r4uGyosGXRnR3XjN4Zq7oMMsw", did.String())
+
+	parsed, err := dids.ParseDID(did.String())
+	assert.Nil(t, err)
+	assert.Equal(t, did, parsed)
+
+	// sigs over a CBOR block round trip as well
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	sig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+	valid, err := did.Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestSecp256k1KeyDIDKnownVector(t *testing.T) {
+	// ES256K-R signs sha256 of the signing input, so with RFC 6979 nonces the token for a fixed key and block is
+	// fixed too. the sig here was checked against an independent secp256k1 implementation
+	privKey, err := crypto.ToECDSA(common.LeftPadBytes([]byte{1}, 32))
+	assert.Nil(t, err)
+	did, err := dids.NewSecp256k1KeyDID(&privKey.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "did:key:zQ3shVc2UkAfJCdc1TR8E66J85h48P43r93q8jGPkPpjF9Ef9", did.String())
+
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer"})
+	assert.Equal(t, "bafyreihf2zog5baxez2jd2qae7x7gqiolw4g2lvdy423z5q7eyj2rcnimq", block.Cid().String())
+
+	expected := "eyJhbGciOiJFUzI1NkstUiIsImN0eSI6IkpXVCIsImtpZCI6ImRpZDprZXk6elEzc2hWYzJVa0FmSkNkYzFUUjhFNjZKODVoNDhQNDNyOTNxOGpHUGtQcGpGOUVmOSIsInR5cCI6IkpXVCJ9" +
+		".ImJhZnlyZWloZjJ6b2c1YmF4ZXoyamQycWFlN3g3Z3Fpb2x3NGcybHZkeTQyM3o1cTdleWoycmNuaW1xIg==" +
+		".ZtWfT9syufapYociu9WmOqX7igwF6hO6gIqPoPSvjusgr8swNAYNaT2JYahfCkBpb1q/qp0DJPhs54wBZXFk1AA="
+	sig, err := dids.NewSecp256k1KeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, sig)
+
+	valid, err := did.Verify(block, expected)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,189 @@
+package dids
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/multiformats/go-multibase"
+)
+
+// ===== errors =====
+
+var (
+	ErrUnknownKeyCodec = fmt.Errorf("unsupported key multicodec")
+	ErrNotBase58BTC    = fmt.Errorf("did:key identifier must be base58btc (z) encoded")
+)
+
+// ===== interface assertions =====
+
+var _ Provider = Secp256k1KeyProvider{}
+
+// ===== key algorithms =====
+
+// the kind of public key a KeyDID holds, as read from its multicodec prefix
+type KeyAlgorithm int
+
+const (
+	KeyAlgorithmEd25519 KeyAlgorithm = iota + 1
+	KeyAlgorithmSecp256k1
+	KeyAlgorithmBLS12381G1
+)
+
+func (a KeyAlgorithm) String() string {
+	switch a {
+	case KeyAlgorithmEd25519:
+		return "ed25519"
+	case KeyAlgorithmSecp256k1:
+		return "secp256k1"
+	case KeyAlgorithmBLS12381G1:
+		return "bls12381-g1"
+	default:
+		return fmt.Sprintf("KeyAlgorithm(%d)", int(a))
+	}
+}
+
+// varint encoded multicodec prefixes, ed25519-pub (0xed) and secp256k1-pub (0xe7). bls12_381-g1-pub's is in
+// bls.go
+var (
+	ed25519KeyPrefix   = []byte{0xED, 0x01}
+	secp256k1KeyPrefix = []byte{0xE7, 0x01}
+)
+
+// the DID's key algorithm and raw public key bytes, read from the multicodec prefix of its identifier
+//
+// secp256k1 keys are 33 byte compressed points, as the did:key spec has them, and BLS12-381 G1 keys 48 byte ones.
+// the identifier must be base58btc, so each key has exactly one DID
+func (d KeyDID) KeyMaterial() (KeyAlgorithm, []byte, error) {
+	if len(d) <= len(KeyDIDPrefix) || string(d)[:len(KeyDIDPrefix)] != KeyDIDPrefix {
+		return 0, nil, fmt.Errorf("missing %q prefix", KeyDIDPrefix)
+	}
+
+	// did:key only uses base58btc, and allowing other bases would give one key several DIDs
+	encoding, data, err := multibase.Decode(string(d)[len(KeyDIDPrefix):])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid multibase: %w", err)
+	}
+	if encoding != multibase.Base58BTC {
+		return 0, nil, fmt.Errorf("%w, got multibase prefix %q", ErrNotBase58BTC, string(d)[len(KeyDIDPrefix)])
+	}
+
+	switch {
+	case bytes.HasPrefix(data, ed25519KeyPrefix):
+		keyBytes := data[len(ed25519KeyPrefix):]
+		if len(keyBytes) != ed25519.PublicKeySize {
+			return 0, nil, fmt.Errorf("ed25519 key must be %d bytes, got %d", ed25519.PublicKeySize, len(keyBytes))
+		}
+		return KeyAlgorithmEd25519, keyBytes, nil
+
+	case bytes.HasPrefix(data, secp256k1KeyPrefix):
+		keyBytes := data[len(secp256k1KeyPrefix):]
+		if len(keyBytes) != 33 {
+			return 0, nil, fmt.Errorf("secp256k1 key must be 33 bytes (compressed), got %d", len(keyBytes))
+		}
+		return KeyAlgorithmSecp256k1, keyBytes, nil
+
+	case bytes.HasPrefix(data, blsKeyPrefix):
+		keyBytes := data[len(blsKeyPrefix):]
+		if len(keyBytes) != bls12381.SizeOfG1AffineCompressed {
+			return 0, nil, fmt.Errorf("BLS12-381 G1 key must be %d bytes (compressed), got %d", bls12381.SizeOfG1AffineCompressed, len(keyBytes))
+		}
+		return KeyAlgorithmBLS12381G1, keyBytes, nil
+	}
+
+	return 0, nil, fmt.Errorf("%w: % x", ErrUnknownKeyCodec, data[:min(2, len(data))])
+}
+
+// ===== secp256k1 KeyDIDs =====
+
+// a did:key for a secp256k1 public key
+func NewSecp256k1KeyDID(pubKey *ecdsa.PublicKey) (KeyDID, error) {
+	if pubKey == nil {
+		return KeyDID(""), fmt.Errorf("invalid public key")
+	}
+
+	data := append(append([]byte{}, secp256k1KeyPrefix...), crypto.CompressPubkey(pubKey)...)
+
+	base58Encoded, err := multibase.Encode(multibase.Base58BTC, data)
+	if err != nil {
+		return KeyDID(""), err
+	}
+
+	return KeyDID(KeyDIDPrefix + base58Encoded), nil
+}
+
+// checks a secp256k1 sig over msg, prepared as per the pre-hash, against a compressed pub key. JOSE's ES256K and
+// ES256K-R pre-hash with SHA-256
+//
+// 65 byte sigs are recoverable ([R || S || V]) and the recovered key is compared, 64 byte ones are checked directly
+func verifySecp256k1(compressedPubKey []byte, msg []byte, sig []byte, preHash PreHash) bool {
+	// ECDSA needs a 32 byte digest, which only the SHA-256 pre-hashes give
+	if preHash != PreHashSHA256 && preHash != PreHashDoubleSHA256 {
+		return false
+	}
+	hash := preHash.Apply(msg)
+
+	switch len(sig) {
+	case crypto.SignatureLength:
+		recovered, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			return false
+		}
+		return bytes.Equal(crypto.CompressPubkey(recovered), compressedPubKey)
+
+	case crypto.SignatureLength - 1:
+		return crypto.VerifySignature(compressedPubKey, hash, sig)
+	}
+
+	return false
+}
+
+// signs msg with secp256k1, prepared as per the pre-hash, with the recovery ID appended ([R || S || V]) (the
+// mirror of verifySecp256k1)
+func signSecp256k1(privKey *ecdsa.PrivateKey, msg []byte, preHash PreHash) ([]byte, error) {
+	if preHash != PreHashSHA256 && preHash != PreHashDoubleSHA256 {
+		return nil, fmt.Errorf("%w %s for secp256k1 keys", ErrUnsupportedPreHash, preHash)
+	}
+	return crypto.Sign(preHash.Apply(msg), privKey)
+}
+
+// ===== Secp256k1KeyProvider =====
+
+// signs blocks for a secp256k1 KeyDID, in the same JWS format as KeyProvider
+type Secp256k1KeyProvider struct {
+	privKey *ecdsa.PrivateKey
+	preHash PreHash
+}
+
+// signs ES256K-R, over a SHA-256 digest (see WithPreHash for others)
+func NewSecp256k1KeyProvider(privKey *ecdsa.PrivateKey) Secp256k1KeyProvider {
+	return Secp256k1KeyProvider{privKey: privKey, preHash: PreHashSHA256}
+}
+
+// a copy of the provider that signs over the given pre-hash of the signing input, either PreHashSHA256 (ES256K-R)
+// or PreHashDoubleSHA256 (ES256K-R-DSHA256)
+func (k Secp256k1KeyProvider) WithPreHash(preHash PreHash) Secp256k1KeyProvider {
+	k.preHash = preHash
+	return k
+}
+
+func (k Secp256k1KeyProvider) Sign(block blocks.Block) (string, error) {
+	did, err := NewSecp256k1KeyDID(&k.privKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// the alg in the header says which pre-hash was signed over
+	alg, err := jwsAlgName(KeyAlgorithmSecp256k1, k.preHash)
+	if err != nil {
+		return "", err
+	}
+
+	return signJWS(block, alg, did.String(), func(signingInput []byte) ([]byte, error) {
+		return signSecp256k1(k.privKey, signingInput, k.preHash)
+	})
+}
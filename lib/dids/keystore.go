@@ -0,0 +1,30 @@
+package dids
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// ===== errors =====
+
+var ErrWrongPassphrase = fmt.Errorf("could not decrypt keystore with the given passphrase")
+
+// ===== keystore-backed EthProvider =====
+
+// creates a provider signing with the key in an encrypted Web3 Secret Storage (keystore v3) JSON file
+//
+// a wrong passphrase is an ErrWrongPassphrase, told apart from a malformed keystore. neither error carries the
+// keystore's contents
+func NewEthProviderFromKeystore(jsonBytes []byte, passphrase string, opts ...ConvertOption) (*EthProvider, error) {
+	key, err := keystore.DecryptKey(jsonBytes, passphrase)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, ErrWrongPassphrase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore: %w", err)
+	}
+
+	return NewEthProviderFromKey(key.PrivateKey, opts...), nil
+}
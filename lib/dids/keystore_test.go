@@ -0,0 +1,40 @@
+package dids_test
+
+import (
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEthProviderFromKeystore(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+
+	// an operator's keystore file, with the cheap scrypt params so the test stays fast
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(privateKey, "hunter2")
+	assert.Nil(t, err)
+	keystoreJSON, err := ks.Export(account, "hunter2", "correct horse")
+	assert.Nil(t, err)
+
+	provider, err := dids.NewEthProviderFromKeystore(keystoreJSON, "correct horse")
+	assert.Nil(t, err)
+
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex()).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a wrong passphrase is told apart from a broken file
+	_, err = dids.NewEthProviderFromKeystore(keystoreJSON, "hunter2")
+	assert.ErrorIs(t, err, dids.ErrWrongPassphrase)
+
+	_, err = dids.NewEthProviderFromKeystore([]byte(`{"version":3}`), "correct horse")
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, dids.ErrWrongPassphrase)
+}
@@ -0,0 +1,204 @@
+package dids
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+// ===== errors =====
+
+var (
+	ErrLinkDepthExceeded  = fmt.Errorf("IPLD links nest deeper than the allowed depth")
+	ErrLinkBudgetExceeded = fmt.Errorf("IPLD links pull in more blocks or bytes than allowed")
+)
+
+// ===== constants =====
+
+const (
+	// how deep links are followed when no limit is given
+	DefaultMaxLinkDepth = 8
+
+	// how many distinct blocks one resolution may fetch when no limit is given
+	DefaultMaxLinkBlocks = 1024
+
+	// how many bytes of linked blocks one resolution may fetch when no limit is given
+	DefaultMaxLinkBytes = 8 << 20
+)
+
+// ===== link resolution =====
+
+// replaces every IPLD link (CID) in the data with the content it points to, recursively
+//
+// linked blocks are fetched with the given fetcher, checked against their CID and CBOR decoded. links inside
+// resolved content are followed too, up to maxDepth levels (DefaultMaxLinkDepth if <= 0), which also stops a
+// misbehaving fetcher from looping us forever. the input is left untouched
+//
+// each CID is fetched once per call and links repeated across the data share its resolved value. the total
+// fetched is capped at DefaultMaxLinkBlocks blocks and DefaultMaxLinkBytes bytes (see ResolveLinksWithBudget)
+//
+// signers wanting a sig that verifies with WithLinkResolver should sign over the output of this
+func ResolveLinks(ctx context.Context, data map[string]interface{}, fetch BlockFetcher, maxDepth int) (map[string]interface{}, error) {
+	return ResolveLinksWithBudget(ctx, data, fetch, maxDepth, 0, 0)
+}
+
+// ResolveLinks with a different cap on the distinct blocks and total bytes fetched, failing with
+// ErrLinkBudgetExceeded past either. maxBlocks and maxBytes <= 0 use DefaultMaxLinkBlocks and DefaultMaxLinkBytes
+func ResolveLinksWithBudget(
+	ctx context.Context,
+	data map[string]interface{},
+	fetch BlockFetcher,
+	maxDepth, maxBlocks, maxBytes int,
+) (map[string]interface{}, error) {
+	if fetch == nil {
+		return nil, fmt.Errorf("block fetcher cannot be nil")
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxLinkDepth
+	}
+	if maxBlocks <= 0 {
+		maxBlocks = DefaultMaxLinkBlocks
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxLinkBytes
+	}
+
+	r := &linkResolver{
+		fetch:      fetch,
+		blocksLeft: maxBlocks,
+		bytesLeft:  maxBytes,
+		decoded:    map[cid.Cid]interface{}{},
+		resolved:   map[resolvedLinkKey]interface{}{},
+	}
+	resolved, err := r.resolveIn(ctx, data, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// a link resolved with some depth left, since the same CID can be allowed less of its subtree deeper down
+type resolvedLinkKey struct {
+	c         cid.Cid
+	depthLeft int
+}
+
+// the state of one ResolveLinks call: what's been fetched so far and how much more may be
+type linkResolver struct {
+	fetch      BlockFetcher
+	blocksLeft int
+	bytesLeft  int
+
+	// checked and decoded blocks, by CID
+	decoded map[cid.Cid]interface{}
+
+	// fully resolved blocks, by CID and the depth they were resolved with
+	resolved map[resolvedLinkKey]interface{}
+}
+
+// walks a decoded value, swapping links for their content with depthLeft more links allowed on the way down
+func (r *linkResolver) resolveIn(ctx context.Context, value interface{}, depthLeft int) (interface{}, error) {
+	switch v := value.(type) {
+	case cid.Cid:
+		return r.resolveLink(ctx, v, depthLeft)
+
+	case *cid.Cid:
+		if v == nil {
+			return nil, nil
+		}
+		return r.resolveLink(ctx, *v, depthLeft)
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			resolved, err := r.resolveIn(ctx, elem, depthLeft)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			resolved, err := r.resolveIn(ctx, elem, depthLeft)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+
+	return value, nil
+}
+
+// resolves the links inside a single linked block, reusing an earlier resolution of it when there is one
+func (r *linkResolver) resolveLink(ctx context.Context, c cid.Cid, depthLeft int) (interface{}, error) {
+	if depthLeft <= 0 {
+		return nil, fmt.Errorf("%w: at link %s", ErrLinkDepthExceeded, c)
+	}
+
+	key := resolvedLinkKey{c: c, depthLeft: depthLeft}
+	if resolved, ok := r.resolved[key]; ok {
+		return resolved, nil
+	}
+
+	decoded, err := r.decodeLink(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := r.resolveIn(ctx, decoded, depthLeft-1)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[key] = resolved
+	return resolved, nil
+}
+
+// fetches, checks and decodes a single linked block, once per CID, charging it against the budget
+func (r *linkResolver) decodeLink(ctx context.Context, c cid.Cid) (interface{}, error) {
+	if decoded, ok := r.decoded[c]; ok {
+		return decoded, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.blocksLeft <= 0 {
+		return nil, fmt.Errorf("%w: block limit reached at link %s", ErrLinkBudgetExceeded, c)
+	}
+	r.blocksLeft--
+
+	data, err := r.fetch(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch linked block %s: %w", c, err)
+	}
+
+	if len(data) > r.bytesLeft {
+		return nil, fmt.Errorf("%w: byte limit reached at link %s", ErrLinkBudgetExceeded, c)
+	}
+	r.bytesLeft -= len(data)
+
+	// same check as VerifyByCID, so a store can't swap the linked content out
+	computed, err := c.Prefix().Sum(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash linked block %s: %w", c, err)
+	}
+	if !computed.Equals(c) {
+		return nil, fmt.Errorf("%w: %s", ErrCIDMismatch, c)
+	}
+
+	var decoded interface{}
+	if err := cbor.DecodeInto(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode linked block %s: %w", c, err)
+	}
+
+	r.decoded[c] = decoded
+	return decoded, nil
+}
@@ -0,0 +1,119 @@
+package dids_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+// mock "IPFS" store holding the given CBOR nodes
+func linkStore(nodes ...*cbor.Node) dids.BlockFetcher {
+	store := map[string][]byte{}
+	for _, node := range nodes {
+		store[node.Cid().String()] = node.RawData()
+	}
+	return func(ctx context.Context, c cid.Cid) ([]byte, error) {
+		data, ok := store[c.String()]
+		if !ok {
+			return nil, fmt.Errorf("not found")
+		}
+		return data, nil
+	}
+}
+
+func TestEthDIDVerifyWithLinkResolver(t *testing.T) {
+	child, err := cbor.WrapObject(map[string]interface{}{"to": "hive:bob", "amount": 5}, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	fetch := linkStore(child)
+
+	// the payload lives in its own block, linked from the signed one
+	data := map[string]interface{}{"op": "transfer", "payload": child.Cid()}
+	block := createCBORBlock(t, data)
+
+	resolved, err := dids.ResolveLinks(context.Background(), data, fetch, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"op":      "transfer",
+		"payload": map[string]interface{}{"to": "hive:bob", "amount": 5},
+	}, resolved)
+
+	// sign over the resolved view, which the verifier rebuilds through the same fetcher
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(resolved)
+	assert.Nil(t, err)
+
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	valid, err := did.VerifyWithOptions(block, sig, dids.WithLinkResolver(fetch, 0))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// links the fetcher can't resolve fail verification
+	valid, err = did.VerifyWithOptions(block, sig, dids.WithLinkResolver(linkStore(), 0))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+}
+
+func TestResolveLinksDepthLimit(t *testing.T) {
+	// a chain of links: root -> middle -> leaf
+	leaf, err := cbor.WrapObject(map[string]interface{}{"value": "leaf"}, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	middle, err := cbor.WrapObject(map[string]interface{}{"next": leaf.Cid()}, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	fetch := linkStore(leaf, middle)
+	root := map[string]interface{}{"next": middle.Cid()}
+
+	resolved, err := dids.ResolveLinks(context.Background(), root, fetch, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"next": map[string]interface{}{"next": map[string]interface{}{"value": "leaf"}},
+	}, resolved)
+
+	_, err = dids.ResolveLinks(context.Background(), root, fetch, 1)
+	assert.ErrorIs(t, err, dids.ErrLinkDepthExceeded)
+}
+
+func TestResolveLinksWideRepeatedDAG(t *testing.T) {
+	// every level links the level below 16 times, so a naive walk would do 16^6 fetches
+	const width, levels = 16, 6
+	node, err := cbor.WrapObject(map[string]interface{}{"value": "leaf"}, multihash.SHA2_256, -1)
+	assert.Nil(t, err)
+	nodes := []*cbor.Node{node}
+	for i := 0; i < levels; i++ {
+		links := make([]interface{}, width)
+		for j := range links {
+			links[j] = node.Cid()
+		}
+		node, err = cbor.WrapObject(map[string]interface{}{"children": links}, multihash.SHA2_256, -1)
+		assert.Nil(t, err)
+		nodes = append(nodes, node)
+	}
+
+	store := linkStore(nodes...)
+	fetches := map[string]int{}
+	fetch := func(ctx context.Context, c cid.Cid) ([]byte, error) {
+		fetches[c.String()]++
+		return store(ctx, c)
+	}
+	root := map[string]interface{}{"root": node.Cid()}
+
+	_, err = dids.ResolveLinks(context.Background(), root, fetch, levels+1)
+	assert.Nil(t, err)
+	assert.Len(t, fetches, len(nodes))
+	for c, count := range fetches {
+		assert.Equal(t, 1, count, c)
+	}
+
+	// the same DAG is over budget once its distinct blocks or bytes are
+	_, err = dids.ResolveLinksWithBudget(context.Background(), root, fetch, levels+1, len(nodes)-1, 0)
+	assert.ErrorIs(t, err, dids.ErrLinkBudgetExceeded)
+	_, err = dids.ResolveLinksWithBudget(context.Background(), root, fetch, levels+1, 0, 64)
+	assert.ErrorIs(t, err, dids.ErrLinkBudgetExceeded)
+}
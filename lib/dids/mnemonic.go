@@ -0,0 +1,107 @@
+package dids
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ===== constants =====
+
+// the BIP-44 path of the first Ethereum account, as wallets derive it
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+// ===== errors =====
+
+var (
+	ErrInvalidMnemonic       = fmt.Errorf("invalid mnemonic")
+	ErrInvalidDerivationPath = fmt.Errorf("invalid derivation path")
+)
+
+// ===== mnemonic-backed EthProvider =====
+
+// creates a provider signing with the key a BIP-39 mnemonic derives at a BIP-32 path (DefaultDerivationPath if
+// empty), the same key a wallet restored from the phrase would use
+//
+// the phrase's checksum is validated, and the path must be absolute (starting at "m/")
+func NewEthProviderFromMnemonic(mnemonic string, path string, opts ...ConvertOption) (*EthProvider, error) {
+	if path == "" {
+		path = DefaultDerivationPath
+	}
+	if !strings.HasPrefix(path, "m/") {
+		return nil, fmt.Errorf("%w: %q must start at m/", ErrInvalidDerivationPath, path)
+	}
+	derivationPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDerivationPath, err)
+	}
+
+	// the checksum error doesn't include the phrase, so it's safe to pass along
+	seed, err := bip39.NewSeedWithErrorChecking(strings.Join(strings.Fields(mnemonic), " "), "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	defer clear(seed)
+
+	privKey, err := deriveBIP32Key(seed, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewEthProviderFromKey(privKey, opts...), nil
+}
+
+// ===== utils =====
+
+// derives the secp256k1 private key at the path from a BIP-32 master seed
+func deriveBIP32Key(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	curveOrder := crypto.S256().Params().N
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	if key.Sign() == 0 || key.Cmp(curveOrder) >= 0 {
+		return nil, fmt.Errorf("seed derives an invalid master key")
+	}
+
+	for _, index := range path {
+		// hardened children commit to the private key, normal ones to the public key
+		var data []byte
+		if index >= 0x80000000 {
+			data = append([]byte{0x00}, crypto.FromECDSA(toECDSA(key))...)
+		} else {
+			data = crypto.CompressPubkey(&toECDSA(key).PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(curveOrder) >= 0 {
+			return nil, fmt.Errorf("%w: child %d is invalid", ErrInvalidDerivationPath, index)
+		}
+		key = tweak.Add(tweak, key).Mod(tweak, curveOrder)
+		if key.Sign() == 0 {
+			return nil, fmt.Errorf("%w: child %d is invalid", ErrInvalidDerivationPath, index)
+		}
+		chainCode = sum[32:]
+	}
+
+	return toECDSA(key), nil
+}
+
+// the private key for a scalar already known to be in range
+func toECDSA(key *big.Int) *ecdsa.PrivateKey {
+	privKey, _ := crypto.ToECDSA(key.FillBytes(make([]byte, 32)))
+	return privKey
+}
@@ -0,0 +1,57 @@
+package dids_test
+
+import (
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// the well known development mnemonic (hardhat, anvil) and the accounts wallets derive from it
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestNewEthProviderFromMnemonic(t *testing.T) {
+	for path, expected := range map[string]string{
+		"":                 "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"m/44'/60'/0'/0/0": "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"m/44'/60'/0'/0/1": "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	} {
+		provider, err := dids.NewEthProviderFromMnemonic(testMnemonic, path)
+		assert.Nil(t, err)
+
+		data := map[string]interface{}{"op": "transfer", "amount": 10}
+		sig, err := provider.SignData(data)
+		assert.Nil(t, err)
+		valid, err := dids.NewEthDID(expected).Verify(createCBORBlock(t, data), sig)
+		assert.Nil(t, err)
+		assert.True(t, valid, path)
+	}
+
+	// signs exactly like a provider built from the raw key
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	assert.Nil(t, err)
+	data := map[string]interface{}{"op": "stake", "amount": 3}
+	fromKey, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	provider, err := dids.NewEthProviderFromMnemonic(testMnemonic, "")
+	assert.Nil(t, err)
+	fromMnemonic, err := provider.SignData(data)
+	assert.Nil(t, err)
+	assert.Equal(t, fromKey, fromMnemonic)
+}
+
+func TestNewEthProviderFromMnemonicInvalid(t *testing.T) {
+	// bad checksum (last word changed)
+	_, err := dids.NewEthProviderFromMnemonic("test test test test test test test test test test test test", "")
+	assert.ErrorIs(t, err, dids.ErrInvalidMnemonic)
+
+	// not a word
+	_, err = dids.NewEthProviderFromMnemonic("test test test test test test test test test test test junkk", "")
+	assert.ErrorIs(t, err, dids.ErrInvalidMnemonic)
+
+	for _, path := range []string{"44'/60'/0'/0/0", "m/44'/60'/x", "m/44'/60'/0'/0/0/"} {
+		_, err = dids.NewEthProviderFromMnemonic(testMnemonic, path)
+		assert.ErrorIs(t, err, dids.ErrInvalidDerivationPath, path)
+	}
+}
@@ -0,0 +1,278 @@
+package dids
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ===== number normalization =====
+
+// with WithWholeFloatsAsInts, turns whole-valued floats into int64s so they're typed (int256) and encoded
+// exactly like the integers a different client would have sent, recursing into arrays
+//
+// floats with a fractional part, or outside int64's range, are left alone for the float handler
+func normalizeWholeFloats(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if i, ok := wholeFloatToInt(v); ok {
+			return i
+		}
+		return v
+	case float32:
+		if i, ok := wholeFloatToInt(float64(v)); ok {
+			return i
+		}
+		return v
+	}
+
+	// only arrays that can hold floats are rebuilt, so e.g. byte arrays keep their type
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return value
+	}
+	switch rv.Type().Elem().Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Interface:
+	default:
+		return value
+	}
+
+	normalized := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		normalized[i] = normalizeWholeFloats(rv.Index(i).Interface())
+	}
+	return normalized
+}
+
+// the float as an int64, if it is a whole number that fits in one
+func wholeFloatToInt(f float64) (int64, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, false
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, so that bound is exclusive
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// the float as an exact big int, if it is a whole number (of any size)
+func wholeFloatToBigInt(f float64) (*big.Int, bool) {
+	if isNonFinite(f) || f != math.Trunc(f) {
+		return nil, false
+	}
+	n, _ := new(big.Float).SetFloat64(f).Int(nil)
+	return n, true
+}
+
+// whether the float is NaN or ±Inf
+func isNonFinite(f float64) bool {
+	return math.IsNaN(f) || math.IsInf(f, 0)
+}
+
+// the float as an exact big int, rounded per the mode if it has a fractional part
+func roundFloat(f float64, rounding FloatRounding) (*big.Int, error) {
+	if isNonFinite(f) {
+		return nil, fmt.Errorf("%w: %v", ErrNonFiniteFloat, f)
+	}
+
+	switch rounding {
+	case FloatRoundingNearest:
+		f = math.Round(f)
+	case FloatRoundingError:
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%w: %v", ErrInexactFloat, f)
+		}
+	default:
+		f = math.Trunc(f)
+	}
+
+	n, _ := new(big.Float).SetFloat64(f).Int(nil)
+	return n, nil
+}
+
+// ===== JSON numbers =====
+
+// the largest integer every smaller one of which a float64 holds exactly
+const maxExactFloatInt = 1 << 53
+
+// decodes JSON into the generic form json.Unmarshal gives (maps, slices, float64 numbers and so on), except that
+// integers too big for a float64 to hold exactly become *big.Int, so a uint256 amount survives the trip
+//
+// every other number is the float64 it always was, so payloads whose numbers fit convert exactly as before
+func decodeJSONExact(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return exactJSONNumbers(decoded)
+}
+
+// swaps the json.Numbers in a decoded value for float64s, or *big.Ints for integers past maxExactFloatInt
+func exactJSONNumbers(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			n, ok := new(big.Int).SetString(v.String(), 10)
+			if ok && new(big.Int).Abs(n).Cmp(big.NewInt(maxExactFloatInt)) > 0 {
+				return n, nil
+			}
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		for key, elem := range v {
+			converted, err := exactJSONNumbers(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+	case []interface{}:
+		for i, elem := range v {
+			converted, err := exactJSONNumbers(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	}
+	return value, nil
+}
+
+// ===== common value types =====
+
+// converts values whose Go shape doesn't say what they are into something the converter types sensibly
+//
+// time.Time (a struct of unexported fields) becomes a timestamp or string per timeFormat, and big.Int and
+// big.Float values (by value or pointer) become *big.Int, which is typed by sign like the float handler's output.
+// a big.Float with a fractional part has no exact integer form, so it's an error. anything else is returned as is
+func normalizeCommonValue(value interface{}, timeFormat TimeFormat) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		if timeFormat == TimeFormatRFC3339 {
+			return v.UTC().Format(time.RFC3339Nano), nil
+		}
+		return big.NewInt(v.Unix()), nil
+	case *time.Time:
+		if v != nil {
+			return normalizeCommonValue(*v, timeFormat)
+		}
+	case big.Int:
+		return new(big.Int).Set(&v), nil
+	case big.Float:
+		return normalizeCommonValue(&v, timeFormat)
+	case *big.Float:
+		if v == nil {
+			return value, nil
+		}
+		if !v.IsInt() {
+			return nil, fmt.Errorf("big.Float %s has a fractional part", v.Text('g', -1))
+		}
+		n, _ := v.Int(nil)
+		return n, nil
+	}
+	return value, nil
+}
+
+// ===== maps =====
+
+// a map with string keys (like map[string]string or map[string]uint64) as the map[string]interface{} it converts
+// as, each key a field typed from its value. maps keyed by anything else have no field names, so aren't one
+func stringKeyedMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// ===== pointers =====
+
+// follows pointers down to the value they point at, so a `*string` converts like a `string` and a `*T` like a `T`
+//
+// *big.Int stays a pointer, since that's the form big numbers are typed from, as do Stringers when stringers is set
+// (their String method may need the pointer receiver). a nil pointer is returned as is
+func derefValue(value interface{}, stringers bool) interface{} {
+	for {
+		if _, ok := value.(*big.Int); ok {
+			return value
+		}
+		if _, ok := value.(fmt.Stringer); ok && stringers {
+			return value
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return value
+		}
+		value = rv.Elem().Interface()
+	}
+}
+
+// whether the value is an untyped nil or a nil pointer
+func isNilValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// applies the nil policy to an array's nil elements, returning the array to convert instead (or the same one when
+// it has none)
+//
+// omitting drops them, and zeroing swaps a nil pointer for what it points at's zero value (a nil *big.Int for 0).
+// an untyped nil (a JSON/CBOR null) has nothing to take a zero value of, so it still errors
+func applyNilPolicyToArray(arrayVal reflect.Value, fieldPath string, nilPolicy NilPolicy) (reflect.Value, error) {
+	hasNil := false
+	for i := 0; i < arrayVal.Len() && !hasNil; i++ {
+		hasNil = isNilValue(arrayVal.Index(i).Interface())
+	}
+	if !hasNil {
+		return arrayVal, nil
+	}
+
+	elems := make([]interface{}, 0, arrayVal.Len())
+	for i := 0; i < arrayVal.Len(); i++ {
+		elem := arrayVal.Index(i).Interface()
+		if !isNilValue(elem) {
+			elems = append(elems, elem)
+			continue
+		}
+
+		switch nilPolicy {
+		case NilPolicyOmit:
+			continue
+		case NilPolicyZero:
+			if elem == nil {
+				return reflect.Value{}, fmt.Errorf("%w for element of field '%s[%d]' has no type to take a zero value of", ErrNilValue, fieldPath, i)
+			}
+			if _, ok := elem.(*big.Int); ok {
+				elems = append(elems, new(big.Int))
+				continue
+			}
+			elems = append(elems, reflect.Zero(reflect.TypeOf(elem).Elem()).Interface())
+		default:
+			return reflect.Value{}, fmt.Errorf("%w for element of field '%s[%d]'", ErrNilValue, fieldPath, i)
+		}
+	}
+	return reflect.ValueOf(elems), nil
+}
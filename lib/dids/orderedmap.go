@@ -0,0 +1,180 @@
+package dids
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ===== OrderedMap =====
+
+// a map that remembers the order its keys were first set in
+//
+// ConvertToEIP712TypedData keeps that order for the struct's fields, rather than sorting them by name, so the
+// typed data matches a wallet or client that built its types in a specific order. the message JSON follows
+// the same order (see TypedData.MarshalJSON)
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]interface{})}
+}
+
+// sets a key's value, appending the key if it's new and keeping its position if it isn't
+func (m *OrderedMap) Set(key string, value interface{}) *OrderedMap {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+	return m
+}
+
+func (m OrderedMap) Get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// the keys, in insertion order
+func (m OrderedMap) Keys() []string {
+	return append([]string{}, m.keys...)
+}
+
+func (m OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// marshals as a JSON object with the keys in insertion order
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, key := range m.keys {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		if err := writeJSONField(&buffer, key, m.values[key]); err != nil {
+			return nil, err
+		}
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// ===== type-ordered message JSON =====
+
+// marshals a typed data message with each struct's fields in the order its type lists them
+//
+// for the usual name-sorted types this is the same as encoding/json's output. message fields missing from
+// the types (which would fail hashing anyway) follow the typed ones, sorted
+func marshalMessageJSON(types apitypes.Types, typeName string, message map[string]interface{}) ([]byte, error) {
+	if _, ok := types[typeName]; !ok {
+		return json.Marshal(message)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	written := make(map[string]bool, len(message))
+	for _, field := range types[typeName] {
+		value, ok := message[field.Name]
+		if !ok {
+			continue
+		}
+
+		encoded, err := marshalTypedValueJSON(types, field.Type, value)
+		if err != nil {
+			return nil, err
+		}
+		if len(written) > 0 {
+			buffer.WriteString(",")
+		}
+		if err := writeJSONField(&buffer, field.Name, json.RawMessage(encoded)); err != nil {
+			return nil, err
+		}
+		written[field.Name] = true
+	}
+
+	var rest []string
+	for key := range message {
+		if !written[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		if len(written) > 0 {
+			buffer.WriteString(",")
+		}
+		if err := writeJSONField(&buffer, key, message[key]); err != nil {
+			return nil, err
+		}
+		written[key] = true
+	}
+
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// marshals a single message value of the given EIP-712 type, ordering any structs inside it
+func marshalTypedValueJSON(types apitypes.Types, encType string, value interface{}) ([]byte, error) {
+	if strings.HasSuffix(encType, "]") {
+		elemType := encType[:strings.LastIndex(encType, "[")]
+		arrayVal := reflect.ValueOf(value)
+		if _, isStruct := types[baseTypeName(elemType)]; !isStruct || (arrayVal.Kind() != reflect.Slice && arrayVal.Kind() != reflect.Array) {
+			return json.Marshal(value)
+		}
+
+		elems := make([]json.RawMessage, arrayVal.Len())
+		for i := range elems {
+			encoded, err := marshalTypedValueJSON(types, elemType, arrayVal.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = encoded
+		}
+		return json.Marshal(elems)
+	}
+
+	if structValue, ok := value.(map[string]interface{}); ok {
+		if _, isStruct := types[encType]; isStruct {
+			return marshalMessageJSON(types, encType, structValue)
+		}
+	}
+	return json.Marshal(value)
+}
+
+// ===== utils =====
+
+// writes `"key":value` to the buffer
+func writeJSONField(buffer *bytes.Buffer, key string, value interface{}) error {
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buffer.Write(encodedKey)
+	buffer.WriteString(":")
+	buffer.Write(encodedValue)
+	return nil
+}
+
+// the ordered map a value holds, if it holds one (by value or pointer)
+func asOrderedMap(value interface{}) (*OrderedMap, bool) {
+	switch v := value.(type) {
+	case OrderedMap:
+		return &v, true
+	case *OrderedMap:
+		return v, v != nil
+	}
+	return nil, false
+}
@@ -0,0 +1,107 @@
+package dids
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== constants =====
+
+// did:pkh DIDs for an address on any eip155 (EVM) chain, as did:pkh:eip155:<chain id>:<address>
+const PkhDIDPrefix = "did:pkh:eip155:"
+
+// ===== interface assertions =====
+
+var _ IdentifiedDID[string] = PkhDID("")
+
+// ===== PkhDID =====
+
+// an address on a specific EVM chain, whose sigs are EIP-712 sigs like EthDID's but with the chain ID bound into
+// the domain (a `chainId` field), so a sig made for one chain doesn't verify on another
+//
+// chain 1 DIDs share EthDID's form, which vsc signs without a chain ID, so Parse keeps giving EthDIDs for those
+// (ParsePkhDID reads any chain as a PkhDID) and a chain 1 PkhDID verifies without a chain ID too, exactly like the
+// EthDID it's the same string as. an EthProvider created with WithChainID signs for a PkhDID on any other chain
+type PkhDID string
+
+// a valid address is stored EIP-55 checksummed, like NewEthDID, and anything else is kept as given (and won't
+// verify)
+func NewPkhDID(chainID uint64, address string) PkhDID {
+	if checksummed, err := checksumAddress(address); err == nil {
+		address = checksummed
+	}
+	return PkhDID(PkhDIDPrefix + strconv.FormatUint(chainID, 10) + ":" + address)
+}
+
+// parses did:pkh:eip155:<chain id>:<address>, for any chain ID (including 1)
+//
+// the chain ID must be plain decimal and the address a 0x-prefixed 20 byte hex one, which is stored checksummed
+func ParsePkhDID(did string) (PkhDID, error) {
+	if !strings.HasPrefix(did, PkhDIDPrefix) {
+		return "", fmt.Errorf("missing %q prefix", PkhDIDPrefix)
+	}
+
+	chain, address, found := strings.Cut(did[len(PkhDIDPrefix):], ":")
+	if !found {
+		return "", fmt.Errorf("missing chain ID or address in %q", did)
+	}
+	chainID, err := strconv.ParseUint(chain, 10, 64)
+	if err != nil || strconv.FormatUint(chainID, 10) != chain {
+		return "", fmt.Errorf("invalid chain ID %q", chain)
+	}
+	checksummed, err := checksumAddress(address)
+	if err != nil {
+		return "", err
+	}
+
+	return NewPkhDID(chainID, checksummed), nil
+}
+
+// the chain ID the DID is on, or 0 if it can't be read
+func (d PkhDID) ChainID() uint64 {
+	chain, _, _ := strings.Cut(strings.TrimPrefix(string(d), PkhDIDPrefix), ":")
+	chainID, err := strconv.ParseUint(chain, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return chainID
+}
+
+// ===== implementing the DID interface =====
+
+func (d PkhDID) String() string {
+	return string(d)
+}
+
+// the address part, like 0x123...
+func (d PkhDID) Identifier() string {
+	_, address, _ := strings.Cut(strings.TrimPrefix(string(d), PkhDIDPrefix), ":")
+	return address
+}
+
+func (d PkhDID) Verify(block blocks.Block, sig string) (bool, error) {
+	return d.VerifyWithOptions(block, sig)
+}
+
+// ===== other methods =====
+
+// verifies like EthDID.VerifyWithOptions, with the DID's chain ID added to the domain (unless it's chain 1, see
+// PkhDID)
+func (d PkhDID) VerifyWithOptions(block blocks.Block, sig string, opts ...VerifyOption) (bool, error) {
+	if _, err := ParsePkhDID(string(d)); err != nil {
+		return false, fmt.Errorf("invalid pkh DID %q: %w", d, err)
+	}
+
+	if !strings.HasPrefix(string(d), EthDIDPrefix) {
+		opts = append(opts, WithConvertOptions(WithChainID(d.ChainID())))
+	}
+	return d.ethDID().VerifyWithOptions(block, sig, opts...)
+}
+
+// the EthDID for the same address, which checks the sig once the domain has the chain ID
+func (d PkhDID) ethDID() EthDID {
+	return NewEthDID(d.Identifier())
+}
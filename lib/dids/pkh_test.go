@@ -0,0 +1,121 @@
+package dids_test
+
+import (
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePkhDID(t *testing.T) {
+	// the did:pkh:eip155:1:YYYYY form the real tx data uses, with a real address
+	address := "0x553cB1F25F8409360E081e5E015812D1Fb238d22"
+	pkhDID, err := dids.ParsePkhDID("did:pkh:eip155:1:" + strings.ToLower(address))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), pkhDID.ChainID())
+	assert.Equal(t, address, pkhDID.Identifier())
+	assert.Equal(t, "did:pkh:eip155:1:"+address, pkhDID.String())
+
+	// which Parse still reads as an EthDID
+	parsed, err := dids.ParseDID("did:pkh:eip155:1:" + address)
+	assert.Nil(t, err)
+	assert.Equal(t, dids.NewEthDID(address), parsed)
+
+	// other chains round trip as PkhDIDs
+	polygon := dids.NewPkhDID(137, strings.ToLower(address))
+	assert.Equal(t, "did:pkh:eip155:137:"+address, polygon.String())
+	parsed, err = dids.ParseDID(polygon.String())
+	assert.Nil(t, err)
+	assert.Equal(t, polygon, parsed)
+	assert.Equal(t, uint64(137), parsed.(dids.PkhDID).ChainID())
+
+	for _, malformed := range []string{
+		"did:pkh:eip155:137",
+		"did:pkh:eip155:0137:" + address,
+		"did:pkh:eip155:polygon:" + address,
+		"did:pkh:eip155:137:YYYYY",
+		"did:pkh:eip155:137:" + address + ":extra",
+	} {
+		_, err := dids.ParseDID(malformed)
+		assert.ErrorIs(t, err, dids.ErrMalformedDID, malformed)
+	}
+}
+
+func TestPkhDIDVerifyBindsChainID(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	sig, err := dids.NewEthProviderFromKey(privateKey, dids.WithChainID(137)).SignData(data)
+	assert.Nil(t, err)
+
+	valid, err := dids.NewPkhDID(137, address).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the same sig isn't valid on another chain, or without a chain ID at all
+	valid, err = dids.NewPkhDID(10, address).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	valid, err = dids.NewEthDID(address).Verify(block, sig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestSameAccount(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	ethDID := dids.NewEthDID(address)
+
+	assert.Equal(t, ethDID.String(), ethDID.ToPkh(1))
+	assert.Equal(t, "did:pkh:eip155:137:"+address, ethDID.ToPkh(137))
+
+	// every form of the same key's address is the same account
+	polygon, err := dids.ParseDID(ethDID.ToPkh(137))
+	assert.Nil(t, err)
+	assert.True(t, dids.SameAccount(ethDID, polygon))
+	assert.True(t, dids.SameAccount(ethDID, dids.EthDID("did:pkh:eip155:1:"+strings.ToLower(address))))
+	assert.True(t, dids.SameAccount(dids.NewPkhDID(10, address), polygon))
+
+	// a different address isn't
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	other := dids.NewEthDID(crypto.PubkeyToAddress(otherKey.PublicKey).Hex())
+	assert.False(t, dids.SameAccount(ethDID, other))
+	assert.False(t, dids.SameAccount(polygon, other))
+
+	// nor is anything that isn't an EVM address
+	keyDID, err := dids.NewSecp256k1KeyDID(&privateKey.PublicKey)
+	assert.Nil(t, err)
+	assert.False(t, dids.SameAccount(ethDID, keyDID))
+	assert.False(t, dids.SameAccount(ethDID, nil))
+}
+
+func TestPkhDIDChainOneMatchesEthDID(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	did := dids.EthDIDPrefix + crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	// the one chain 1 DID verifies the same sig whichever way it's parsed
+	parsed, err := dids.ParseDID(did)
+	assert.Nil(t, err)
+	pkhDID, err := dids.ParsePkhDID(did)
+	assert.Nil(t, err)
+	for _, d := range []dids.DID{parsed, pkhDID} {
+		valid, err := d.Verify(block, sig)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	}
+}
@@ -0,0 +1,79 @@
+package dids
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ===== pre-encoded sub-structs =====
+
+// a sub-struct that has already been converted, which the converter splices in as-is instead of re-converting
+//
+// its declared type is trusted, not checked against the value, and struct tag or path type overrides don't
+// apply to it. handy for large static sub-objects that get embedded in many payloads
+type PreEncodedStruct struct {
+	// the EIP-712 type name the field gets, e.g. "tx_container_v0.tx.payload"
+	TypeName string
+
+	// the type's fields, as they'd appear in the typed data's types
+	Fields []apitypes.Type
+
+	// the definitions of any struct types Fields reference, if there are nested ones
+	Nested apitypes.Types
+
+	// the already converted value
+	Message map[string]interface{}
+}
+
+// captures converted typed data as a sub-struct, typed as its primary type
+//
+// convert the sub-object with the primary type set to the type name it'll be embedded under (e.g.
+// "tx_container_v0.tx.payload") so it matches what a full conversion would produce
+func NewPreEncodedStruct(typedData TypedData) (PreEncodedStruct, error) {
+	typeName := typedData.Data.PrimaryType
+	fields, ok := typedData.Data.Types[typeName]
+	if !ok {
+		return PreEncodedStruct{}, fmt.Errorf("primary type %q is not defined in the typed data", typeName)
+	}
+
+	nested := apitypes.Types{}
+	for name, nestedFields := range typedData.Data.Types {
+		if name != typeName {
+			nested[name] = nestedFields
+		}
+	}
+
+	return PreEncodedStruct{
+		TypeName: typeName,
+		Fields:   fields,
+		Nested:   nested,
+		Message:  typedData.Data.Message,
+	}, nil
+}
+
+// ===== utils =====
+
+// the pre-encoded struct a field holds, if it holds one (by value or pointer)
+func asPreEncodedStruct(value interface{}) (*PreEncodedStruct, bool) {
+	switch v := value.(type) {
+	case PreEncodedStruct:
+		return &v, true
+	case *PreEncodedStruct:
+		return v, v != nil
+	}
+	return nil, false
+}
+
+// every type definition the struct brings along, its own included
+func (p *PreEncodedStruct) types() (apitypes.Types, error) {
+	if p.TypeName == "" {
+		return nil, fmt.Errorf("pre-encoded struct has no type name")
+	}
+
+	types := apitypes.Types{p.TypeName: p.Fields}
+	for name, fields := range p.Nested {
+		types[name] = fields
+	}
+	return types, nil
+}
@@ -0,0 +1,145 @@
+package dids
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+)
+
+// ===== errors =====
+
+var ErrUnsupportedPreHash = fmt.Errorf("unsupported pre-hash")
+
+// ===== message pre-hashing =====
+
+// how a (non-EVM) DID type prepares message bytes before handing them to its native signature scheme
+//
+// EthDIDs don't use this: they always sign the EIP-712 digest of the decoded block
+type PreHash int
+
+const (
+	// the scheme signs over the raw message bytes (pure ed25519, as KeyDIDs sign by default)
+	PreHashNone PreHash = iota
+	// the message is SHA-256 hashed first (ES256K-R, as secp256k1 KeyDIDs sign by default)
+	PreHashSHA256
+	// the message is SHA-512 hashed first (ed25519ph, RFC 8032)
+	PreHashSHA512
+	// the message is SHA-256 hashed twice (Bitcoin-style message signing)
+	PreHashDoubleSHA256
+)
+
+func (h PreHash) String() string {
+	switch h {
+	case PreHashNone:
+		return "none"
+	case PreHashSHA256:
+		return "sha256"
+	case PreHashSHA512:
+		return "sha512"
+	case PreHashDoubleSHA256:
+		return "double-sha256"
+	default:
+		return "unknown"
+	}
+}
+
+// prepares the message bytes that actually get passed to the signature scheme
+func (h PreHash) Apply(msg []byte) []byte {
+	switch h {
+	case PreHashSHA256:
+		digest := sha256.Sum256(msg)
+		return digest[:]
+	case PreHashSHA512:
+		digest := sha512.Sum512(msg)
+		return digest[:]
+	case PreHashDoubleSHA256:
+		first := sha256.Sum256(msg)
+		second := sha256.Sum256(first[:])
+		return second[:]
+	default:
+		return msg
+	}
+}
+
+// ===== JWS algs =====
+
+// a KeyDID JWS alg, and the key algorithm and pre-hash it stands for
+type jwsAlg struct {
+	name      string
+	algorithm KeyAlgorithm
+	preHash   PreHash
+}
+
+// every alg a KeyDID JWS can be signed with, the first for each key algorithm being its default
+//
+// the alg is in the signed header, so a sig can't be replayed under another pre-hash. ES256K-R-DSHA256 isn't a
+// registered JOSE alg, it's this package's name for ES256K-R over a double SHA-256 (Bitcoin-style) digest
+var jwsAlgs = []jwsAlg{
+	{"EdDSA", KeyAlgorithmEd25519, PreHashNone},
+	{"Ed25519ph", KeyAlgorithmEd25519, PreHashSHA512},
+	{"ES256K-R", KeyAlgorithmSecp256k1, PreHashSHA256},
+	{"ES256K-R-DSHA256", KeyAlgorithmSecp256k1, PreHashDoubleSHA256},
+}
+
+// the pre-hash a KeyDID JWS signed with the alg uses, if the alg is one for the key algorithm
+func jwsAlgPreHash(algorithm KeyAlgorithm, name string) (PreHash, error) {
+	for _, alg := range jwsAlgs {
+		if alg.name == name {
+			if alg.algorithm != algorithm {
+				return 0, fmt.Errorf("alg %q is not for %s keys", name, algorithm)
+			}
+			return alg.preHash, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported alg %q", name)
+}
+
+// the alg to sign a KeyDID JWS with, for the key algorithm and pre-hash
+func jwsAlgName(algorithm KeyAlgorithm, preHash PreHash) (string, error) {
+	for _, alg := range jwsAlgs {
+		if alg.algorithm == algorithm && alg.preHash == preHash {
+			return alg.name, nil
+		}
+	}
+	return "", fmt.Errorf("%w %s for %s keys", ErrUnsupportedPreHash, preHash, algorithm)
+}
+
+// the pre-hash a key algorithm signs with by default
+func defaultPreHash(algorithm KeyAlgorithm) PreHash {
+	for _, alg := range jwsAlgs {
+		if alg.algorithm == algorithm {
+			return alg.preHash
+		}
+	}
+	return PreHashNone
+}
+
+// ===== ed25519 =====
+
+// verifies an ed25519 sig over msg, prepared as per the pre-hash
+//
+// SHA-512 pre-hashing maps onto proper ed25519ph (with its domain separation), not pure ed25519 over a digest
+func verifyEd25519(pubKey ed25519.PublicKey, msg []byte, sig []byte, preHash PreHash) bool {
+	switch preHash {
+	case PreHashNone:
+		return ed25519.Verify(pubKey, msg, sig)
+	case PreHashSHA512:
+		return ed25519.VerifyWithOptions(pubKey, preHash.Apply(msg), sig, &ed25519.Options{Hash: crypto.SHA512}) == nil
+	default:
+		return false
+	}
+}
+
+// signs msg with ed25519, prepared as per the pre-hash (the mirror of verifyEd25519)
+func signEd25519(privKey ed25519.PrivateKey, msg []byte, preHash PreHash) ([]byte, error) {
+	switch preHash {
+	case PreHashNone:
+		return ed25519.Sign(privKey, msg), nil
+	case PreHashSHA512:
+		return privKey.Sign(nil, preHash.Apply(msg), &ed25519.Options{Hash: crypto.SHA512})
+	default:
+		return nil, fmt.Errorf("%w %s for ed25519 keys", ErrUnsupportedPreHash, preHash)
+	}
+}
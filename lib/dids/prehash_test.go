@@ -0,0 +1,186 @@
+package dids_test
+
+import (
+	gocrypto "crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// the header, signing input and raw sig of a KeyDID JWS
+func splitKeyDIDSig(t *testing.T, sig string) (string, []byte, []byte) {
+	parts := strings.Split(sig, ".")
+	assert.Len(t, parts, 3)
+	header, err := base64.StdEncoding.DecodeString(parts[0])
+	assert.Nil(t, err)
+	rawSig, err := base64.StdEncoding.DecodeString(parts[2])
+	assert.Nil(t, err)
+	return string(header), []byte(parts[0] + "." + parts[1]), rawSig
+}
+
+// the sig with its header's alg swapped, keeping the rest (and the now mismatched sig bytes) as they were
+func relabelAlg(t *testing.T, sig string, from string, to string) string {
+	parts := strings.Split(sig, ".")
+	header, err := base64.StdEncoding.DecodeString(parts[0])
+	assert.Nil(t, err)
+	relabelled := strings.Replace(string(header), `"alg":"`+from+`"`, `"alg":"`+to+`"`, 1)
+	assert.NotEqual(t, string(header), relabelled)
+	return base64.StdEncoding.EncodeToString([]byte(relabelled)) + "." + parts[1] + "." + parts[2]
+}
+
+func TestPreHashApply(t *testing.T) {
+	msg := []byte("hello vsc")
+
+	// none passes the bytes straight through
+	assert.Equal(t, msg, dids.PreHashNone.Apply(msg))
+
+	// sha256 (ES256K-R)
+	sha256Digest := sha256.Sum256(msg)
+	assert.Equal(t, sha256Digest[:], dids.PreHashSHA256.Apply(msg))
+
+	// sha512 (ed25519ph)
+	sha512Digest := sha512.Sum512(msg)
+	assert.Equal(t, sha512Digest[:], dids.PreHashSHA512.Apply(msg))
+
+	// double sha256 (Bitcoin)
+	second := sha256.Sum256(sha256Digest[:])
+	assert.Equal(t, second[:], dids.PreHashDoubleSHA256.Apply(msg))
+}
+
+func TestKeyDIDPreHash(t *testing.T) {
+	block := createDummyBlock([]byte("dummy data"))
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	did, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+
+	// ed25519 KeyDIDs are pure ed25519 by default
+	assert.Equal(t, dids.PreHashNone, did.(dids.KeyDID).PreHash())
+
+	sig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+
+	// the sig must be over the raw signing input, not some digest of it
+	header, signingInput, rawSig := splitKeyDIDSig(t, sig)
+	assert.Contains(t, header, `"alg":"EdDSA"`)
+	assert.True(t, ed25519.Verify(pubKey, signingInput, rawSig))
+	assert.False(t, ed25519.Verify(pubKey, dids.PreHashSHA512.Apply(signingInput), rawSig))
+
+	// ed25519ph signs the SHA-512 digest, with its own domain separation
+	phSig, err := dids.NewKeyProvider(privKey).WithPreHash(dids.PreHashSHA512).Sign(block)
+	assert.Nil(t, err)
+	header, signingInput, rawSig = splitKeyDIDSig(t, phSig)
+	assert.Contains(t, header, `"alg":"Ed25519ph"`)
+	assert.Nil(t, ed25519.VerifyWithOptions(pubKey, dids.PreHashSHA512.Apply(signingInput), rawSig, &ed25519.Options{Hash: gocrypto.SHA512}))
+	assert.False(t, ed25519.Verify(pubKey, signingInput, rawSig))
+
+	valid, err := did.Verify(block, phSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a sig doesn't verify under the other mode's alg
+	valid, err = did.Verify(block, relabelAlg(t, phSig, "Ed25519ph", "EdDSA"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+	valid, err = did.Verify(block, relabelAlg(t, sig, "EdDSA", "Ed25519ph"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	// nor under an alg for another kind of key
+	valid, err = did.Verify(block, relabelAlg(t, sig, "EdDSA", "ES256K-R"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	// and ed25519 keys have no SHA-256 modes to sign with
+	_, err = dids.NewKeyProvider(privKey).WithPreHash(dids.PreHashDoubleSHA256).Sign(block)
+	assert.ErrorIs(t, err, dids.ErrUnsupportedPreHash)
+}
+
+func TestSecp256k1KeyDIDPreHash(t *testing.T) {
+	block := createDummyBlock([]byte("dummy data"))
+	privKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+
+	did, err := dids.NewSecp256k1KeyDID(&privKey.PublicKey)
+	assert.Nil(t, err)
+
+	// secp256k1 KeyDIDs are ES256K-R, over SHA-256, by default
+	assert.Equal(t, dids.PreHashSHA256, did.PreHash())
+
+	sig, err := dids.NewSecp256k1KeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+	header, signingInput, rawSig := splitKeyDIDSig(t, sig)
+	assert.Contains(t, header, `"alg":"ES256K-R"`)
+	recovered, err := crypto.SigToPub(dids.PreHashSHA256.Apply(signingInput), rawSig)
+	assert.Nil(t, err)
+	assert.Equal(t, privKey.PublicKey, *recovered)
+
+	// Bitcoin-style signing hashes it twice
+	dshaSig, err := dids.NewSecp256k1KeyProvider(privKey).WithPreHash(dids.PreHashDoubleSHA256).Sign(block)
+	assert.Nil(t, err)
+	header, signingInput, rawSig = splitKeyDIDSig(t, dshaSig)
+	assert.Contains(t, header, `"alg":"ES256K-R-DSHA256"`)
+	recovered, err = crypto.SigToPub(dids.PreHashDoubleSHA256.Apply(signingInput), rawSig)
+	assert.Nil(t, err)
+	assert.Equal(t, privKey.PublicKey, *recovered)
+
+	valid, err := did.Verify(block, dshaSig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// a sig doesn't verify under the other mode's alg
+	valid, err = did.Verify(block, relabelAlg(t, dshaSig, "ES256K-R-DSHA256", "ES256K-R"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+	valid, err = did.Verify(block, relabelAlg(t, sig, "ES256K-R", "ES256K-R-DSHA256"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	// nor under an alg for another kind of key
+	valid, err = did.Verify(block, relabelAlg(t, sig, "ES256K-R", "EdDSA"))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	// and ECDSA can't sign unhashed or SHA-512 hashed input
+	for _, preHash := range []dids.PreHash{dids.PreHashNone, dids.PreHashSHA512} {
+		_, err = dids.NewSecp256k1KeyProvider(privKey).WithPreHash(preHash).Sign(block)
+		assert.ErrorIs(t, err, dids.ErrUnsupportedPreHash, preHash.String())
+	}
+}
+
+func TestBlsDIDPreHash(t *testing.T) {
+	block := createDummyBlock([]byte("dummy data"))
+	secretKey := big.NewInt(42)
+	provider, err := dids.NewBlsProvider(secretKey.FillBytes(make([]byte, 32)))
+	assert.Nil(t, err)
+	did, err := provider.DID()
+	assert.Nil(t, err)
+
+	// BlsDIDs hash the raw CID bytes to G2 themselves
+	assert.Equal(t, dids.PreHashNone, did.PreHash())
+
+	sig, err := provider.Sign(block)
+	assert.Nil(t, err)
+
+	sign := func(msg []byte) string {
+		point, err := bls12381.HashToG2(msg, []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"))
+		assert.Nil(t, err)
+		var sigPoint bls12381.G2Affine
+		sigPoint.ScalarMultiplication(&point, secretKey)
+		sigBytes := sigPoint.Bytes()
+		return base64.RawURLEncoding.EncodeToString(sigBytes[:])
+	}
+	assert.Equal(t, sign(block.Cid().Bytes()), sig)
+	assert.NotEqual(t, sign(dids.PreHashSHA256.Apply(block.Cid().Bytes())), sig)
+}
@@ -0,0 +1,61 @@
+package dids
+
+import (
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multicodec"
+	"github.com/multiformats/go-multihash"
+	"google.golang.org/protobuf/proto"
+)
+
+// ===== protobuf blocks =====
+//
+// protobuf messages are signed the same way as any other block: the block bytes are the message's encoding, and
+// the CID (codec 0x50, protobuf) commits to them. DIDs that sign the CID (KeyDID, BlsDID) work on these unchanged,
+// while EthDIDs, which sign the decoded dag-cbor payload, don't
+
+// encodes a protobuf message deterministically into a block, CIDed with sha2-256
+func EncodeProtoBlock(msg proto.Message) (blocks.Block, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protobuf message: %w", err)
+	}
+
+	c, err := cid.Prefix{
+		Version:  1,
+		Codec:    uint64(multicodec.Protobuf),
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}.Sum(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute CID: %w", err)
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// decodes a protobuf block into msg, rejecting blocks whose bytes don't hash to their CID
+//
+// a sig commits to the CID, so it's the received bytes that are checked against it, not a re-encoding of the
+// message. deterministic marshalling is only stable within one build of the protobuf library, so a block encoded
+// by another version (or another language) is still accepted as long as its CID is over exactly these bytes
+func DecodeProtoBlock(block blocks.Block, msg proto.Message) error {
+	c := block.Cid()
+	if codec := c.Prefix().Codec; codec != uint64(multicodec.Protobuf) {
+		return fmt.Errorf("block codec is 0x%x, not protobuf", codec)
+	}
+
+	computed, err := c.Prefix().Sum(block.RawData())
+	if err != nil {
+		return fmt.Errorf("failed to hash protobuf block: %w", err)
+	}
+	if !computed.Equals(c) {
+		return fmt.Errorf("%w: %s", ErrCIDMismatch, c)
+	}
+
+	if err := proto.Unmarshal(block.RawData(), msg); err != nil {
+		return fmt.Errorf("failed to decode protobuf message: %w", err)
+	}
+	return nil
+}
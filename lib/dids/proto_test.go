@@ -0,0 +1,114 @@
+package dids_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// a sample message with a map field, whose encoding order is only fixed when marshalling deterministically
+func protoTestMessage(t *testing.T) *structpb.Struct {
+	msg, err := structpb.NewStruct(map[string]interface{}{
+		"op":     "transfer",
+		"to":     "hive:bob",
+		"amount": 10,
+	})
+	assert.Nil(t, err)
+	return msg
+}
+
+func TestSignVerifyProtoBlock(t *testing.T) {
+	msg := protoTestMessage(t)
+
+	block, err := dids.EncodeProtoBlock(msg)
+	assert.Nil(t, err)
+
+	// the same message always gives the same block
+	again, err := dids.EncodeProtoBlock(msg)
+	assert.Nil(t, err)
+	assert.True(t, block.Cid().Equals(again.Cid()))
+
+	// sign and verify with the usual DID flow, for every DID that signs the CID
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	edDID, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+	edSig, err := dids.NewKeyProvider(privKey).Sign(block)
+	assert.Nil(t, err)
+
+	secpKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	secpDID, err := dids.NewSecp256k1KeyDID(&secpKey.PublicKey)
+	assert.Nil(t, err)
+	secpSig, err := dids.NewSecp256k1KeyProvider(secpKey).Sign(block)
+	assert.Nil(t, err)
+
+	blsProvider, blsDID := blsTestSigner(t)
+	blsSig, err := blsProvider.Sign(block)
+	assert.Nil(t, err)
+
+	for _, signed := range []struct {
+		did dids.DID
+		sig string
+	}{{edDID, edSig}, {secpDID, secpSig}, {blsDID, blsSig}} {
+		valid, err := signed.did.Verify(block, signed.sig)
+		assert.Nil(t, err, signed.did.String())
+		assert.True(t, valid, signed.did.String())
+	}
+
+	// EthDIDs sign the decoded dag-cbor payload, which a protobuf block doesn't have
+	ethKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	_, err = dids.NewEthProviderFromKey(ethKey).Sign(block)
+	assert.NotNil(t, err)
+	valid, err := dids.NewEthDID(crypto.PubkeyToAddress(ethKey.PublicKey).Hex()).Verify(block, edSig)
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+
+	// and the message decodes back out
+	decoded := &structpb.Struct{}
+	assert.Nil(t, dids.DecodeProtoBlock(block, decoded))
+	assert.True(t, proto.Equal(msg, decoded))
+}
+
+func TestDecodeProtoBlockChecksCID(t *testing.T) {
+	msg := protoTestMessage(t)
+	block, err := dids.EncodeProtoBlock(msg)
+	assert.Nil(t, err)
+
+	// the same message with its map entries in another order, as another encoder might write it. concatenated
+	// messages merge, so this is one entry per message in reverse key order
+	var reordered []byte
+	for _, key := range []string{"to", "op", "amount"} {
+		entry, err := proto.Marshal(&structpb.Struct{Fields: map[string]*structpb.Value{key: msg.Fields[key]}})
+		assert.Nil(t, err)
+		reordered = append(reordered, entry...)
+	}
+	assert.NotEqual(t, block.RawData(), reordered)
+
+	// it's a different block, but one whose CID is over its bytes, so it decodes to the same message
+	reorderedCID, err := block.Cid().Prefix().Sum(reordered)
+	assert.Nil(t, err)
+	reorderedBlock, err := blocks.NewBlockWithCid(reordered, reorderedCID)
+	assert.Nil(t, err)
+	decoded := &structpb.Struct{}
+	assert.Nil(t, dids.DecodeProtoBlock(reorderedBlock, decoded))
+	assert.True(t, proto.Equal(msg, decoded))
+
+	// bytes that parse but aren't what the CID commits to are refused
+	padded := append(append([]byte{}, block.RawData()...), block.RawData()...)
+	mismatched, err := blocks.NewBlockWithCid(padded, block.Cid())
+	assert.Nil(t, err)
+	assert.ErrorIs(t, dids.DecodeProtoBlock(mismatched, &structpb.Struct{}), dids.ErrCIDMismatch)
+
+	// as is a block that isn't protobuf at all
+	cborBlock := createCBORBlock(t, map[string]interface{}{"op": "transfer"})
+	assert.NotNil(t, dids.DecodeProtoBlock(cborBlock, &structpb.Struct{}))
+}
@@ -0,0 +1,148 @@
+package dids
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ===== constants =====
+
+// did:ethr DIDs name an Ethereum address (optionally under a network, e.g. did:ethr:mainnet:0x…), and parse
+// to the EthDID for it
+const EthrDIDPrefix = "did:ethr:"
+
+// ===== errors =====
+
+var (
+	ErrUnknownDIDMethod = fmt.Errorf("no registered DID method matches")
+	ErrMalformedDID     = fmt.Errorf("malformed DID")
+)
+
+// ===== registry =====
+
+// DID methods by the prefix their DIDs start with, seeded with the ones this package implements
+var (
+	didMethodsMu sync.RWMutex
+	didMethods   = map[string]func(string) (DID, error){
+		EthDIDPrefix:  parseEthDID,
+		EthrDIDPrefix: parseEthrDID,
+		KeyDIDPrefix:  parseKeyDID,
+		PkhDIDPrefix:  parsePkhDID,
+	}
+)
+
+// plugs a DID method into Parse, for DIDs starting with prefix (e.g. "did:test:")
+//
+// like database/sql.Register, this is meant to be called from init and panics on an empty prefix, a nil
+// parser or a prefix that is already registered
+func RegisterDIDMethod(prefix string, parser func(string) (DID, error)) {
+	if prefix == "" {
+		panic("dids: RegisterDIDMethod prefix is empty")
+	}
+	if parser == nil {
+		panic("dids: RegisterDIDMethod parser is nil for " + prefix)
+	}
+
+	didMethodsMu.Lock()
+	defer didMethodsMu.Unlock()
+
+	if _, dup := didMethods[prefix]; dup {
+		panic("dids: RegisterDIDMethod called twice for " + prefix)
+	}
+	didMethods[prefix] = parser
+}
+
+// parses a DID string with whichever registered method's prefix it starts with (the longest, if several do)
+//
+// a DID no method claims is an ErrUnknownDIDMethod, and one its method rejects is an ErrMalformedDID
+func Parse(did string) (DID, error) {
+	didMethodsMu.RLock()
+	var matched string
+	for prefix := range didMethods {
+		if strings.HasPrefix(did, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	parser := didMethods[matched]
+	didMethodsMu.RUnlock()
+
+	if parser == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDIDMethod, did)
+	}
+
+	parsed, err := parser(did)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedDID, err)
+	}
+	return parsed, nil
+}
+
+// the same as Parse, under the name that sits alongside NewEthDID and NewKeyDID
+func ParseDID(did string) (DID, error) {
+	return Parse(did)
+}
+
+// the prefixes of every registered DID method, sorted
+func registeredDIDMethods() []string {
+	didMethodsMu.RLock()
+	defer didMethodsMu.RUnlock()
+
+	prefixes := make([]string, 0, len(didMethods))
+	for prefix := range didMethods {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// ===== built-in parsers =====
+
+func parseEthDID(did string) (DID, error) {
+	ethDID, err := NewEthDIDChecked(strings.TrimPrefix(did, EthDIDPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid eth DID %q: %w", did, err)
+	}
+	return ethDID, nil
+}
+
+// chain 1 never gets here, since EthDIDPrefix is the longer match
+func parsePkhDID(did string) (DID, error) {
+	pkhDID, err := ParsePkhDID(did)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkh DID %q: %w", did, err)
+	}
+	return pkhDID, nil
+}
+
+// only mainnet did:ethr DIDs are accepted, since EthDIDs are chain 1
+func parseEthrDID(did string) (DID, error) {
+	address := strings.TrimPrefix(did, EthrDIDPrefix)
+	for _, network := range []string{"mainnet:", "0x1:"} {
+		address = strings.TrimPrefix(address, network)
+	}
+	if strings.Contains(address, ":") {
+		return nil, fmt.Errorf("ethr DID %q isn't on mainnet", did)
+	}
+
+	ethDID, err := NewEthDIDChecked(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ethr DID %q: %w", did, err)
+	}
+	return ethDID, nil
+}
+
+func parseKeyDID(did string) (DID, error) {
+	algorithm, _, err := KeyDID(did).KeyMaterial()
+	if err != nil {
+		return nil, fmt.Errorf("invalid key DID %q: %w", did, err)
+	}
+	if algorithm == KeyAlgorithmBLS12381G1 {
+		if _, err := BlsDID(did).pubKey(); err != nil {
+			return nil, fmt.Errorf("invalid key DID %q: %w", did, err)
+		}
+		return BlsDID(did), nil
+	}
+	return KeyDID(did), nil
+}
@@ -0,0 +1,107 @@
+package dids_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"vsc-node/lib/dids"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/assert"
+)
+
+// a dummy DID method that accepts any "ok" sig
+type testDID string
+
+func (d testDID) String() string {
+	return string(d)
+}
+
+func (d testDID) Verify(block blocks.Block, sig string) (bool, error) {
+	return sig == "ok", nil
+}
+
+func TestRegisterDIDMethod(t *testing.T) {
+	dids.RegisterDIDMethod("did:test:", func(did string) (dids.DID, error) {
+		if strings.TrimPrefix(did, "did:test:") == "" {
+			return nil, fmt.Errorf("empty test DID")
+		}
+		return testDID(did), nil
+	})
+
+	did, err := dids.Parse("did:test:alice")
+	assert.Nil(t, err)
+	assert.Equal(t, testDID("did:test:alice"), did)
+	assert.Contains(t, dids.SupportedDIDMethods(), "did:test:")
+
+	// the method's own validation still applies
+	_, err = dids.Parse("did:test:")
+	assert.NotNil(t, err)
+
+	// a second registration of the same prefix is refused
+	assert.Panics(t, func() {
+		dids.RegisterDIDMethod("did:test:", func(did string) (dids.DID, error) { return nil, nil })
+	})
+
+	// parsing is safe alongside registration
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			dids.RegisterDIDMethod(fmt.Sprintf("did:test%d:", i), func(did string) (dids.DID, error) { return testDID(did), nil })
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := dids.Parse("did:test:bob")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseBuiltinDIDs(t *testing.T) {
+	ethDID, err := dids.Parse("did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22")
+	assert.Nil(t, err)
+	assert.Equal(t, dids.NewEthDID("0x553Cb1F25f8409360E081E5e015812d1FB238d22"), ethDID)
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	keyDID, err := dids.NewKeyDID(pubKey)
+	assert.Nil(t, err)
+	parsed, err := dids.Parse(keyDID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, keyDID, parsed)
+
+	// malformed or unknown DIDs are rejected
+	_, err = dids.Parse("did:pkh:eip155:1:0x1234")
+	assert.ErrorIs(t, err, dids.ErrMalformedDID)
+	_, err = dids.Parse("did:key:zNope")
+	assert.ErrorIs(t, err, dids.ErrMalformedDID)
+	_, err = dids.Parse("did:web:example.com")
+	assert.ErrorIs(t, err, dids.ErrUnknownDIDMethod)
+}
+
+func TestParseDIDEthr(t *testing.T) {
+	expected := dids.NewEthDID("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+
+	for _, did := range []string{
+		"did:ethr:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"did:ethr:mainnet:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"did:ethr:0x1:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	} {
+		parsed, err := dids.ParseDID(did)
+		assert.Nil(t, err, did)
+		assert.Equal(t, expected, parsed)
+	}
+
+	_, err := dids.ParseDID("did:ethr:goerli:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	assert.ErrorIs(t, err, dids.ErrMalformedDID)
+	_, err = dids.ParseDID("did:ethr:0x5aaeb6")
+	assert.ErrorIs(t, err, dids.ErrMalformedDID)
+	_, err = dids.ParseDID("did:ethr:0x5aaeb6")
+	assert.ErrorIs(t, err, dids.ErrInvalidAddress)
+}
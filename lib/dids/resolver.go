@@ -0,0 +1,132 @@
+package dids
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ===== constants =====
+
+// the most DIDs a ResolverCache holds unless WithMaxEntries says otherwise
+const DefaultResolverCacheSize = 10000
+
+// ===== types =====
+
+// memoizes ParseDID by DID string, for hot paths that see the same signers over and over
+//
+// entries expire ttl after they were parsed, and once the cache is full the least recently used one is evicted.
+// failed parses aren't cached. safe for concurrent use
+type ResolverCache struct {
+	mu sync.Mutex
+
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	// most recently used at the front
+	order   *list.List
+	entries map[string]*list.Element
+
+	hits   int
+	misses int
+}
+
+type resolverCacheEntry struct {
+	key       string
+	did       DID
+	expiresAt time.Time
+}
+
+// tweaks a ResolverCache
+type ResolverCacheOption func(*ResolverCache)
+
+// ===== constructor + options =====
+
+func NewResolverCache(ttl time.Duration, opts ...ResolverCacheOption) *ResolverCache {
+	c := &ResolverCache{
+		ttl:        ttl,
+		maxEntries: DefaultResolverCacheSize,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// caps how many DIDs the cache holds (at least 1)
+func WithMaxEntries(maxEntries int) ResolverCacheOption {
+	return func(c *ResolverCache) {
+		c.maxEntries = max(maxEntries, 1)
+	}
+}
+
+// the clock entries expire by, time.Now by default
+func WithResolverClock(now func() time.Time) ResolverCacheOption {
+	return func(c *ResolverCache) {
+		c.now = now
+	}
+}
+
+// ===== resolving =====
+
+// the parsed DID for the string, from the cache while its entry is fresh and from ParseDID otherwise
+func (c *ResolverCache) Resolve(did string) (DID, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[did]; ok {
+		entry := elem.Value.(*resolverCacheEntry)
+		if c.now().Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.hits++
+			c.mu.Unlock()
+			return entry.did, nil
+		}
+		c.remove(elem)
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// parsed outside the lock, so a slow parser doesn't hold up hits on other DIDs
+	parsed, err := ParseDID(did)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// another caller may have stored it meanwhile, which is the same DID either way
+	if elem, ok := c.entries[did]; ok {
+		c.remove(elem)
+	}
+	c.entries[did] = c.order.PushFront(&resolverCacheEntry{key: did, did: parsed, expiresAt: c.now().Add(c.ttl)})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return parsed, nil
+}
+
+// the number of entries held, including expired ones not yet looked up again
+func (c *ResolverCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// how many Resolve calls were answered from the cache, and how many had to parse
+func (c *ResolverCache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// ===== utils =====
+
+// drops an entry, with the lock held
+func (c *ResolverCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*resolverCacheEntry).key)
+}
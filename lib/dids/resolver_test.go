@@ -0,0 +1,111 @@
+package dids_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"vsc-node/lib/dids"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counts how often the cache falls through to parsing
+var cacheTestParses atomic.Int64
+
+func init() {
+	dids.RegisterDIDMethod("did:cachetest:", func(did string) (dids.DID, error) {
+		cacheTestParses.Add(1)
+		return testDID(did), nil
+	})
+}
+
+func TestResolverCacheHits(t *testing.T) {
+	cache := dids.NewResolverCache(time.Minute)
+	before := cacheTestParses.Load()
+
+	for i := 0; i < 5; i++ {
+		did, err := cache.Resolve("did:cachetest:alice")
+		assert.Nil(t, err)
+		assert.Equal(t, testDID("did:cachetest:alice"), did)
+	}
+	assert.Equal(t, int64(1), cacheTestParses.Load()-before)
+
+	hits, misses := cache.Stats()
+	assert.Equal(t, 4, hits)
+	assert.Equal(t, 1, misses)
+
+	// built in methods resolve the same way
+	ethDID, err := cache.Resolve("did:pkh:eip155:1:0x553Cb1F25f8409360E081E5e015812d1FB238d22")
+	assert.Nil(t, err)
+	assert.Equal(t, dids.NewEthDID("0x553Cb1F25f8409360E081E5e015812d1FB238d22"), ethDID)
+
+	// failures aren't cached
+	_, err = cache.Resolve("did:unknown:alice")
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestResolverCacheExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := dids.NewResolverCache(time.Minute, dids.WithResolverClock(func() time.Time { return now }))
+	before := cacheTestParses.Load()
+
+	_, err := cache.Resolve("did:cachetest:bob")
+	assert.Nil(t, err)
+
+	now = now.Add(59 * time.Second)
+	_, err = cache.Resolve("did:cachetest:bob")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cacheTestParses.Load()-before)
+
+	// past the TTL it's parsed again
+	now = now.Add(time.Second)
+	_, err = cache.Resolve("did:cachetest:bob")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), cacheTestParses.Load()-before)
+}
+
+func TestResolverCacheEviction(t *testing.T) {
+	cache := dids.NewResolverCache(time.Minute, dids.WithMaxEntries(2))
+	before := cacheTestParses.Load()
+
+	for _, name := range []string{"a", "b", "a", "c"} {
+		_, err := cache.Resolve("did:cachetest:" + name)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, int64(3), cacheTestParses.Load()-before)
+
+	// b was the least recently used, so it went and a stayed
+	_, err := cache.Resolve("did:cachetest:a")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), cacheTestParses.Load()-before)
+	_, err = cache.Resolve("did:cachetest:b")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), cacheTestParses.Load()-before)
+}
+
+func TestResolverCacheConcurrent(t *testing.T) {
+	cache := dids.NewResolverCache(time.Minute, dids.WithMaxEntries(8))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				did := fmt.Sprintf("did:cachetest:concurrent%d", (i+j)%12)
+				resolved, err := cache.Resolve(did)
+				assert.Nil(t, err)
+				assert.Equal(t, testDID(did), resolved)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, cache.Len(), 8)
+	hits, misses := cache.Stats()
+	assert.Equal(t, 1600, hits+misses)
+}
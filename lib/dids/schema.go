@@ -0,0 +1,40 @@
+package dids
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ===== schema export =====
+
+// converts an example payload and returns just its schema as JSON: the types, primary type and domain (in the
+// same layout TypedData.MarshalJSON uses), with no message
+//
+// meant for docs and client codegen, where the types matter and the example's values don't
+func SchemaOnly(
+	domainName string,
+	data interface{},
+	primaryTypeName string,
+	floatHandler func(float64) (*big.Int, error),
+	opts ...ConvertOption,
+) ([]byte, error) {
+	typedData, err := ConvertToEIP712TypedData(domainName, data, primaryTypeName, floatHandler, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := struct {
+		Types        apitypes.Types         `json:"types"`
+		PrimaryType  string                 `json:"primaryType"`
+		Domain       map[string]interface{} `json:"domain"`
+		EIP712Domain []apitypes.Type        `json:"EIP712Domain"`
+	}{
+		Types:        typedData.Data.Types,
+		PrimaryType:  typedData.Data.PrimaryType,
+		Domain:       domainJSON(typedData.Data.Domain),
+		EIP712Domain: domainTypes(typedData.Data.Domain),
+	}
+	return json.Marshal(schema)
+}
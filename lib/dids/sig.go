@@ -0,0 +1,182 @@
+package dids
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ===== errors =====
+
+// a sig that can't be a sig at all: empty, not hex, or the wrong length
+var ErrMalformedSignature = fmt.Errorf("malformed signature")
+
+// a sig whose EIP-155 style v was made for another chain than the one being verified for
+var ErrSignatureChainID = fmt.Errorf("signature v is for a different chain")
+
+// ===== signature encodings =====
+
+// how a sig string given to EthDID.Verify is encoded
+type SignatureEncoding int
+
+const (
+	// hex if the sig is valid hex (0x-prefixed or not), else base64 in any of its forms
+	SignatureEncodingAuto SignatureEncoding = iota
+	// hex only, 0x-prefixed or not
+	SignatureEncodingHex
+	// base64 only: standard or URL alphabet, padded or not
+	SignatureEncodingBase64
+)
+
+// ===== signature formatting =====
+
+// returns the canonical form of a hex sig: lowercase and 0x-prefixed
+//
+// sigs arrive with or without the 0x prefix and in either case, so anything comparing or caching
+// sigs should key on this form instead of the raw input
+func CanonicalSigHex(sig string) (string, error) {
+	sigBytes, err := decodeSigHex(sig, false)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(sigBytes), nil
+}
+
+// decodes a hex sig
+//
+// by default surrounding whitespace is trimmed and a 0x or 0X prefix is dropped. when strict, the sig
+// must be bare hex or carry a lowercase 0x prefix, with nothing around it
+func decodeSigHex(sig string, strict bool) ([]byte, error) {
+	var trimmed string
+	if strict {
+		trimmed = strings.TrimPrefix(sig, "0x")
+	} else {
+		trimmed = normalizeHexInput(sig)
+	}
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w: signature is empty", ErrMalformedSignature)
+	}
+
+	sigBytes, err := hex.DecodeString(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not valid hex: %v", ErrMalformedSignature, err)
+	}
+	return sigBytes, nil
+}
+
+// decodes a sig in the given encoding
+//
+// strict inputs (see WithStrictInputs) have no room for guessing, so auto detection only takes hex for them
+func decodeSig(sig string, encoding SignatureEncoding, strict bool) ([]byte, error) {
+	switch encoding {
+	case SignatureEncodingHex:
+		return decodeSigHex(sig, strict)
+	case SignatureEncodingBase64:
+		return decodeSigBase64(sig)
+	}
+
+	// hex first, since hex digits are all valid base64 too (though as base64 they'd decode to the wrong length)
+	sigBytes, err := decodeSigHex(sig, strict)
+	if err == nil || strict {
+		return sigBytes, err
+	}
+	if sigBytes, base64Err := decodeSigBase64(sig); base64Err == nil {
+		return sigBytes, nil
+	}
+	return nil, err
+}
+
+// decodes a base64 sig in the standard or URL alphabet, with or without padding, ignoring surrounding whitespace
+func decodeSigBase64(sig string) ([]byte, error) {
+	trimmed := strings.TrimSpace(sig)
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w: signature is empty", ErrMalformedSignature)
+	}
+
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(trimmed, "-_") {
+		encoding = base64.URLEncoding
+	}
+	if !strings.HasSuffix(trimmed, "=") {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+
+	sigBytes, err := encoding.DecodeString(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not valid base64: %v", ErrMalformedSignature, err)
+	}
+	return sigBytes, nil
+}
+
+// trims surrounding whitespace and drops a 0x or 0X prefix, leaving the bare hex digits
+func normalizeHexInput(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return s
+}
+
+// normalizes an ECDSA sig to the 65 byte [R || S || V] form with V as 0 or 1, as crypto.SigToPub wants it
+//
+// accepts a V of 27/28 (as most wallets produce) and 64 byte EIP-2098 compact sigs, whose top bit of s is the V.
+// when the chain ID the sig is for is known (non-nil), an EIP-155 style V of chainId*2 + 35/36 is accepted too
+func recoverableSig(sigBytes []byte, chainID *big.Int) ([]byte, error) {
+	switch len(sigBytes) {
+	case 65:
+		recoveryID, err := sigRecoveryID(sigBytes[64], chainID)
+		if err != nil {
+			return nil, err
+		}
+		normalized := append([]byte{}, sigBytes...)
+		normalized[64] = recoveryID
+		return normalized, nil
+
+	case 64:
+		normalized := make([]byte, 65)
+		copy(normalized, sigBytes)
+		normalized[64] = normalized[32] >> 7
+		normalized[32] &= 0x7f
+		return normalized, nil
+	}
+
+	return nil, fmt.Errorf("%w: signature must be 64 or 65 bytes, got %d", ErrMalformedSignature, len(sigBytes))
+}
+
+// the 0/1 recovery id a sig's V stands for
+//
+// an EIP-155 V encodes a chain ID, which must be the one given. a V that big only fits in the sig's one byte for
+// chain IDs up to 110, so that's as far as these go
+func sigRecoveryID(v byte, chainID *big.Int) (byte, error) {
+	switch {
+	case v <= 1:
+		return v, nil
+	case v == 27 || v == 28:
+		return v - 27, nil
+	case v >= 35:
+		if chainID == nil {
+			return 0, fmt.Errorf("%w: recovery id %d is EIP-155 style, but there's no chain ID to check it against", ErrMalformedSignature, v)
+		}
+		sigChainID := uint64(v-35) / 2
+		if !chainID.IsUint64() || chainID.Uint64() != sigChainID {
+			return 0, fmt.Errorf("%w: v %d is for chain %d, not chain %s", ErrSignatureChainID, v, sigChainID, chainID)
+		}
+		return (v - 35) % 2, nil
+	}
+	return 0, fmt.Errorf("%w: invalid signature recovery id %d", ErrMalformedSignature, v)
+}
+
+// ===== comparisons =====
+//
+// checks of attacker-influenced bytes against an expected value that decides the outcome (the EIP-1271 magic
+// value a contract wallet returns, a revealed commitment's hash) go through constantTimeEqual, so how long they
+// take says nothing about how much of the value matched. comparisons of values that are public anyway (the
+// recovered vs expected address, the EIP-6492 suffix, a wallet's EIP-712 hash, canonical encodings) stay plain
+
+// whether a and b are equal, in time that depends only on their lengths
+func constantTimeEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
@@ -0,0 +1,173 @@
+package dids_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalSigHex(t *testing.T) {
+	equivalent := []string{
+		"abcdef0123",
+		"ABCDEF0123",
+		"0xabcdef0123",
+		"0xABCDEF0123",
+		"0XAbCdEf0123",
+		"  0xabcdef0123\n",
+		"\tABCDEF0123 ",
+	}
+
+	for _, sig := range equivalent {
+		canonical, err := dids.CanonicalSigHex(sig)
+		assert.Nil(t, err)
+		assert.Equal(t, "0xabcdef0123", canonical)
+	}
+
+	// invalid inputs are rejected rather than "canonicalized"
+	for _, sig := range []string{"", "0x", "  ", "xyz", "abc", "0x 0xabcdef"} {
+		_, err := dids.CanonicalSigHex(sig)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestEthDIDVerifyMalformedSignature(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	for name, malformed := range map[string]string{
+		"empty":          "",
+		"odd length":     sig[:129],
+		"non-hex":        "zz" + sig[2:],
+		"truncated":      sig[:100],
+		"too long":       sig + "00",
+		"bad recovery":   sig[:128] + "05",
+		"just 0x prefix": "0x",
+	} {
+		valid, err := ethDID.Verify(block, malformed)
+		assert.ErrorIs(t, err, dids.ErrMalformedSignature, name)
+		assert.False(t, valid, name)
+	}
+
+	// wallet style v of 27/28 and EIP-2098 compact sigs are well formed
+	sigBytes, err := hex.DecodeString(sig)
+	assert.Nil(t, err)
+	walletSig := append([]byte{}, sigBytes...)
+	walletSig[64] += 27
+	valid, err := ethDID.Verify(block, hex.EncodeToString(walletSig))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	compact := append([]byte{}, sigBytes[:64]...)
+	compact[32] |= sigBytes[64] << 7
+	valid, err = ethDID.Verify(block, hex.EncodeToString(compact))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+}
+
+func TestEthDIDVerifySignatureEncodings(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ethDID := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	sigBytes, err := hex.DecodeString(sig)
+	assert.Nil(t, err)
+
+	// a different key's sig, to check every encoding gives the same (negative) answer too
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	otherSig, err := dids.NewEthProviderFromKey(otherKey).SignData(data)
+	assert.Nil(t, err)
+	otherSigBytes, err := hex.DecodeString(otherSig)
+	assert.Nil(t, err)
+
+	encodings := map[string]func([]byte) string{
+		"plain hex":      hex.EncodeToString,
+		"0x hex":         func(b []byte) string { return "0x" + hex.EncodeToString(b) },
+		"std base64":     base64.StdEncoding.EncodeToString,
+		"raw std base64": base64.RawStdEncoding.EncodeToString,
+		"url base64":     base64.URLEncoding.EncodeToString,
+		"raw url base64": base64.RawURLEncoding.EncodeToString,
+	}
+	for name, encode := range encodings {
+		valid, err := ethDID.Verify(block, encode(sigBytes))
+		assert.Nil(t, err, name)
+		assert.True(t, valid, name)
+
+		valid, err = ethDID.Verify(block, encode(otherSigBytes))
+		assert.Nil(t, err, name)
+		assert.False(t, valid, name)
+	}
+
+	// pinning the encoding still reads the matching form
+	valid, err := ethDID.VerifyWithOptions(block, base64.StdEncoding.EncodeToString(sigBytes), dids.WithSignatureEncoding(dids.SignatureEncodingBase64))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	valid, err = ethDID.VerifyWithOptions(block, "0x"+sig, dids.WithSignatureEncoding(dids.SignatureEncodingHex))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// but not the other one
+	_, err = ethDID.VerifyWithOptions(block, base64.StdEncoding.EncodeToString(sigBytes), dids.WithSignatureEncoding(dids.SignatureEncodingHex))
+	assert.ErrorIs(t, err, dids.ErrMalformedSignature)
+	_, err = ethDID.VerifyWithOptions(block, sig, dids.WithSignatureEncoding(dids.SignatureEncodingBase64))
+	assert.ErrorIs(t, err, dids.ErrMalformedSignature)
+}
+
+func TestEthDIDVerifyEIP155V(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	sig, err := dids.NewEthProviderFromKey(privateKey, dids.WithChainID(5)).SignData(data)
+	assert.Nil(t, err)
+	sigBytes, err := hex.DecodeString(sig)
+	assert.Nil(t, err)
+	recoveryID := sigBytes[64]
+
+	withV := func(v byte) string {
+		return hex.EncodeToString(append(append([]byte{}, sigBytes[:64]...), v))
+	}
+	pkhDID := dids.NewPkhDID(5, address)
+
+	// 0/1, 27/28 and chainId*2 + 35/36 all say the same thing for chain 5
+	for _, v := range []byte{recoveryID, recoveryID + 27, 5*2 + 35 + recoveryID} {
+		valid, err := pkhDID.Verify(block, withV(v))
+		assert.Nil(t, err, v)
+		assert.True(t, valid, v)
+	}
+
+	// the same goes for an EthDID given the chain ID, and for recovery
+	chainOpt := dids.WithConvertOptions(dids.WithChainID(5))
+	valid, err := dids.NewEthDID(address).VerifyWithOptions(block, withV(5*2+35+recoveryID), chainOpt)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	recovered, err := dids.RecoverSigner(block, withV(5*2+35+recoveryID), chainOpt)
+	assert.Nil(t, err)
+	assert.Equal(t, address, recovered)
+
+	// an EIP-155 v for another chain is rejected, not recovered to some other address
+	valid, err = pkhDID.Verify(block, withV(1*2+35+recoveryID))
+	assert.ErrorIs(t, err, dids.ErrSignatureChainID)
+	assert.False(t, valid)
+
+	// and without a chain ID there's nothing to check one against
+	valid, err = dids.NewEthDID(address).Verify(block, withV(1*2+35+recoveryID))
+	assert.ErrorIs(t, err, dids.ErrMalformedSignature)
+	assert.False(t, valid)
+}
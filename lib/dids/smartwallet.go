@@ -0,0 +1,166 @@
+package dids
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ===== constants =====
+
+// suffix marking a sig as an EIP-6492 wrapped sig for a counterfactual (not yet deployed) smart wallet
+//
+// ref: https://eips.ethereum.org/EIPS/eip-6492
+var eip6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+// both the selector of `isValidSignature(bytes32,bytes)` and the value a contract wallet returns from it for a valid sig
+//
+// ref: https://eips.ethereum.org/EIPS/eip-1271
+var eip1271MagicValue = common.FromHex("0x1626ba7e")
+
+// ===== errors =====
+
+var ErrNoCounterfactualValidator = fmt.Errorf("EIP-6492 signature given but no counterfactual validator configured")
+
+// ===== types =====
+
+// simulates deploying a counterfactual smart wallet and then validating a sig against it, all in one state
+//
+// a node-backed implementation would eth_call the EIP-6492 universal off-chain validator, which runs the
+// factory call and then `isValidSignature(bytes32,bytes)` on the resulting wallet
+type CounterfactualValidator interface {
+	ValidateCounterfactual(
+		ctx context.Context,
+		wallet common.Address,
+		factory common.Address,
+		factoryCalldata []byte,
+		hash common.Hash,
+		sig []byte,
+	) (bool, error)
+}
+
+// the contents of an EIP-6492 wrapped sig
+type EIP6492Signature struct {
+	Factory         common.Address
+	FactoryCalldata []byte
+	Signature       []byte
+}
+
+// ===== EIP-6492 =====
+
+// whether the sig bytes end in the EIP-6492 magic suffix
+func IsEIP6492Signature(sig []byte) bool {
+	return len(sig) >= len(eip6492MagicSuffix) && bytes.Equal(sig[len(sig)-len(eip6492MagicSuffix):], eip6492MagicSuffix)
+}
+
+// unwraps an EIP-6492 sig, which is abi.encode(address factory, bytes factoryCalldata, bytes sig) ‖ magic suffix
+func ParseEIP6492Signature(sig []byte) (EIP6492Signature, error) {
+	if !IsEIP6492Signature(sig) {
+		return EIP6492Signature{}, fmt.Errorf("signature is missing the EIP-6492 magic suffix")
+	}
+
+	unpacked, err := eip6492Arguments().Unpack(sig[:len(sig)-len(eip6492MagicSuffix)])
+	if err != nil {
+		return EIP6492Signature{}, fmt.Errorf("failed to ABI decode EIP-6492 signature: %w", err)
+	}
+
+	return EIP6492Signature{
+		Factory:         unpacked[0].(common.Address),
+		FactoryCalldata: unpacked[1].([]byte),
+		Signature:       unpacked[2].([]byte),
+	}, nil
+}
+
+// wraps a sig in the EIP-6492 format (mostly useful for tests and tooling)
+func (s EIP6492Signature) Encode() ([]byte, error) {
+	packed, err := eip6492Arguments().Pack(s.Factory, s.FactoryCalldata, s.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ABI encode EIP-6492 signature: %w", err)
+	}
+	return append(packed, eip6492MagicSuffix...), nil
+}
+
+// validates a wrapped sig for a counterfactual wallet using its embedded factory/deploy data
+func verifyEIP6492(
+	ctx context.Context,
+	validator CounterfactualValidator,
+	wallet common.Address,
+	hash []byte,
+	sig []byte,
+) (bool, error) {
+	if validator == nil {
+		return false, ErrNoCounterfactualValidator
+	}
+
+	wrapped, err := ParseEIP6492Signature(sig)
+	if err != nil {
+		return false, err
+	}
+
+	return validator.ValidateCounterfactual(ctx, wallet, wrapped.Factory, wrapped.FactoryCalldata, common.BytesToHash(hash), wrapped.Signature)
+}
+
+// ===== EIP-1271 =====
+
+// asks a deployed contract wallet whether it accepts the sig over the hash, via `isValidSignature(bytes32,bytes)`
+//
+// anything but the magic value back (including a revert, which a wallet may use to reject) means invalid, while
+// any other failed call (an unreachable node, a cancelled context, ...) is an error
+func verifyEIP1271(
+	ctx context.Context,
+	caller ethereum.ContractCaller,
+	wallet common.Address,
+	hash []byte,
+	sig []byte,
+) (bool, error) {
+	args, err := eip1271Arguments().Pack(common.BytesToHash(hash), sig)
+	if err != nil {
+		return false, fmt.Errorf("failed to ABI encode isValidSignature call: %w", err)
+	}
+
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{
+		To:   &wallet,
+		Data: append(append([]byte{}, eip1271MagicValue...), args...),
+	}, nil)
+	if err != nil {
+		if isExecutionReverted(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("isValidSignature call to %s failed: %w", wallet.Hex(), err)
+	}
+
+	// the bytes4 return value is left aligned in its 32 byte word, and is compared in constant time
+	return len(result) >= len(eip1271MagicValue) && constantTimeEqual(result[:len(eip1271MagicValue)], eip1271MagicValue), nil
+}
+
+// whether an eth_call failed because the contract reverted, rather than because the call never ran
+//
+// geth and most other nodes answer a revert with JSON-RPC error code 3 (carrying the revert data), which ethclient
+// surfaces as an rpc.Error, and every node (and the simulated backend) says "execution reverted" in the message
+func isExecutionReverted(err error) bool {
+	var rpcErr interface{ ErrorCode() int }
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// (bytes32, bytes)
+func eip1271Arguments() abi.Arguments {
+	bytes32Type, _ := abi.NewType("bytes32", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	return abi.Arguments{{Type: bytes32Type}, {Type: bytesType}}
+}
+
+// (address, bytes, bytes)
+func eip6492Arguments() abi.Arguments {
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	return abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}
+}
@@ -0,0 +1,263 @@
+package dids_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// pretends to deploy wallets via a known factory and accepts one known inner sig
+type mockCounterfactualValidator struct {
+	wallet    common.Address
+	factory   common.Address
+	calldata  []byte
+	validSig  []byte
+	gotHashes []common.Hash
+}
+
+func (m *mockCounterfactualValidator) ValidateCounterfactual(
+	ctx context.Context,
+	wallet common.Address,
+	factory common.Address,
+	factoryCalldata []byte,
+	hash common.Hash,
+	sig []byte,
+) (bool, error) {
+	m.gotHashes = append(m.gotHashes, hash)
+
+	// the "deployment" only yields our wallet if the factory and calldata match
+	if wallet != m.wallet || factory != m.factory || !bytes.Equal(factoryCalldata, m.calldata) {
+		return false, nil
+	}
+	return bytes.Equal(sig, m.validSig), nil
+}
+
+func TestEthDIDVerifyEIP6492(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	validator := &mockCounterfactualValidator{
+		wallet:   wallet,
+		factory:  common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		calldata: []byte{0xde, 0xad, 0xbe, 0xef},
+		validSig: []byte("smart wallet owner sig"),
+	}
+
+	wrapped, err := dids.EIP6492Signature{
+		Factory:         validator.factory,
+		FactoryCalldata: validator.calldata,
+		Signature:       validator.validSig,
+	}.Encode()
+	assert.Nil(t, err)
+	assert.True(t, dids.IsEIP6492Signature(wrapped))
+
+	// round trips
+	parsed, err := dids.ParseEIP6492Signature(wrapped)
+	assert.Nil(t, err)
+	assert.Equal(t, validator.factory, parsed.Factory)
+	assert.Equal(t, validator.calldata, parsed.FactoryCalldata)
+	assert.Equal(t, validator.validSig, parsed.Signature)
+
+	did := dids.NewEthDID(wallet.Hex())
+
+	valid, err := did.VerifyWithOptions(block, hex.EncodeToString(wrapped), dids.WithCounterfactualValidator(validator))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// the validator is handed the EIP-712 hash of the block
+	assert.Len(t, validator.gotHashes, 1)
+	assert.NotEqual(t, common.Hash{}, validator.gotHashes[0])
+
+	// a wrapped sig with a different inner sig is rejected
+	tampered, err := dids.EIP6492Signature{
+		Factory:         validator.factory,
+		FactoryCalldata: validator.calldata,
+		Signature:       []byte("someone else"),
+	}.Encode()
+	assert.Nil(t, err)
+	valid, err = did.VerifyWithOptions(block, hex.EncodeToString(tampered), dids.WithCounterfactualValidator(validator))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// without a validator we can't say anything about it
+	_, err = did.Verify(block, hex.EncodeToString(wrapped))
+	assert.ErrorIs(t, err, dids.ErrNoCounterfactualValidator)
+}
+
+// a contract wallet at one address that accepts one known sig, answering eth_calls like a node would
+type mockContractCaller struct {
+	wallet   common.Address
+	validSig []byte
+	calls    []ethereum.CallMsg
+}
+
+func (m *mockContractCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	m.calls = append(m.calls, call)
+
+	// isValidSignature(bytes32 hash, bytes sig): selector, hash, offset, length, sig
+	if call.To == nil || *call.To != m.wallet || !bytes.Equal(call.Data[:4], common.FromHex("0x1626ba7e")) {
+		return nil, nil
+	}
+	sigLen := new(big.Int).SetBytes(call.Data[68:100]).Int64()
+	if !bytes.Equal(call.Data[100:100+sigLen], m.validSig) {
+		return common.LeftPadBytes(nil, 32), nil
+	}
+	return common.RightPadBytes(common.FromHex("0x1626ba7e"), 32), nil
+}
+
+func TestEthDIDVerifyEIP1271(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	// the wallet's owner signs, but the DID is the wallet's own address
+	owner, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	ownerSig, err := dids.NewEthProviderFromKey(owner).SignData(data)
+	assert.Nil(t, err)
+	ownerSigBytes, err := hex.DecodeString(ownerSig)
+	assert.Nil(t, err)
+
+	wallet := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	caller := &mockContractCaller{wallet: wallet, validSig: ownerSigBytes}
+	did := dids.NewEthDID(wallet.Hex())
+
+	// ECDSA recovery gives the owner, so only the contract can vouch for it
+	valid, err := did.Verify(block, ownerSig)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	valid, err = did.VerifyWithOptions(block, ownerSig, dids.WithContractCaller(caller))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	assert.Len(t, caller.calls, 1)
+
+	// non-ECDSA sigs (e.g. a multisig's concatenated ones) go straight to the contract
+	caller.validSig = []byte("safe multisig sigs")
+	valid, err = did.VerifyWithOptions(block, hex.EncodeToString(caller.validSig), dids.WithContractCaller(caller))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// and a sig the contract doesn't accept is invalid
+	valid, err = did.VerifyWithOptions(block, ownerSig, dids.WithContractCaller(caller))
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// EOA sigs that recover correctly never hit the node
+	calls := len(caller.calls)
+	eoaDID := dids.NewEthDID(crypto.PubkeyToAddress(owner.PublicKey).Hex())
+	valid, err = eoaDID.VerifyWithOptions(block, ownerSig, dids.WithContractCaller(caller))
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	assert.Len(t, caller.calls, calls)
+}
+
+// a node that never answers, until the call's context gives up
+type hangingContractCaller struct{}
+
+func (hangingContractCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestEthDIDVerifyContext(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+
+	valid, err := did.VerifyContext(context.Background(), block, sig)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	// an already cancelled context stops even a pure ECDSA check
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	valid, err = did.VerifyContext(ctx, block, sig)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, valid)
+
+	// and a timeout cuts a contract wallet check short
+	wallet := dids.NewEthDID("0x3333333333333333333333333333333333333333")
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	valid, err = wallet.VerifyContext(ctx, block, sig, dids.WithContractCaller(hangingContractCaller{}))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, valid)
+}
+
+// a node whose eth_calls all return the same bytes
+type fixedContractCaller []byte
+
+func (f fixedContractCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return f, nil
+}
+
+// a wallet that rejects every sig by reverting, with the given error
+type revertingContractCaller struct{ err error }
+
+func (r revertingContractCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, r.err
+}
+
+// what an RPC node answers a reverted eth_call with
+type revertRPCError struct{}
+
+func (revertRPCError) Error() string          { return "execution reverted: not the owner" }
+func (revertRPCError) ErrorCode() int         { return 3 }
+func (revertRPCError) ErrorData() interface{} { return "0x08c379a0" }
+
+func TestEthDIDVerifyEIP1271Revert(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	did := dids.NewEthDID("0x3333333333333333333333333333333333333333")
+	sig := hex.EncodeToString([]byte("contract sig"))
+
+	// a revert is the wallet saying no, not a failure
+	for _, revert := range []error{revertRPCError{}, fmt.Errorf("execution reverted")} {
+		valid, err := did.VerifyWithOptions(block, sig, dids.WithContractCaller(revertingContractCaller{revert}))
+		assert.Nil(t, err, revert.Error())
+		assert.False(t, valid, revert.Error())
+	}
+
+	// while not reaching the node still is
+	valid, err := did.VerifyWithOptions(block, sig, dids.WithContractCaller(revertingContractCaller{fmt.Errorf("connection refused")}))
+	assert.NotNil(t, err)
+	assert.False(t, valid)
+}
+
+func TestEthDIDVerifyEIP1271MagicValue(t *testing.T) {
+	block := createCBORBlock(t, map[string]interface{}{"op": "transfer", "amount": 10})
+	did := dids.NewEthDID("0x3333333333333333333333333333333333333333")
+	sig := hex.EncodeToString([]byte("contract sig"))
+
+	for _, tc := range []struct {
+		name     string
+		result   []byte
+		expected bool
+	}{
+		{"padded magic value", common.RightPadBytes(common.FromHex("0x1626ba7e"), 32), true},
+		{"bare magic value", common.FromHex("0x1626ba7e"), true},
+		{"last byte off", common.RightPadBytes(common.FromHex("0x1626ba7f"), 32), false},
+		{"first byte off", common.RightPadBytes(common.FromHex("0x0626ba7e"), 32), false},
+		{"right aligned", common.LeftPadBytes(common.FromHex("0x1626ba7e"), 32), false},
+		{"too short", common.FromHex("0x1626ba"), false},
+		{"empty", nil, false},
+	} {
+		valid, err := did.VerifyWithOptions(block, sig, dids.WithContractCaller(fixedContractCaller(tc.result)))
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.expected, valid, tc.name)
+	}
+}
@@ -0,0 +1,156 @@
+package dids
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ===== struct -> typed data field layout =====
+//
+// structs can pin their EIP-712 field names and types with an `eip712` tag:
+//
+//	type Transfer struct {
+//		Amount uint64   `eip712:"name=amount,type=uint256"`
+//		To     string   `eip712:"name=to"`
+//		Auths  []string `eip712:"required_auths,string[]"` // positional form, name then type
+//		Memo   string   `eip712:"-"`                       // skipped
+//	}
+//
+// without a tag, the json tag name (or else the Go field name) and the inferred type are used
+
+// a struct field as it appears in the typed data
+type structField struct {
+	index int
+	name  string
+	// explicit EIP-712 type from the tag, empty to infer it
+	typ string
+}
+
+// the layout (or tag error) worked out for a struct type, which never changes for the life of the program
+type structLayout struct {
+	fields []structField
+	err    error
+}
+
+// struct layouts by reflect.Type, so a type converted over and over (as in a validation loop) is only walked once
+var structLayoutCache sync.Map
+
+// the typed data layout of a struct type, worked out on first use and cached after that
+//
+// the returned fields are shared between callers, so they must not be modified
+func structFields(t reflect.Type) ([]structField, error) {
+	if cached, ok := structLayoutCache.Load(t); ok {
+		layout := cached.(structLayout)
+		return layout.fields, layout.err
+	}
+
+	// racing callers work out the same layout, so whichever is stored first is as good as any
+	fields, err := buildStructFields(t)
+	cached, _ := structLayoutCache.LoadOrStore(t, structLayout{fields: fields, err: err})
+	layout := cached.(structLayout)
+	return layout.fields, layout.err
+}
+
+// works out the typed data layout of a struct type from its tags
+func buildStructFields(t reflect.Type) ([]structField, error) {
+	fields := []structField{}
+	for i := 0; i < t.NumField(); i++ {
+		goField := t.Field(i)
+
+		// unexported fields can't be read, and aren't part of the payload anyway
+		if !goField.IsExported() {
+			continue
+		}
+
+		name, typ, skip, err := parseEIP712Tag(goField.Tag.Get("eip712"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid eip712 tag on %s.%s: %w", t.Name(), goField.Name, err)
+		}
+		if skip {
+			continue
+		}
+
+		if name == "" {
+			name = jsonFieldName(goField)
+		}
+		if name == "" {
+			continue
+		}
+
+		fields = append(fields, structField{index: i, name: name, typ: typ})
+	}
+	return fields, nil
+}
+
+// converts a struct value into the map form the converter walks, plus the tag-pinned types keyed by field name
+func structToMap(v reflect.Value) (map[string]interface{}, map[string]string, error) {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make(map[string]interface{}, len(fields))
+	var fieldTypes map[string]string
+	for _, field := range fields {
+		data[field.name] = v.Field(field.index).Interface()
+		if field.typ != "" {
+			if fieldTypes == nil {
+				fieldTypes = make(map[string]string)
+			}
+			fieldTypes[field.name] = field.typ
+		}
+	}
+	return data, fieldTypes, nil
+}
+
+// parses `name=<name>,type=<type>` or positional `<name>,<type>`, where either part is optional (e.g. `,uint64`
+// for just the type), or `-` to skip the field
+func parseEIP712Tag(tag string) (name string, typ string, skip bool, err error) {
+	if tag == "" {
+		return "", "", false, nil
+	}
+	if tag == "-" {
+		return "", "", true, nil
+	}
+
+	for i, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		key, value, found := strings.Cut(part, "=")
+
+		// without a key, the first part is the name and the second the type
+		if !found && i < 2 {
+			if i == 0 {
+				name = part
+			} else {
+				typ = part
+			}
+			continue
+		}
+		if !found || value == "" {
+			return "", "", false, fmt.Errorf("expected key=value, got %q", part)
+		}
+		switch key {
+		case "name":
+			name = value
+		case "type":
+			typ = value
+		default:
+			return "", "", false, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return name, typ, false, nil
+}
+
+// the field's name as encoding/json would use it, or "" if json skips it
+func jsonFieldName(field reflect.StructField) string {
+	jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if jsonName == "-" {
+		return ""
+	}
+	if jsonName != "" {
+		return jsonName
+	}
+	return field.Name
+}
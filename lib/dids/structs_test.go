@@ -0,0 +1,244 @@
+package dids_test
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEIP712StructTags(t *testing.T) {
+	type payload struct {
+		To string `eip712:"name=to"`
+	}
+	type transfer struct {
+		Amount  uint32  `eip712:"name=amount,type=uint64"`
+		Payload payload `eip712:"name=payload"`
+		Memo    string  `eip712:"-"`
+		Op      string  `json:"op"`
+		Nonce   uint64
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", transfer{
+		Amount:  5,
+		Payload: payload{To: "hive:bob"},
+		Memo:    "not signed",
+		Op:      "transfer",
+		Nonce:   1,
+	}, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.Nil(t, err)
+
+	// tagged name and type, then the json name, then the Go name with an inferred type
+	assert.Equal(t, []apitypes.Type{
+		{Name: "Nonce", Type: "uint256"},
+		{Name: "amount", Type: "uint64"},
+		{Name: "op", Type: "string"},
+		{Name: "payload", Type: "tx_container_v0.payload"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, []apitypes.Type{{Name: "to", Type: "string"}}, typedData.Data.Types["tx_container_v0.payload"])
+
+	assert.Equal(t, big.NewInt(5), typedData.Data.Message["amount"])
+	assert.Equal(t, map[string]interface{}{"to": "hive:bob"}, typedData.Data.Message["payload"])
+	assert.NotContains(t, typedData.Data.Message, "Memo")
+}
+
+func TestEIP712StructTagsPositional(t *testing.T) {
+	type header struct {
+		RequiredAuths []string `eip712:"required_auths,string[]"`
+		Nonce         uint32   `eip712:",uint64"`
+		NetID         string   `eip712:"net_id"`
+		internal      string
+	}
+
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", header{
+		RequiredAuths: []string{"hive:alice"},
+		Nonce:         7,
+		NetID:         "vsc-mainnet",
+		internal:      "not signed",
+	}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+
+	// renamed with a pinned type, the Go name with a pinned type, renamed with an inferred type, and no
+	// unexported field
+	assert.Equal(t, []apitypes.Type{
+		{Name: "Nonce", Type: "uint64"},
+		{Name: "net_id", Type: "string"},
+		{Name: "required_auths", Type: "string[]"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, []string{"hive:alice"}, typedData.Data.Message["required_auths"])
+	assert.NotContains(t, typedData.Data.Message, "internal")
+}
+
+func TestEIP712StructTagsInvalid(t *testing.T) {
+	type bad struct {
+		Amount uint64 `eip712:"size=256"`
+	}
+
+	_, err := dids.ConvertToEIP712TypedData("vsc.network", bad{}, "tx_container_v0", func(f float64) (*big.Int, error) {
+		return big.NewInt(int64(f)), nil
+	})
+	assert.NotNil(t, err)
+}
+
+func TestEIP712StructPointers(t *testing.T) {
+	type payload struct {
+		To string `json:"to"`
+	}
+	type transfer struct {
+		Memo    *string  `json:"memo"`
+		Amount  *big.Int `json:"amount"`
+		Payload *payload `json:"payload"`
+	}
+
+	// non-nil pointers convert as what they point at
+	memo := "rent"
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", transfer{
+		Memo:    &memo,
+		Amount:  big.NewInt(5),
+		Payload: &payload{To: "hive:bob"},
+	}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "amount", Type: "uint256"},
+		{Name: "memo", Type: "string"},
+		{Name: "payload", Type: "tx_container_v0.payload"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, "rent", typedData.Data.Message["memo"])
+	assert.Equal(t, map[string]interface{}{"to": "hive:bob"}, typedData.Data.Message["payload"])
+
+	// a nil pointer errors by default
+	withNil := transfer{Memo: &memo, Payload: &payload{To: "hive:bob"}}
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", withNil, "tx_container_v0", nil)
+	assert.ErrorIs(t, err, dids.ErrNilValue)
+	assert.ErrorContains(t, err, "amount")
+
+	// is left out entirely when omitted
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", withNil, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyOmit))
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "memo", Type: "string"},
+		{Name: "payload", Type: "tx_container_v0.payload"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.NotContains(t, typedData.Data.Message, "amount")
+
+	// or becomes the zero value of its type
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", transfer{}, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyZero))
+	assert.Nil(t, err)
+	assert.Equal(t, []apitypes.Type{
+		{Name: "amount", Type: "uint256"},
+		{Name: "memo", Type: "string"},
+		{Name: "payload", Type: "tx_container_v0.payload"},
+	}, typedData.Data.Types["tx_container_v0"])
+	assert.Equal(t, big.NewInt(0), typedData.Data.Message["amount"])
+	assert.Equal(t, "", typedData.Data.Message["memo"])
+	assert.Equal(t, map[string]interface{}{"to": ""}, typedData.Data.Message["payload"])
+
+	// but an untyped nil (a JSON or CBOR null) has no type to zero
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"memo": nil}, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyZero))
+	assert.ErrorIs(t, err, dids.ErrNilValue)
+}
+
+type cachedTransfer struct {
+	Amount uint64 `eip712:"name=amount,type=uint64"`
+	To     string `json:"to"`
+	Memo   string `eip712:"-"`
+	Op     string `json:"op"`
+	Nonce  uint64
+	Flags  []string `json:"flags"`
+}
+
+func TestEIP712NilArrayElements(t *testing.T) {
+	// a block from a peer holding {"a":[null]} fails verification rather than crashing it
+	block := createCBORBlock(t, map[string]interface{}{"a": []interface{}{nil}})
+	did := dids.NewEthDID("0x553Cb1F25f6409e3C9A5B8bD6fd4A6A3B5cA2a8f")
+	sig := "0x" + strings.Repeat("00", 65)
+	valid, err := did.Verify(block, sig)
+	assert.ErrorIs(t, err, dids.ErrNilValue)
+	assert.False(t, valid)
+
+	// nils further in are caught the same way
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"a": []interface{}{"x", nil}}, "tx_container_v0", nil)
+	assert.ErrorIs(t, err, dids.ErrNilValue)
+	assert.ErrorContains(t, err, "a[1]")
+
+	// the nil policy applies to elements like it does to fields
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"a": []interface{}{"x", nil}}, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyOmit))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"x"}, typedData.Data.Message["a"])
+	assert.Equal(t, "string[]", typedData.Data.Types["tx_container_v0"][0].Type)
+
+	typedData, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"a": []*big.Int{big.NewInt(1), nil}}, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyZero))
+	assert.Nil(t, err)
+	assert.Equal(t, "[1 0]", fmt.Sprint(typedData.Data.Message["a"]))
+
+	_, err = dids.ConvertToEIP712TypedData("vsc.network", map[string]interface{}{"a": []interface{}{nil}}, "tx_container_v0", nil, dids.WithNilPolicy(dids.NilPolicyZero))
+	assert.ErrorIs(t, err, dids.ErrNilValue)
+
+	// and a block verifies under the policy it was signed with
+	valid, err = did.VerifyWithOptions(block, sig, dids.WithConvertOptions(dids.WithNilPolicy(dids.NilPolicyOmit)))
+	assert.NotErrorIs(t, err, dids.ErrNilValue)
+	assert.False(t, valid)
+}
+
+func TestEIP712StructConcurrent(t *testing.T) {
+	expected, err := dids.ConvertToEIP712TypedData("vsc.network", cachedTransfer{Op: "transfer", Flags: []string{"a"}}, "tx_container_v0", nil)
+	assert.Nil(t, err)
+
+	// the same struct type from many goroutines at once (run with -race to catch unsafe sharing)
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			typedData, err := dids.ConvertToEIP712TypedData("vsc.network", cachedTransfer{
+				Amount: uint64(i),
+				To:     fmt.Sprintf("hive:user%d", i),
+				Op:     "transfer",
+				Nonce:  uint64(i),
+				Flags:  []string{"a"},
+			}, "tx_container_v0", nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !assert.Equal(t, expected.Data.Types, typedData.Data.Types) {
+				return
+			}
+			assert.Equal(t, fmt.Sprintf("hive:user%d", i), typedData.Data.Message["to"])
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	// a bad tag is reported every time, not just the first
+	for i := 0; i < 2; i++ {
+		type bad struct {
+			Amount uint64 `eip712:"size=256"`
+		}
+		_, err := dids.ConvertToEIP712TypedData("vsc.network", bad{}, "tx_container_v0", nil)
+		assert.NotNil(t, err)
+	}
+}
+
+func BenchmarkConvertStruct(b *testing.B) {
+	value := cachedTransfer{Amount: 5, To: "hive:bob", Op: "transfer", Nonce: 1, Flags: []string{"a", "b"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dids.ConvertToEIP712TypedData("vsc.network", value, "tx_container_v0", nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package dids
+
+import (
+	"context"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+// ===== verifier interface =====
+
+// something that can check a DID's sig over a block, locally or by handing it off (to a remote service,
+// dedicated hardware, a batching queue, etc.)
+//
+// call sites that depend on this rather than on DID.Verify directly can swap backends without changes
+type Verifier interface {
+	Verify(ctx context.Context, block blocks.Block, sig string, did DID) (bool, error)
+}
+
+// ===== local implementation =====
+
+var _ Verifier = LocalVerifier{}
+
+// verifies in-process with the DID's own Verify
+type LocalVerifier struct {
+	// applied when the DID is an EthDID, e.g. to verify under a non-default domain
+	EthOptions []VerifyOption
+}
+
+// creates an in-process verifier, applying opts to EthDID verification
+func NewLocalVerifier(opts ...VerifyOption) LocalVerifier {
+	return LocalVerifier{EthOptions: opts}
+}
+
+func (v LocalVerifier) Verify(ctx context.Context, block blocks.Block, sig string, did DID) (bool, error) {
+	// bail early if the caller already gave up
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if ethDID, ok := did.(EthDID); ok {
+		opts := append([]VerifyOption{WithContext(ctx)}, v.EthOptions...)
+		return ethDID.VerifyWithOptions(block, sig, opts...)
+	}
+	return did.Verify(block, sig)
+}
+
+// ===== adapters =====
+
+var _ Verifier = VerifierFunc(nil)
+
+// lets a plain function act as a Verifier, e.g. a thin client for a remote verification service
+type VerifierFunc func(ctx context.Context, block blocks.Block, sig string, did DID) (bool, error)
+
+func (f VerifierFunc) Verify(ctx context.Context, block blocks.Block, sig string, did DID) (bool, error) {
+	return f(ctx, block, sig, did)
+}
@@ -0,0 +1,91 @@
+package dids_test
+
+import (
+	"context"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/assert"
+)
+
+// a stand-in for a remote verification service: requests go over a channel to a "server" goroutine
+type mockRemoteVerifier struct {
+	requests chan mockRemoteRequest
+}
+
+type mockRemoteRequest struct {
+	block blocks.Block
+	sig   string
+	did   dids.DID
+	reply chan bool
+}
+
+func newMockRemoteVerifier(backend dids.Verifier) *mockRemoteVerifier {
+	v := &mockRemoteVerifier{requests: make(chan mockRemoteRequest)}
+	go func() {
+		for req := range v.requests {
+			valid, _ := backend.Verify(context.Background(), req.block, req.sig, req.did)
+			req.reply <- valid
+		}
+	}()
+	return v
+}
+
+func (v *mockRemoteVerifier) Verify(ctx context.Context, block blocks.Block, sig string, did dids.DID) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	reply := make(chan bool, 1)
+	select {
+	case v.requests <- mockRemoteRequest{block: block, sig: sig, did: did, reply: reply}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	select {
+	case valid := <-reply:
+		return valid, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func TestVerifierBackends(t *testing.T) {
+	data := map[string]interface{}{"op": "transfer", "amount": 10}
+	block := createCBORBlock(t, data)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	did := dids.NewEthDID(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	sig, err := dids.NewEthProviderFromKey(privateKey).SignData(data)
+	assert.Nil(t, err)
+
+	otherKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	otherDID := dids.NewEthDID(crypto.PubkeyToAddress(otherKey.PublicKey).Hex())
+
+	remote := newMockRemoteVerifier(dids.NewLocalVerifier())
+	defer close(remote.requests)
+
+	// call sites only see the interface, whichever backend is behind it
+	for name, verifier := range map[string]dids.Verifier{
+		"local":  dids.NewLocalVerifier(),
+		"remote": remote,
+	} {
+		valid, err := verifier.Verify(context.Background(), block, sig, did)
+		assert.Nil(t, err, name)
+		assert.True(t, valid, name)
+
+		valid, err = verifier.Verify(context.Background(), block, sig, otherDID)
+		assert.Nil(t, err, name)
+		assert.False(t, valid, name)
+
+		// a cancelled request doesn't get verified
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = verifier.Verify(ctx, block, sig, did)
+		assert.ErrorIs(t, err, context.Canceled, name)
+	}
+}
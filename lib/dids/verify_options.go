@@ -0,0 +1,172 @@
+package dids
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// ===== EthDID verification options =====
+
+// tweaks how EthDID.VerifyWithOptions reconstructs the typed data a sig was made over
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	domainName   string
+	primaryType  string
+	floatHandler func(float64) (*big.Int, error)
+	convertOpts  []ConvertOption
+
+	ctx                     context.Context
+	counterfactualValidator CounterfactualValidator
+
+	// when set, sigs that don't ECDSA recover to the DID's address are checked with the DID's contract (EIP-1271)
+	contractCaller ethereum.ContractCaller
+
+	// when set, IPLD links in the block are resolved (up to linkMaxDepth deep) before hashing
+	linkFetcher  BlockFetcher
+	linkMaxDepth int
+
+	// caps on the distinct blocks and total bytes link resolution fetches (defaults if <= 0)
+	linkMaxBlocks int
+	linkMaxBytes  int
+
+	// when set, VerifyDetailed includes the reconstructed message in its result
+	includeMessage bool
+
+	// when set, sig and address inputs are used exactly as given instead of being trimmed and 0x-normalized
+	strictInputs bool
+
+	// how sigs are encoded (auto detected by default)
+	sigEncoding SignatureEncoding
+
+	// when set, blocks that aren't canonical dag-cbor are rejected before hashing
+	strictCBOR bool
+
+	// when set, blocks whose CID doesn't have the dag-cbor codec are rejected before hashing
+	requireDagCBOR bool
+}
+
+// defaults match what vsc txs are signed with (and what EthDID.Verify uses)
+func newVerifyOptions(opts []VerifyOption) *verifyOptions {
+	o := &verifyOptions{
+		domainName:   vscDomainName,
+		primaryType:  vscPrimaryType,
+		floatHandler: defaultFloatHandler,
+		ctx:          context.Background(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// the chain ID the rebuilt typed data's domain carries (see WithChainID), or nil when it has none
+func (o *verifyOptions) chainID() *big.Int {
+	return newConvertOptions(o.floatHandler, o.convertOpts).chainID
+}
+
+// verifies under a different EIP-712 domain name than vsc.network
+func WithDomainName(domainName string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.domainName = domainName
+	}
+}
+
+// verifies under a different primary type than tx_container_v0
+func WithPrimaryType(primaryType string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.primaryType = primaryType
+	}
+}
+
+// applies conversion options when rebuilding the typed data, so they match what the signer used
+func WithConvertOptions(opts ...ConvertOption) VerifyOption {
+	return func(o *verifyOptions) {
+		o.convertOpts = append(o.convertOpts, opts...)
+	}
+}
+
+// the context used for anything verification reaches out for (counterfactual validation, link fetching)
+func WithContext(ctx context.Context) VerifyOption {
+	return func(o *verifyOptions) {
+		o.ctx = ctx
+	}
+}
+
+// resolves IPLD links in the signed block via the fetcher before hashing, for sigs made over the
+// resolved content (see ResolveLinks); maxDepth <= 0 uses DefaultMaxLinkDepth
+func WithLinkResolver(fetch BlockFetcher, maxDepth int) VerifyOption {
+	return func(o *verifyOptions) {
+		o.linkFetcher = fetch
+		o.linkMaxDepth = maxDepth
+	}
+}
+
+// caps the distinct blocks and total bytes WithLinkResolver may fetch (see ResolveLinksWithBudget); <= 0 uses
+// DefaultMaxLinkBlocks and DefaultMaxLinkBytes
+func WithLinkBudget(maxBlocks, maxBytes int) VerifyOption {
+	return func(o *verifyOptions) {
+		o.linkMaxBlocks = maxBlocks
+		o.linkMaxBytes = maxBytes
+	}
+}
+
+// validates EIP-6492 wrapped sigs (counterfactual smart wallets) with the given validator
+func WithCounterfactualValidator(validator CounterfactualValidator) VerifyOption {
+	return func(o *verifyOptions) {
+		o.counterfactualValidator = validator
+	}
+}
+
+// checks sigs that don't ECDSA recover to the DID's address with the contract wallet at that address, via EIP-1271's
+// `isValidSignature(bytes32,bytes)`
+//
+// caller is anything that can eth_call, like an *ethclient.Client
+func WithContractCaller(caller ethereum.ContractCaller) VerifyOption {
+	return func(o *verifyOptions) {
+		o.contractCaller = caller
+	}
+}
+
+// turns off the whitespace trimming and 0x normalization of the sig and the DID's address, so only
+// bare or lowercase 0x-prefixed hex with nothing around it is accepted
+func WithStrictInputs() VerifyOption {
+	return func(o *verifyOptions) {
+		o.strictInputs = true
+	}
+}
+
+// sets how sigs are encoded instead of detecting it (SignatureEncodingAuto by default)
+//
+// auto detection tells hex and base64 sigs apart reliably, but pinning the encoding keeps a sig from being read
+// as the other one
+func WithSignatureEncoding(encoding SignatureEncoding) VerifyOption {
+	return func(o *verifyOptions) {
+		o.sigEncoding = encoding
+	}
+}
+
+// rejects blocks that aren't canonically encoded (see IsCanonicalCBOR) with ErrNonCanonicalCBOR, rather than
+// verifying whatever they decode to
+func WithStrictCBOR() VerifyOption {
+	return func(o *verifyOptions) {
+		o.strictCBOR = true
+	}
+}
+
+// rejects blocks whose CID codec isn't dag-cbor (0x71) with ErrNotDagCBOR, so a block addressed as something else
+// (raw bytes, dag-json, ...) isn't verified as the CBOR it happens to decode as
+func WithRequireDagCBOR() VerifyOption {
+	return func(o *verifyOptions) {
+		o.requireDagCBOR = true
+	}
+}
+
+// has EthDID.VerifyDetailed include the reconstructed message the sig covers in its result
+func WithMessage() VerifyOption {
+	return func(o *verifyOptions) {
+		o.includeMessage = true
+	}
+}
@@ -0,0 +1,42 @@
+package dids
+
+// ===== version =====
+
+// the version of this package's signing and verification behavior, for advertising to peers
+const Version = "0.2.0"
+
+// ===== capabilities =====
+
+// signature formats, as reported by SupportedSignatureFormats
+const (
+	// hex secp256k1 sig over the EIP-712 hash of the block (EthDID)
+	SignatureFormatEIP712 = "eip712"
+	// EIP-712 sig from a counterfactual smart wallet, wrapped per EIP-6492 (EthDID)
+	SignatureFormatEIP6492 = "eip6492"
+	// EIP-712 sig checked by a deployed smart wallet per EIP-1271, with WithContractCaller (EthDID)
+	SignatureFormatEIP1271 = "eip1271"
+	// hex secp256k1 sig over ERC-191 prefixed raw bytes, with EthDID.VerifyPersonalSign (EthDID)
+	SignatureFormatPersonalSign = "personal_sign"
+	// compact JWS with an ed25519 or secp256k1 sig over the block's CID (KeyDID)
+	SignatureFormatJWS = "jws"
+	// base64url compressed BLS12-381 G2 sig over the block's CID, possibly aggregated (BlsDID)
+	SignatureFormatBLS = "bls12381"
+)
+
+// the DID methods this package can verify, as the prefix their DIDs start with (including any registered
+// with RegisterDIDMethod)
+func SupportedDIDMethods() []string {
+	return registeredDIDMethods()
+}
+
+// the sig formats this package can verify
+func SupportedSignatureFormats() []string {
+	return []string{
+		SignatureFormatEIP712,
+		SignatureFormatEIP6492,
+		SignatureFormatEIP1271,
+		SignatureFormatPersonalSign,
+		SignatureFormatJWS,
+		SignatureFormatBLS,
+	}
+}
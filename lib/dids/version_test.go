@@ -0,0 +1,24 @@
+package dids_test
+
+import (
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	assert.NotEmpty(t, dids.Version)
+
+	methods := dids.SupportedDIDMethods()
+	assert.Contains(t, methods, dids.EthDIDPrefix)
+	assert.Contains(t, methods, dids.EthrDIDPrefix)
+	assert.Contains(t, methods, dids.KeyDIDPrefix)
+
+	formats := dids.SupportedSignatureFormats()
+	assert.Contains(t, formats, dids.SignatureFormatEIP712)
+	assert.Contains(t, formats, dids.SignatureFormatEIP6492)
+	assert.Contains(t, formats, dids.SignatureFormatEIP1271)
+	assert.Contains(t, formats, dids.SignatureFormatJWS)
+	assert.Contains(t, formats, dids.SignatureFormatBLS)
+}
@@ -0,0 +1,101 @@
+package dids
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ===== errors =====
+
+var ErrEmptyDomainForWallet = fmt.Errorf("wallets can't sign typed data with an empty domain")
+
+// ===== eth_signTypedData_v4 =====
+
+// the typed data as the JSON object MetaMask and WalletConnect take as eth_signTypedData_v4's param: `types`
+// (with its EIP712Domain entry), `domain`, `primaryType` and `message`
+//
+// unlike MarshalJSON, the EIP712Domain type sits inside `types`, listed first with its fields in EIP-712's order.
+// integers in the message are written as decimal strings, which JS wallets parse without losing precision, and
+// bytes as 0x hex. a wallet signing this computes the same hash as ComputeEIP712Hash
+//
+// a domain with no fields at all is hashed differently by wallets than by this package, so it's an error
+func (d TypedData) ForSignTypedDataV4() (json.RawMessage, error) {
+	domainFields := domainTypes(d.Data.Domain)
+	if len(domainFields) == 0 {
+		return nil, ErrEmptyDomainForWallet
+	}
+
+	var types bytes.Buffer
+	types.WriteString("{")
+	if err := writeJSONField(&types, "EIP712Domain", domainFields); err != nil {
+		return nil, err
+	}
+	typeNames := make([]string, 0, len(d.Data.Types))
+	for typeName := range d.Data.Types {
+		if typeName != "EIP712Domain" {
+			typeNames = append(typeNames, typeName)
+		}
+	}
+	sort.Strings(typeNames)
+	for _, typeName := range typeNames {
+		types.WriteString(",")
+		if err := writeJSONField(&types, typeName, d.Data.Types[typeName]); err != nil {
+			return nil, err
+		}
+	}
+	types.WriteString("}")
+
+	walletMessage := walletValue(map[string]interface{}(d.Data.Message)).(map[string]interface{})
+	message, err := marshalMessageJSON(d.Data.Types, d.Data.PrimaryType, walletMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return json.Marshal(struct {
+		Types       json.RawMessage        `json:"types"`
+		Domain      map[string]interface{} `json:"domain"`
+		PrimaryType string                 `json:"primaryType"`
+		Message     json.RawMessage        `json:"message"`
+	}{
+		Types:       types.Bytes(),
+		Domain:      domainJSON(d.Data.Domain),
+		PrimaryType: d.Data.PrimaryType,
+		Message:     message,
+	})
+}
+
+// ===== utils =====
+
+// a message value with its integers as decimal strings and its bytes as 0x hex, at any depth
+func walletValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case []*big.Int:
+		rendered := make([]interface{}, len(v))
+		for i, n := range v {
+			rendered[i] = walletValue(n)
+		}
+		return rendered
+	case []byte:
+		return hexutil.Encode(v)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			rendered[key] = walletValue(elem)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, elem := range v {
+			rendered[i] = walletValue(elem)
+		}
+		return rendered
+	}
+	return value
+}
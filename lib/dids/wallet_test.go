@@ -0,0 +1,53 @@
+package dids_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"vsc-node/lib/dids"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedDataForSignTypedDataV4(t *testing.T) {
+	// a flat payload, since go-ethereum only takes Solidity identifiers as type names
+	data := map[string]interface{}{
+		"op":     "transfer",
+		"amount": new(big.Int).Lsh(big.NewInt(1), 70),
+		"to":     "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		"memo":   []byte("rent"),
+		"ids":    []uint64{1, 2},
+	}
+	typedData, err := dids.ConvertToEIP712TypedData("vsc.network", data, "tx_container_v0", nil,
+		dids.WithDomainVersion("1"), dids.WithChainID(1))
+	assert.Nil(t, err)
+
+	v4, err := typedData.ForSignTypedDataV4()
+	assert.Nil(t, err)
+
+	// the domain type comes first, in EIP-712's field order
+	assert.True(t, strings.HasPrefix(string(v4), `{"types":{"EIP712Domain":[{"name":"name","type":"string"},{"name":"version","type":"string"},{"name":"chainId","type":"uint256"}],`), string(v4))
+	assert.Contains(t, string(v4), `"amount":"1180591620717411303424"`)
+	assert.Contains(t, string(v4), `"memo":"0x72656e74"`)
+	assert.Contains(t, string(v4), `"ids":["1","2"]`)
+
+	// go-ethereum parses it and hashes it to the same digest
+	var parsed apitypes.TypedData
+	assert.Nil(t, json.Unmarshal(v4, &parsed))
+	assert.Len(t, parsed.Types["EIP712Domain"], 3)
+	assert.Equal(t, "tx_container_v0", parsed.PrimaryType)
+
+	walletHash, _, err := apitypes.TypedDataAndHash(parsed)
+	assert.Nil(t, err)
+	expected, err := dids.ComputeEIP712Hash(typedData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, walletHash)
+
+	// wallets hash an empty domain their own way
+	empty, err := dids.ConvertToEIP712TypedData("", data, "tx_container_v0", nil, dids.WithEmptyDomain())
+	assert.Nil(t, err)
+	_, err = empty.ForSignTypedDataV4()
+	assert.ErrorIs(t, err, dids.ErrEmptyDomainForWallet)
+}